@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"scrollwork/internal/audit"
+)
+
+// verifyAuditCommand verifies the hash chain of an audit log and returns the exit code of the command.
+func verifyAuditCommand(args []string) int {
+	flags := flag.NewFlagSet("verify-audit", flag.ExitOnError)
+	path := flags.String("auditLog", os.Getenv("SCROLLWORK_AUDIT_LOG"), "Path of the audit log to verify. Rotated files next to it are verified too")
+	flags.Parse(args)
+
+	if *path == "" {
+		fmt.Fprintln(os.Stderr, "Audit log is required. Use --auditLog to set it.")
+		return 2
+	}
+
+	result, err := audit.Verify(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Audit log is not intact: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Audit log is intact: %d entries across %d files, last hash %s\n", result.Entries, len(result.Files), result.LastHash)
+	return 0
+}
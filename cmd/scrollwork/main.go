@@ -2,17 +2,83 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"os/signal"
+	"scrollwork/internal/alert"
+	"scrollwork/internal/audit"
+	"scrollwork/internal/llm"
+	"scrollwork/internal/logging"
+	"scrollwork/internal/retry"
 	"scrollwork/internal/scrollwork"
+	"scrollwork/internal/tracing"
+	"scrollwork/internal/usage"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	_ "embed"
 )
 
+// allowedCallersFlag is a custom flag type that accumulates multiple --allowCaller flags
+type allowedCallersFlag []scrollwork.AllowedCaller
+
+func (c *allowedCallersFlag) String() string {
+	names := make([]string, 0, len(*c))
+	for _, allowed := range *c {
+		names = append(names, allowed.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *allowedCallersFlag) Set(value string) error {
+	allowed, err := scrollwork.ParseAllowedCaller(value)
+	if err != nil {
+		return err
+	}
+
+	*c = append(*c, allowed)
+	return nil
+}
+
+// urlsFlag is a custom flag type that accumulates multiple URL flags
+type urlsFlag []string
+
+func (u *urlsFlag) String() string {
+	return strings.Join(*u, ",")
+}
+
+func (u *urlsFlag) Set(value string) error {
+	*u = append(*u, value)
+	return nil
+}
+
+// deploymentsFlag is a custom flag type that accumulates multiple --azureDeployment flags
+type deploymentsFlag map[string]string
+
+func (d deploymentsFlag) String() string {
+	pairs := make([]string, 0, len(d))
+	for deployment, model := range d {
+		pairs = append(pairs, deployment+"="+model)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (d deploymentsFlag) Set(value string) error {
+	deployment, model, ok := strings.Cut(value, "=")
+	if !ok || deployment == "" || model == "" {
+		return fmt.Errorf("deployment must be <name>=<model>, got %q", value)
+	}
+
+	d[deployment] = model
+	return nil
+}
+
 // modelsFlag is a custom flag type that accumulates multiple --model flags
 type modelsFlag []string
 
@@ -29,10 +95,72 @@ var (
 	models              modelsFlag
 	apiKey              string
 	adminKey            string
+	bedrockRegion       string
+	bedrockEndpoint     string
+	cloudWatchEndpoint  string
+	vertexCredentials   string
+	vertexProject       string
+	vertexRegion        string
+	vertexEndpoint      string
+	vertexTokenEndpoint string
+	monitoringEndpoint  string
+	geminiAPIKey        string
+	geminiEndpoint      string
+	usageLedgerPath     string
+	localBaseURL        string
+	localAPIKey         string
+	localTokenizer      string
+	gpuHourlyCost       float64
+	gpuInputTPS         float64
+	gpuOutputTPS        float64
+	azureEndpoint       string
+	azureAPIKey         string
+	azureAPIVersion     string
+	azureDeployments    = deploymentsFlag{}
+	azureResourceID     string
+	azureTenantID       string
+	azureClientID       string
+	azureClientSecret   string
+	azureMgmtEndpoint   string
+	azureTokenEndpoint  string
 	refreshRateMinutes  int
-	lowRiskThreshold    float64
+	fetchAttempts       int
+	fetchBackoff        time.Duration
+	fetchBackoffMax     time.Duration
+	breakerThreshold    int
+	breakerCooldown     time.Duration
 	mediumRiskThreshold float64
 	highRiskThreshold   float64
+	riskCeiling         float64
+	beyondHighRisk      string
+	quotaWindow         string
+	quotaTokens         int
+	rpmLimit            int
+	itpmLimit           int
+	otpmLimit           int
+	staleRiskLevel      string
+	shutdownTimeout     time.Duration
+	socketPath          string
+	socketMode          string
+	socketGroup         string
+	tcpAddr             string
+	tlsCertFile         string
+	tlsKeyFile          string
+	tlsClientCAFile     string
+	tlsHandshakeTimeout time.Duration
+	tlsIdleTimeout      time.Duration
+	allowedCallers      allowedCallersFlag
+	adminAddr           string
+	otlpEndpoint        string
+	otlpInsecure        bool
+	alertThresholds     string
+	alertWebhooks       urlsFlag
+	alertSecret         string
+	alertOutboxDir      string
+	auditLogPath        string
+	auditLogMaxBytes    int64
+	logFormat           string
+	logLevel            string
 )
 
 func init() {
@@ -41,11 +169,94 @@ func init() {
 	// TODO: These are assuming Anthropic keys. We should handle OpenAPI differently
 	flag.StringVar(&apiKey, "apiKey", os.Getenv("SCROLLWORK_API_KEY"), "API Key")
 	flag.StringVar(&adminKey, "adminKey", os.Getenv("SCROLLWORK_ADMIN_KEY"), "Admin Key")
+	flag.StringVar(&bedrockRegion, "bedrockRegion", os.Getenv("AWS_REGION"), "AWS region Bedrock models are invoked in. Credentials are read from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables")
+	flag.StringVar(&bedrockEndpoint, "bedrockEndpoint", "", "Bedrock runtime endpoint (default: https://bedrock-runtime.<region>.amazonaws.com)")
+	flag.StringVar(&cloudWatchEndpoint, "cloudWatchEndpoint", "", "CloudWatch endpoint Bedrock usage is read from (default: https://monitoring.<region>.amazonaws.com)")
+
+	flag.StringVar(&vertexCredentials, "vertexCredentials", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"), "Service account key file Vertex AI models are invoked with")
+	flag.StringVar(&vertexProject, "vertexProject", "", "Google Cloud project Vertex AI models are invoked in (default: the project of the service account)")
+	flag.StringVar(&vertexRegion, "vertexRegion", "global", "Region Vertex AI models are invoked in")
+	flag.StringVar(&vertexEndpoint, "vertexEndpoint", "", "Vertex AI endpoint (default: https://<region>-aiplatform.googleapis.com)")
+	flag.StringVar(&vertexTokenEndpoint, "vertexTokenEndpoint", "", "OAuth token endpoint service account assertions are exchanged with (default: the token_uri of the service account)")
+	flag.StringVar(&monitoringEndpoint, "monitoringEndpoint", "", "Cloud Monitoring endpoint Vertex AI usage is read from (default: https://monitoring.googleapis.com)")
+
+	flag.StringVar(&geminiAPIKey, "geminiAPIKey", os.Getenv("GEMINI_API_KEY"), "Gemini API key")
+	flag.StringVar(&geminiEndpoint, "geminiEndpoint", "", "Gemini API endpoint (default: https://generativelanguage.googleapis.com)")
+
+	flag.StringVar(&localBaseURL, "localBaseURL", "", "Base URL of the OpenAI-compatible server self-hosted models (local/<name>) are served by, such as http://localhost:8000")
+	flag.StringVar(&localAPIKey, "localAPIKey", os.Getenv("SCROLLWORK_LOCAL_API_KEY"), "Bearer token of the self-hosted inference server")
+	flag.StringVar(&localTokenizer, "localTokenizer", "server", "How prompts of self-hosted models are counted: server, to use the server's /tokenize endpoint, or estimate, to count them offline")
+	flag.Float64Var(&gpuHourlyCost, "gpuHourlyCost", 0, "Hourly cost in USD of the GPUs serving self-hosted models")
+	flag.Float64Var(&gpuInputTPS, "gpuInputTokensPerSecond", 0, "Prompt tokens per second the GPUs serving self-hosted models process")
+	flag.Float64Var(&gpuOutputTPS, "gpuOutputTokensPerSecond", 0, "Output tokens per second the GPUs serving self-hosted models generate")
+
+	flag.StringVar(&azureEndpoint, "azureEndpoint", os.Getenv("AZURE_OPENAI_ENDPOINT"), "Azure OpenAI resource endpoint, such as https://my-resource.openai.azure.com")
+	flag.StringVar(&azureAPIKey, "azureAPIKey", os.Getenv("AZURE_OPENAI_API_KEY"), "Azure OpenAI API key")
+	flag.StringVar(&azureAPIVersion, "azureAPIVersion", "2024-10-21", "Azure OpenAI API version")
+	flag.Var(azureDeployments, "azureDeployment", "Map an Azure OpenAI deployment (azure/<name>) to the model it serves, such as gpt-4o-prod=gpt-4o-2024-08-06 (can be specified multiple times)")
+	flag.StringVar(&azureResourceID, "azureResourceID", "", "Azure resource id of the Azure OpenAI resource usage metrics are read from")
+	flag.StringVar(&azureTenantID, "azureTenantID", os.Getenv("AZURE_TENANT_ID"), "Microsoft Entra ID tenant of the service principal usage metrics are read with")
+	flag.StringVar(&azureClientID, "azureClientID", os.Getenv("AZURE_CLIENT_ID"), "Client id of the service principal usage metrics are read with")
+	flag.StringVar(&azureClientSecret, "azureClientSecret", os.Getenv("AZURE_CLIENT_SECRET"), "Client secret of the service principal usage metrics are read with")
+	flag.StringVar(&azureMgmtEndpoint, "azureManagementEndpoint", "", "Azure Resource Manager endpoint usage metrics are read from (default: https://management.azure.com)")
+	flag.StringVar(&azureTokenEndpoint, "azureTokenEndpoint", "", "OAuth token endpoint client credentials are exchanged with (default: https://login.microsoftonline.com/<tenant>/oauth2/v2.0/token)")
+
+	flag.StringVar(&usageLedgerPath, "usageLedger", os.Getenv("SCROLLWORK_USAGE_LEDGER"), "Path of the JSONL file the usage clients report for providers without a usage API, such as Gemini, is kept in. Kept in memory only when empty")
+
 	flag.IntVar(&refreshRateMinutes, "refreshRate", 1, "Refresh rate in minutes for fetching organization usage")
+	flag.IntVar(&fetchAttempts, "usageFetchAttempts", 3, "Number of times usage is fetched on every refresh before giving up")
+	flag.DurationVar(&fetchBackoff, "usageFetchBackoff", retry.DefaultBackoff.Initial, "Delay before retrying a failed usage fetch, doubled after every attempt")
+	flag.DurationVar(&fetchBackoffMax, "usageFetchBackoffMax", retry.DefaultBackoff.Max, "Largest delay between usage fetch attempts, which also caps the provider's Retry-After")
+	flag.IntVar(&breakerThreshold, "usageBreakerThreshold", retry.DefaultCircuitBreakerConfig.FailureThreshold, "Number of consecutive failed refreshes after which usage is stale")
+	flag.DurationVar(&breakerCooldown, "usageBreakerCooldown", retry.DefaultCircuitBreakerConfig.Cooldown, "How long usage fetches are skipped once usage is stale")
 
-	flag.Float64Var(&lowRiskThreshold, "lowRiskThreshold", 50, "Token percentage threshold for low risk level (default: 50)")
+	flag.Func("lowRiskThreshold", "Removed. Prompts at or below mediumRiskThreshold are low risk", func(string) error {
+		return errors.New("the flag has been removed, prompts at or below mediumRiskThreshold are low risk")
+	})
 	flag.Float64Var(&mediumRiskThreshold, "mediumRiskThreshold", 75, "Token percentage threshold for medium risk level (default: 75)")
 	flag.Float64Var(&highRiskThreshold, "highRiskThreshold", 100, "Token percentage threshold for high risk level (default: 100)")
+	flag.Float64Var(&riskCeiling, "riskCeiling", 0, "Token threshold past which a prompt is beyond high risk (default: 10x highRiskThreshold)")
+	flag.StringVar(&beyondHighRisk, "beyondHighRisk", string(usage.RiskLevelUnknown), "Risk level returned past riskCeiling: high, unknown or block")
+
+	flag.StringVar(&quotaWindow, "quotaWindow", string(usage.WindowDaily), "Quota window usage is fetched and forecast for: daily or monthly")
+	flag.IntVar(&quotaTokens, "quotaTokens", 0, "Token quota per model within the quota window. Forecasting is disabled when 0")
+
+	flag.IntVar(&rpmLimit, "rpmLimit", 0, "Requests per minute limit used until the provider reports one")
+	flag.IntVar(&itpmLimit, "itpmLimit", 0, "Input tokens per minute limit used until the provider reports one")
+	flag.IntVar(&otpmLimit, "otpmLimit", 0, "Output tokens per minute limit used until the provider reports one")
+
+	flag.StringVar(&staleRiskLevel, "staleRiskLevel", "", "Lowest risk level of assessments while usage is stale: low, medium, high, unknown or block. Left unchanged when empty")
+
+	flag.DurationVar(&shutdownTimeout, "shutdownTimeout", 30*time.Second, "How long to wait for in-flight assessments to drain on shutdown")
+
+	flag.StringVar(&socketPath, "socket", envOrDefault("SCROLLWORK_SOCKET", "/tmp/scrollwork.sock"), "Path of the Unix socket to listen on")
+	flag.StringVar(&socketMode, "socketMode", "0660", "File mode of the Unix socket in octal")
+	flag.StringVar(&socketGroup, "socketGroup", "", "Group name or id that owns the Unix socket")
+
+	flag.StringVar(&tcpAddr, "tcpAddr", "", "Address to serve remote clients over TLS, such as :7443. Disabled when empty")
+	flag.StringVar(&tlsCertFile, "tlsCert", "", "PEM certificate presented to remote clients")
+	flag.StringVar(&tlsKeyFile, "tlsKey", "", "PEM private key of the certificate presented to remote clients")
+	flag.StringVar(&tlsClientCAFile, "tlsClientCA", "", "PEM bundle of CAs that sign client certificates. Enables mutual TLS when set")
+	flag.DurationVar(&tlsHandshakeTimeout, "tlsHandshakeTimeout", 10*time.Second, "Time remote clients have to complete the TLS handshake")
+	flag.DurationVar(&tlsIdleTimeout, "tlsIdleTimeout", 5*time.Minute, "Time after which remote clients that send no request are disconnected")
+
+	flag.Var(&allowedCallers, "allowCaller", "Allow a caller to connect, such as name=billing,uid=1001 or name=billing,cn=billing-service (can be specified multiple times). Any caller may connect when unset")
+
+	flag.StringVar(&adminAddr, "adminAddr", "", "Address of the HTTP admin listener serving /healthz, /readyz, /status and /metrics, such as :9090. Disabled when empty")
+
+	flag.StringVar(&otlpEndpoint, "otlpEndpoint", "", "OTLP/HTTP collector spans are exported to, such as localhost:4318. Defaults to the OTEL_EXPORTER_OTLP_* environment variables")
+	flag.BoolVar(&otlpInsecure, "otlpInsecure", false, "Export spans to the OTLP collector over plain HTTP")
+
+	flag.StringVar(&alertThresholds, "alertThresholds", "0.8,1", "Comma separated fractions of quotaTokens that raise an alert when crossed")
+	flag.Var(&alertWebhooks, "alertWebhook", "URL alerts are posted to (can be specified multiple times). Alerting is disabled when unset")
+	flag.StringVar(&alertSecret, "alertSecret", os.Getenv("SCROLLWORK_ALERT_SECRET"), "Secret alert webhooks are signed with")
+	flag.StringVar(&alertOutboxDir, "alertOutbox", "", "Directory undelivered alerts are kept in across restarts. Kept in memory only when empty")
+
+	flag.StringVar(&auditLogPath, "auditLog", os.Getenv("SCROLLWORK_AUDIT_LOG"), "Path of the hash-chained JSONL file every assessment is recorded in. Disabled when empty")
+	flag.Int64Var(&auditLogMaxBytes, "auditLogMaxBytes", 100*1024*1024, "Size in bytes past which the audit log is rotated")
+
+	flag.StringVar(&logFormat, "logFormat", logging.FormatText, "Log output format: text or json")
+	flag.StringVar(&logLevel, "logLevel", envOrDefault("SCROLLWORK_LOG_LEVEL", "info"), "Minimum log level: debug, info, warn or error")
 
 	// Handle SCROLLWORK_MODEL environment variable
 	if envModel := os.Getenv("SCROLLWORK_MODEL"); envModel != "" {
@@ -53,61 +264,306 @@ func init() {
 	}
 }
 
+func envOrDefault(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+// fatal logs msg at the error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		os.Exit(verifyAuditCommand(os.Args[2:]))
+	}
+
 	flag.Parse()
 
+	logger, err := logging.New(os.Stderr, logging.Config{Format: logFormat, Level: logLevel})
+	if err != nil {
+		slog.Error("Logging could not be configured", logging.Err(err))
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
 	if len(models) == 0 {
-		log.Fatal("At least one AI Model is required. Use --model to set it.")
+		fatal("At least one AI Model is required. Use --model to set it.")
+	}
+
+	var bedrock *llm.BedrockConfig
+	var vertex *llm.VertexConfig
+	var gemini *llm.GeminiConfig
+	var local *llm.LocalConfig
+	var azure *llm.AzureConfig
+	for _, model := range models {
+		parsed, err := llm.ParseModel(model)
+		if err != nil {
+			fatal("Model is not supported", logging.KeyModel, model, logging.Err(err))
+		}
+
+		switch parsed.Platform {
+		case llm.PlatformBedrock:
+			if bedrockRegion == "" {
+				fatal("Bedrock region is required. Use --bedrockRegion to set it.")
+			}
+
+			bedrock = &llm.BedrockConfig{
+				Region: bedrockRegion,
+				Credentials: llm.AWSCredentials{
+					AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+					SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+					SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+				},
+				RuntimeEndpoint:    bedrockEndpoint,
+				CloudWatchEndpoint: cloudWatchEndpoint,
+			}
+		case llm.PlatformVertex:
+			if vertexCredentials == "" {
+				fatal("Vertex AI credentials are required. Use --vertexCredentials to set it.")
+			}
+
+			credentials, err := os.ReadFile(vertexCredentials)
+			if err != nil {
+				fatal("Vertex AI credentials could not be read", logging.Err(err))
+			}
+
+			vertex = &llm.VertexConfig{
+				Credentials:        credentials,
+				ProjectID:          vertexProject,
+				Region:             vertexRegion,
+				APIEndpoint:        vertexEndpoint,
+				TokenEndpoint:      vertexTokenEndpoint,
+				MonitoringEndpoint: monitoringEndpoint,
+			}
+		case llm.PlatformGemini:
+			if geminiAPIKey == "" {
+				fatal("Gemini API key is required. Use --geminiAPIKey to set it.")
+			}
+
+			gemini = &llm.GeminiConfig{
+				APIKey:   geminiAPIKey,
+				Endpoint: geminiEndpoint,
+			}
+		case llm.PlatformLocal:
+			if localBaseURL == "" {
+				fatal("Self-hosted models require an inference server. Use --localBaseURL to set it.")
+			}
+
+			local = &llm.LocalConfig{
+				BaseURL: localBaseURL,
+				APIKey:  localAPIKey,
+				GPU: llm.GPUCost{
+					HourlyUSD:             gpuHourlyCost,
+					InputTokensPerSecond:  gpuInputTPS,
+					OutputTokensPerSecond: gpuOutputTPS,
+				},
+			}
+
+			switch localTokenizer {
+			case "server":
+			case "estimate":
+				local.Tokenizer = llm.EstimateTokenizer{}
+			default:
+				fatal("Local tokenizer must be server or estimate.")
+			}
+		case llm.PlatformAzure:
+			if azureEndpoint == "" || azureAPIKey == "" {
+				fatal("Azure OpenAI endpoint and API key are required. Use --azureEndpoint and --azureAPIKey to set them.")
+			}
+
+			if _, ok := azureDeployments[strings.TrimPrefix(model, llm.AzureModelPrefix)]; !ok {
+				fatal("Azure OpenAI deployment is not mapped to a model. Use --azureDeployment to map it.", logging.KeyModel, model)
+			}
+
+			azure = &llm.AzureConfig{
+				Endpoint:           azureEndpoint,
+				APIKey:             azureAPIKey,
+				APIVersion:         azureAPIVersion,
+				Deployments:        azureDeployments,
+				ResourceID:         azureResourceID,
+				TenantID:           azureTenantID,
+				ClientID:           azureClientID,
+				ClientSecret:       azureClientSecret,
+				ManagementEndpoint: azureMgmtEndpoint,
+				TokenEndpoint:      azureTokenEndpoint,
+			}
+		case llm.PlatformAnthropic:
+			if apiKey == "" {
+				fatal("API Key is required. Use --apiKey to set it.")
+			}
+
+			if adminKey == "" {
+				fatal("Admin Key is required. Use --adminkey to set it.")
+			}
+		}
+	}
+
+	if refreshRateMinutes <= 0 {
+		fatal("Refresh rate must be a positive.")
+	}
+
+	if fetchAttempts <= 0 || breakerThreshold <= 0 {
+		fatal("Usage fetch attempts and breaker threshold must be positive.")
 	}
 
-	if len(models) > 1 {
-		log.Fatal("Multiple models are not yet supported. Please specify exactly one --model flag.")
+	if fetchBackoff <= 0 || fetchBackoffMax < fetchBackoff || breakerCooldown <= 0 {
+		fatal("Usage fetch backoff and breaker cooldown must be positive, and the backoff must not exceed its max.")
 	}
 
-	if apiKey == "" {
-		log.Fatal("API Key is required. Use --apiKey to set it.")
+	window, err := usage.ParseWindow(quotaWindow)
+	if err != nil {
+		fatal("Quota window is invalid", logging.Err(err))
 	}
 
-	if adminKey == "" {
-		log.Fatal("Admin Key is required. Use --adminkey to set it.")
+	if quotaTokens < 0 {
+		fatal("Quota tokens must not be negative.")
 	}
 
-	if refreshRateMinutes <= 0 {
-		log.Fatal("Refresh rate must be a positive.")
+	mode, err := strconv.ParseUint(socketMode, 8, 32)
+	if err != nil {
+		fatal("Socket mode must be an octal file mode", logging.Err(err))
+	}
+
+	var staleRisk usage.RiskLevel
+	if staleRiskLevel != "" {
+		staleRisk, err = usage.ParseRiskLevel(staleRiskLevel)
+		if err != nil {
+			fatal("Stale risk level is invalid", logging.Err(err))
+		}
+	}
+
+	var thresholds []float64
+	for _, threshold := range strings.Split(alertThresholds, ",") {
+		t, err := strconv.ParseFloat(strings.TrimSpace(threshold), 64)
+		if err != nil {
+			fatal("Alert thresholds must be comma separated numbers", logging.Err(err))
+		}
+		thresholds = append(thresholds, t)
+	}
+
+	if len(alertWebhooks) > 0 && quotaTokens == 0 {
+		fatal("Quota tokens are required to alert on usage. Use --quotaTokens to set it.")
 	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Endpoint:    otlpEndpoint,
+		Insecure:    otlpInsecure,
+		ServiceName: "scrollwork",
+	})
+	if err != nil {
+		fatal("Tracing could not be configured", logging.Err(err))
+	}
+
+	var auditSink audit.Sink
+	if auditLogPath != "" {
+		auditSink, err = audit.NewFileSink(audit.FileSinkConfig{Path: auditLogPath, MaxBytes: auditLogMaxBytes})
+		if err != nil {
+			fatal("Audit log could not be opened", logging.Err(err))
+		}
+	}
+
+	usageLedger, err := llm.NewLedger(llm.LedgerConfig{Path: usageLedgerPath})
+	if err != nil {
+		fatal("Usage ledger could not be opened", logging.Err(err))
+	}
+
 	config := &scrollwork.AgentConfig{
 		Models:                      []string(models),
 		APIKey:                      apiKey,
 		AdminKey:                    adminKey,
+		Bedrock:                     bedrock,
+		Vertex:                      vertex,
+		Gemini:                      gemini,
+		Local:                       local,
+		Azure:                       azure,
+		UsageLedger:                 usageLedger,
 		RefreshUsageIntervalMinutes: refreshRateMinutes,
+		UsageFetchAttempts:          fetchAttempts,
+		UsageFetchBackoff: &retry.Backoff{
+			Initial:    fetchBackoff,
+			Max:        fetchBackoffMax,
+			Multiplier: retry.DefaultBackoff.Multiplier,
+			Jitter:     retry.DefaultBackoff.Jitter,
+		},
+		UsageCircuitBreaker: retry.CircuitBreakerConfig{FailureThreshold: breakerThreshold, Cooldown: breakerCooldown},
+
+		Socket: scrollwork.SocketConfig{
+			Path:  socketPath,
+			Mode:  fs.FileMode(mode),
+			Group: socketGroup,
+		},
+		TCP: scrollwork.TCPConfig{
+			Addr:             tcpAddr,
+			CertFile:         tlsCertFile,
+			KeyFile:          tlsKeyFile,
+			ClientCAFile:     tlsClientCAFile,
+			HandshakeTimeout: tlsHandshakeTimeout,
+			IdleTimeout:      tlsIdleTimeout,
+		},
+		AllowedCallers: []scrollwork.AllowedCaller(allowedCallers),
+		AdminAddr:      adminAddr,
 
-		LowRiskThreshold:    float32(lowRiskThreshold),
 		MediumRiskThreshold: float32(mediumRiskThreshold),
 		HigthRiskThreshold:  float32(highRiskThreshold),
+		RiskCeiling:         float32(riskCeiling),
+		BeyondHighRiskLevel: usage.RiskLevel(beyondHighRisk),
+
+		QuotaWindow: window,
+		QuotaTokens: quotaTokens,
+
+		StaleRiskLevel: staleRisk,
+
+		Alerts: alert.Config{
+			Thresholds: thresholds,
+			URLs:       []string(alertWebhooks),
+			Secret:     alertSecret,
+			OutboxDir:  alertOutboxDir,
+		},
+
+		Audit:  auditSink,
+		Logger: logger,
+
+		RateLimits: map[usage.RateLimitKind]int{
+			usage.RateLimitRequests:     rpmLimit,
+			usage.RateLimitInputTokens:  itpmLimit,
+			usage.RateLimitOutputTokens: otpmLimit,
+		},
 	}
 	agent, err := scrollwork.NewAgent(config)
 	if err != nil {
-		log.Fatalf("Scrollwork Agent could be initialized: %v", err)
+		fatal("Scrollwork Agent could not be initialized", logging.Err(err))
 	}
 
 	if err := agent.Start(ctx); err != nil {
-		log.Fatalf("Scrollwork Agent could not start: %v", err)
+		fatal("Scrollwork Agent could not start", logging.Err(err))
 	}
 
 	if err := agent.Run(ctx); err != nil {
-		log.Fatalf("Scrollwork Agent failed to run: %v", err)
+		fatal("Scrollwork Agent failed to run", logging.Err(err))
 	}
 
 	select {
 	case <-ctx.Done():
-		log.Println("Shutdown signal received, Scrollwork Agent and Usage worker will be shutting down...")
+		slog.Info("Shutdown signal received, Scrollwork Agent and Usage Worker will be shutting down")
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := agent.Stop(stopCtx); err != nil {
+		fatal("Scrollwork Agent failed to shut down", logging.Err(err))
 	}
 
-	if err := agent.Stop(); err != nil {
-		log.Fatalf("Scrollwork Agent failed to shut down: %v", err)
+	if err := shutdownTracing(stopCtx); err != nil {
+		fatal("Scrollwork Agent failed to flush traces", logging.Err(err))
 	}
 }
@@ -0,0 +1,286 @@
+// Package alert notifies webhooks when a model's usage crosses a fraction of its quota.
+package alert
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"scrollwork/internal/logging"
+	"scrollwork/internal/retry"
+	"scrollwork/internal/usage"
+	"slices"
+	"sync"
+	"time"
+)
+
+type (
+	Config struct {
+		// Thresholds are the fractions of QuotaTokens that raise an alert when crossed, such as 0.8 and 1.
+		Thresholds []float64
+
+		// QuotaTokens is the token budget of each model within the Window.
+		QuotaTokens int
+		// Window is the budget window usage is reported for. Defaults to usage.WindowDaily.
+		Window usage.Window
+
+		// URLs are the webhooks every event is delivered to.
+		URLs []string
+		// Secret signs every delivery. Deliveries are unsigned when empty.
+		Secret string
+
+		// OutboxDir persists undelivered events and alert state so they survive restarts. Both are kept in memory
+		// only when empty.
+		OutboxDir string
+
+		// MaxAttempts is the number of times a delivery is attempted before it is given up on. Defaults to 12.
+		MaxAttempts int
+		// Backoff is the delay between delivery attempts. Defaults to between 5 seconds and 5 minutes.
+		Backoff *retry.Backoff
+
+		Client *http.Client
+		Logger *slog.Logger
+	}
+
+	// Alerter raises alerts from usage observations and delivers them to webhooks.
+	Alerter struct {
+		config Config
+
+		states     map[string]modelState
+		deliveries map[string]*delivery
+		mu         sync.Mutex
+
+		wake chan struct{}
+	}
+
+	EventType string
+
+	// Event is the JSON body delivered to webhooks.
+	Event struct {
+		ID   string    `json:"id"`
+		Type EventType `json:"type"`
+
+		Model       string       `json:"model"`
+		Window      usage.Window `json:"window"`
+		WindowStart time.Time    `json:"window_start"`
+
+		// Threshold is the highest fraction of the quota crossed within the window.
+		Threshold   float64 `json:"threshold"`
+		UsedTokens  int     `json:"used_tokens"`
+		QuotaTokens int     `json:"quota_tokens"`
+
+		OccurredAt time.Time `json:"occurred_at"`
+	}
+
+	// modelState is the highest threshold alerted on for a model within a window.
+	modelState struct {
+		WindowStart time.Time `json:"window_start"`
+		Threshold   float64   `json:"threshold"`
+		UsedTokens  int       `json:"used_tokens"`
+	}
+)
+
+const (
+	// EventThresholdCrossed is sent when usage crosses a threshold higher than any alerted on within the window.
+	EventThresholdCrossed EventType = "threshold_crossed"
+	// EventResolved is sent once a new window starts for a model that crossed a threshold in the previous window.
+	EventResolved EventType = "resolved"
+
+	defaultMaxAttempts = 12
+
+	stateFile = "state.json"
+)
+
+var defaultBackoff = retry.Backoff{
+	Initial:    5 * time.Second,
+	Max:        5 * time.Minute,
+	Multiplier: 2,
+	Jitter:     0.5,
+}
+
+// New returns an Alerter, loading any alert state and undelivered events from config.OutboxDir.
+func New(config Config) (*Alerter, error) {
+	if len(config.URLs) == 0 {
+		return nil, fmt.Errorf("New failed: missing webhook URLs")
+	}
+
+	if config.QuotaTokens <= 0 {
+		return nil, fmt.Errorf("New failed: quota tokens must be positive to alert on thresholds")
+	}
+
+	thresholds := slices.Clone(config.Thresholds)
+	slices.Sort(thresholds)
+	thresholds = slices.Compact(thresholds)
+	if len(thresholds) == 0 {
+		return nil, fmt.Errorf("New failed: missing thresholds")
+	}
+	if thresholds[0] <= 0 {
+		return nil, fmt.Errorf("New failed: threshold %v must be positive", thresholds[0])
+	}
+	config.Thresholds = thresholds
+
+	if config.Window == "" {
+		config.Window = usage.WindowDaily
+	}
+
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+
+	if config.Backoff == nil {
+		config.Backoff = &defaultBackoff
+	}
+
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	a := &Alerter{
+		config:     config,
+		states:     make(map[string]modelState),
+		deliveries: make(map[string]*delivery),
+		wake:       make(chan struct{}, 1),
+	}
+
+	if config.OutboxDir != "" {
+		if err := os.MkdirAll(filepath.Join(config.OutboxDir, outboxDir), 0o750); err != nil {
+			return nil, fmt.Errorf("New failed: %w", err)
+		}
+
+		if err := a.loadState(); err != nil {
+			return nil, fmt.Errorf("New failed: %w", err)
+		}
+
+		if err := a.loadOutbox(); err != nil {
+			return nil, fmt.Errorf("New failed: %w", err)
+		}
+	}
+
+	return a, nil
+}
+
+// Observe records the tokens used by model within the window containing at and queues any resulting events.
+//
+// An alert is raised the first time usage crosses a threshold within a window. Crossing the same or a lower
+// threshold again does not raise another alert, crossing a higher one does.
+func (a *Alerter) Observe(model string, at time.Time, tokens int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	windowStart, _ := a.config.Window.Bounds(at)
+	state, ok := a.states[model]
+
+	var events []Event
+	if ok && state.WindowStart.Before(windowStart) {
+		if state.Threshold > 0 {
+			events = append(events, a.newEvent(EventResolved, model, state, at))
+		}
+		ok = false
+	}
+
+	if !ok {
+		state = modelState{WindowStart: windowStart}
+	}
+	state.UsedTokens = tokens
+
+	utilization := float64(tokens) / float64(a.config.QuotaTokens)
+	for _, threshold := range slices.Backward(a.config.Thresholds) {
+		if utilization < threshold {
+			continue
+		}
+
+		if threshold > state.Threshold {
+			state.Threshold = threshold
+			events = append(events, a.newEvent(EventThresholdCrossed, model, state, at))
+		}
+		break
+	}
+
+	a.states[model] = state
+	if len(events) == 0 {
+		return nil
+	}
+
+	var errs []error
+	for _, event := range events {
+		a.config.Logger.Info("Alert raised", "type", event.Type, logging.KeyModel, model, "threshold", event.Threshold, "used_tokens", event.UsedTokens)
+		errs = append(errs, a.enqueue(event, at))
+	}
+	errs = append(errs, a.saveState())
+
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("Observe failed: %w", err)
+	}
+
+	return nil
+}
+
+func (a *Alerter) newEvent(eventType EventType, model string, state modelState, at time.Time) Event {
+	return Event{
+		ID:          newID(),
+		Type:        eventType,
+		Model:       model,
+		Window:      a.config.Window,
+		WindowStart: state.WindowStart,
+		Threshold:   state.Threshold,
+		UsedTokens:  state.UsedTokens,
+		QuotaTokens: a.config.QuotaTokens,
+		OccurredAt:  at.UTC(),
+	}
+}
+
+func (a *Alerter) loadState() error {
+	b, err := os.ReadFile(filepath.Join(a.config.OutboxDir, stateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, &a.states)
+}
+
+func (a *Alerter) saveState() error {
+	if a.config.OutboxDir == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(a.states)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(a.config.OutboxDir, stateFile), b)
+}
+
+// writeFileAtomic replaces path with b so that a crash never leaves a partially written file.
+func writeFileAtomic(path string, b []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o640); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,281 @@
+package alert_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"scrollwork/internal/alert"
+	"scrollwork/internal/retry"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "whsec_test"
+
+// webhook is a local stand-in for a webhook receiver. Deliveries that are not signed or do not parse are rejected and
+// recorded, for tests to require there were none with requireValid.
+type webhook struct {
+	*httptest.Server
+
+	failures atomic.Int32
+
+	mu      sync.Mutex
+	events  []alert.Event
+	invalid []error
+}
+
+func newWebhook(t *testing.T) *webhook {
+	t.Helper()
+
+	w := &webhook{}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if w.failures.Add(-1) >= 0 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		event, err := readEvent(r)
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		if err != nil {
+			w.invalid = append(w.invalid, err)
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.events = append(w.events, event)
+	}))
+	t.Cleanup(w.Close)
+
+	return w
+}
+
+func (w *webhook) received() []alert.Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]alert.Event(nil), w.events...)
+}
+
+// requireValid fails the test if a delivery was rejected.
+func (w *webhook) requireValid(t *testing.T) {
+	t.Helper()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	require.Empty(t, w.invalid)
+}
+
+// readEvent reads the event of a delivery, checking its signature and event id header.
+func readEvent(r *http.Request) (alert.Event, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return alert.Event{}, err
+	}
+
+	if err := alert.VerifySignature(testSecret, r.Header.Get(alert.SignatureHeader), body, time.Minute, time.Now()); err != nil {
+		return alert.Event{}, err
+	}
+
+	var event alert.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return alert.Event{}, err
+	}
+
+	if id := r.Header.Get(alert.EventIDHeader); id != event.ID {
+		return alert.Event{}, fmt.Errorf("event id header %q does not match event %q", id, event.ID)
+	}
+
+	return event, nil
+}
+
+func newAlerter(t *testing.T, url string, outboxDir string, maxAttempts int) *alert.Alerter {
+	t.Helper()
+
+	a, err := alert.New(alert.Config{
+		Thresholds:  []float64{0.5, 0.8, 1},
+		QuotaTokens: 1000,
+		URLs:        []string{url},
+		Secret:      testSecret,
+		OutboxDir:   outboxDir,
+		MaxAttempts: maxAttempts,
+		Backoff:     &retry.Backoff{Initial: time.Millisecond, Max: 10 * time.Millisecond, Multiplier: 2},
+	})
+	require.NoError(t, err)
+
+	return a
+}
+
+func run(t *testing.T, a *alert.Alerter) context.CancelFunc {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.Run(ctx)
+		close(done)
+	}()
+
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+
+	return stop
+}
+
+func TestAlerter(t *testing.T) {
+	t.Parallel()
+
+	w := newWebhook(t)
+	a := newAlerter(t, w.URL, t.TempDir(), 0)
+	run(t, a)
+
+	day := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, a.Observe("claude-sonnet-4-20250514", day, 400))
+	require.NoError(t, a.Observe("claude-sonnet-4-20250514", day.Add(time.Minute), 600))
+	// Deduplicated
+	require.NoError(t, a.Observe("claude-sonnet-4-20250514", day.Add(2*time.Minute), 700))
+	// Escalation skips straight to the highest threshold crossed
+	require.NoError(t, a.Observe("claude-sonnet-4-20250514", day.Add(3*time.Minute), 1200))
+	// A new window resolves the previous one
+	require.NoError(t, a.Observe("claude-sonnet-4-20250514", day.Add(24*time.Hour), 100))
+
+	require.Eventually(t, func() bool { return len(w.received()) == 3 }, 5*time.Second, 10*time.Millisecond)
+
+	events := w.received()
+	require.Equal(t, alert.EventThresholdCrossed, events[0].Type)
+	require.Equal(t, 0.5, events[0].Threshold)
+	require.Equal(t, 600, events[0].UsedTokens)
+
+	require.Equal(t, alert.EventThresholdCrossed, events[1].Type)
+	require.Equal(t, 1.0, events[1].Threshold)
+	require.Equal(t, 1200, events[1].UsedTokens)
+
+	require.Equal(t, alert.EventResolved, events[2].Type)
+	require.Equal(t, 1.0, events[2].Threshold)
+	require.Equal(t, time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), events[2].WindowStart)
+	w.requireValid(t)
+}
+
+func TestAlerter_Retry(t *testing.T) {
+	t.Parallel()
+
+	w := newWebhook(t)
+	w.failures.Store(2)
+
+	a := newAlerter(t, w.URL, "", 0)
+	run(t, a)
+
+	require.NoError(t, a.Observe("claude-sonnet-4-20250514", time.Now(), 900))
+
+	require.Eventually(t, func() bool { return len(w.received()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 0.8, w.received()[0].Threshold)
+	w.requireValid(t)
+}
+
+func TestAlerter_Outbox(t *testing.T) {
+	t.Parallel()
+
+	w := newWebhook(t)
+	w.failures.Store(1000)
+	outboxDir := t.TempDir()
+	now := time.Now()
+
+	// The first alerter cannot deliver before it is stopped, and must not run out of attempts either
+	first := newAlerter(t, w.URL, outboxDir, 1_000_000)
+	stop := run(t, first)
+	require.NoError(t, first.Observe("claude-sonnet-4-20250514", now, 900))
+	require.Eventually(t, func() bool { return w.failures.Load() < 999 }, 5*time.Second, 10*time.Millisecond)
+	stop()
+	require.Empty(t, w.received())
+
+	// The restarted alerter delivers the event and does not raise it again. Deliveries are at least once: the request
+	// in flight when the first alerter stopped may still reach the webhook, so the event can arrive twice.
+	w.failures.Store(0)
+	second := newAlerter(t, w.URL, outboxDir, 0)
+	require.NoError(t, second.Observe("claude-sonnet-4-20250514", now.Add(time.Second), 900))
+	run(t, second)
+
+	require.Eventually(t, func() bool { return len(w.received()) > 0 }, 5*time.Second, 10*time.Millisecond)
+	require.Never(t, func() bool {
+		for _, event := range w.received() {
+			if event.ID != w.received()[0].ID {
+				return true
+			}
+		}
+		return false
+	}, 100*time.Millisecond, 10*time.Millisecond)
+	require.Equal(t, 0.8, w.received()[0].Threshold)
+	w.requireValid(t)
+}
+
+func TestAlerter_GiveUp(t *testing.T) {
+	t.Parallel()
+
+	w := newWebhook(t)
+	w.failures.Store(1000)
+	outboxDir := t.TempDir()
+	now := time.Now()
+
+	a := newAlerter(t, w.URL, outboxDir, 2)
+	run(t, a)
+
+	require.NoError(t, a.Observe("claude-sonnet-4-20250514", now, 900))
+	require.Eventually(t, func() bool {
+		deliveries, _ := filepath.Glob(filepath.Join(outboxDir, "outbox", "*.json"))
+		return w.failures.Load() <= 998 && len(deliveries) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(998), w.failures.Load())
+
+	// The threshold stays alerted on, so the lost alert is not raised again within the window
+	w.failures.Store(0)
+	require.NoError(t, a.Observe("claude-sonnet-4-20250514", now.Add(time.Second), 950))
+	require.Never(t, func() bool { return len(w.received()) > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+	w.requireValid(t)
+}
+
+func TestNew_Error(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]alert.Config{
+		"missing urls":       {Thresholds: []float64{0.8}, QuotaTokens: 1000},
+		"missing quota":      {Thresholds: []float64{0.8}, URLs: []string{"http://localhost"}},
+		"missing thresholds": {QuotaTokens: 1000, URLs: []string{"http://localhost"}},
+		"negative threshold": {Thresholds: []float64{-1, 0.8}, QuotaTokens: 1000, URLs: []string{"http://localhost"}},
+	}
+
+	for name, config := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := alert.New(config)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	body := []byte(`{"type":"threshold_crossed"}`)
+	header := alert.Sign(testSecret, now, body)
+
+	require.NoError(t, alert.VerifySignature(testSecret, header, body, time.Minute, now))
+	require.Error(t, alert.VerifySignature("other", header, body, time.Minute, now))
+	require.Error(t, alert.VerifySignature(testSecret, header, []byte(`{}`), time.Minute, now))
+	require.Error(t, alert.VerifySignature(testSecret, header, body, time.Minute, now.Add(time.Hour)))
+}
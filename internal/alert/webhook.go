@@ -0,0 +1,290 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"scrollwork/internal/logging"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// delivery is an event waiting to be delivered to a webhook.
+	delivery struct {
+		ID            string    `json:"id"`
+		URL           string    `json:"url"`
+		Event         Event     `json:"event"`
+		Attempts      int       `json:"attempts"`
+		NextAttemptAt time.Time `json:"next_attempt_at"`
+	}
+)
+
+const (
+	// SignatureHeader carries the signature of a delivery: t=<unix timestamp>,v1=<hex HMAC-SHA256>.
+	SignatureHeader = "Scrollwork-Signature"
+	// EventIDHeader carries the id of the delivered event. Deliveries are retried, so receivers should use it to
+	// discard duplicates.
+	EventIDHeader = "Scrollwork-Event-Id"
+
+	outboxDir = "outbox"
+)
+
+// Sign returns the SignatureHeader value of body sent at timestamp.
+//
+// The signature is the HMAC-SHA256 of the timestamp, a dot and the body, keyed by secret.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+
+	return "t=" + t + ",v1=" + signature(secret, t, body)
+}
+
+// VerifySignature checks the SignatureHeader value header of body. Signatures older than tolerance are rejected.
+func VerifySignature(secret string, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var t, v1 string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			v1 = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return fmt.Errorf("VerifySignature failed: invalid timestamp %q", t)
+	}
+
+	if tolerance > 0 && now.Sub(time.Unix(unix, 0)).Abs() > tolerance {
+		return fmt.Errorf("VerifySignature failed: timestamp is outside the tolerance of %s", tolerance)
+	}
+
+	if !hmac.Equal([]byte(v1), []byte(signature(secret, t, body))) {
+		return fmt.Errorf("VerifySignature failed: signature does not match")
+	}
+
+	return nil
+}
+
+func signature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run delivers queued events until ctx is done. Failed deliveries are retried with backoff and kept in the outbox
+// until they succeed or run out of attempts.
+func (a *Alerter) Run(ctx context.Context) {
+	for {
+		next := a.deliverDue(ctx, time.Now())
+
+		var timer *time.Timer
+		var retry <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			retry = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-a.wake:
+		case <-retry:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// deliverDue attempts every delivery due by now and returns when the next pending delivery is due.
+func (a *Alerter) deliverDue(ctx context.Context, now time.Time) time.Time {
+	a.mu.Lock()
+	var due []*delivery
+	for _, d := range a.deliveries {
+		if !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	a.mu.Unlock()
+
+	slices.SortFunc(due, func(x, y *delivery) int { return x.Event.OccurredAt.Compare(y.Event.OccurredAt) })
+
+	for _, d := range due {
+		if ctx.Err() != nil {
+			break
+		}
+
+		err := a.deliver(ctx, d)
+		if ctx.Err() != nil {
+			break
+		}
+
+		a.mu.Lock()
+		a.completeAttempt(d, err)
+		a.mu.Unlock()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var next time.Time
+	for _, d := range a.deliveries {
+		if next.IsZero() || d.NextAttemptAt.Before(next) {
+			next = d.NextAttemptAt
+		}
+	}
+
+	return next
+}
+
+// completeAttempt removes d from the outbox once it has been delivered or has run out of attempts. Otherwise it
+// schedules the next attempt.
+func (a *Alerter) completeAttempt(d *delivery, err error) {
+	logger := a.config.Logger.With("event_id", d.Event.ID, "url", d.URL)
+
+	if err == nil {
+		logger.Info("Alert delivered", "type", d.Event.Type, logging.KeyModel, d.Event.Model)
+		a.removeDelivery(d)
+		return
+	}
+
+	d.Attempts++
+	if d.Attempts >= a.config.MaxAttempts {
+		logger.Error("Alert delivery failed, giving up", "attempts", d.Attempts, logging.Err(err))
+		a.removeDelivery(d)
+		return
+	}
+
+	d.NextAttemptAt = time.Now().Add(a.config.Backoff.Delay(d.Attempts - 1))
+	logger.Warn("Alert delivery failed, retrying", "attempts", d.Attempts, "next_attempt_at", d.NextAttemptAt, logging.Err(err))
+	if err := a.saveDelivery(d); err != nil {
+		logger.Error("Alert delivery could not be saved to the outbox", logging.Err(err))
+	}
+}
+
+func (a *Alerter) deliver(ctx context.Context, d *delivery) error {
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, d.Event.ID)
+	if a.config.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(a.config.Secret, time.Now(), body))
+	}
+
+	resp, err := a.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+// enqueue queues event for delivery to every webhook.
+func (a *Alerter) enqueue(event Event, at time.Time) error {
+	var errs []error
+	for i, url := range a.config.URLs {
+		d := &delivery{
+			ID:            fmt.Sprintf("%s-%d", event.ID, i),
+			URL:           url,
+			Event:         event,
+			NextAttemptAt: at,
+		}
+		a.deliveries[d.ID] = d
+		errs = append(errs, a.saveDelivery(d))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (a *Alerter) saveDelivery(d *delivery) error {
+	if a.config.OutboxDir == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(a.deliveryPath(d), b)
+}
+
+func (a *Alerter) removeDelivery(d *delivery) {
+	delete(a.deliveries, d.ID)
+
+	if a.config.OutboxDir == "" {
+		return
+	}
+
+	if err := os.Remove(a.deliveryPath(d)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		a.config.Logger.Error("Alert delivery could not be removed from the outbox", "event_id", d.Event.ID, logging.Err(err))
+	}
+}
+
+func (a *Alerter) deliveryPath(d *delivery) string {
+	return filepath.Join(a.config.OutboxDir, outboxDir, d.ID+".json")
+}
+
+// loadOutbox loads undelivered events. Deliveries to webhooks that are no longer configured are discarded.
+func (a *Alerter) loadOutbox() error {
+	matches, err := filepath.Glob(filepath.Join(a.config.OutboxDir, outboxDir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, match := range matches {
+		b, err := os.ReadFile(match)
+		if err != nil {
+			return err
+		}
+
+		var d delivery
+		if err := json.Unmarshal(b, &d); err != nil {
+			return fmt.Errorf("%s: %w", match, err)
+		}
+
+		if !slices.Contains(a.config.URLs, d.URL) {
+			a.config.Logger.Warn("Alert delivery discarded, webhook is no longer configured", "event_id", d.Event.ID, "url", d.URL)
+			os.Remove(match)
+			continue
+		}
+
+		a.deliveries[d.ID] = &d
+	}
+
+	return nil
+}
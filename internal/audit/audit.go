@@ -0,0 +1,59 @@
+// Package audit records Scrollwork Agent decisions in a tamper-evident, append-only log.
+//
+// Every record is wrapped in an Entry whose hash covers the record and the hash of the previous entry. Editing,
+// removing or reordering an entry breaks the chain from that entry onwards, which Verify detects.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+type (
+	// Sink appends audit records.
+	Sink interface {
+		// Append appends record to the log. record must marshal to JSON.
+		Append(ctx context.Context, record any) error
+
+		// Close flushes every appended record to storage and closes the sink.
+		Close(ctx context.Context) error
+	}
+
+	// Entry is a single line of an audit log.
+	Entry struct {
+		Sequence uint64          `json:"seq"`
+		PrevHash string          `json:"prev_hash"`
+		Hash     string          `json:"hash"`
+		Record   json.RawMessage `json:"record"`
+	}
+)
+
+// GenesisHash is the previous hash of the first entry of an audit log.
+var GenesisHash = hex.EncodeToString(make([]byte, sha256.Size))
+
+// newEntry returns the entry following prev for record.
+func newEntry(prev Entry, record json.RawMessage) Entry {
+	e := Entry{
+		Sequence: prev.Sequence + 1,
+		PrevHash: prev.Hash,
+		Record:   record,
+	}
+	e.Hash = e.computeHash()
+
+	return e
+}
+
+// computeHash returns the SHA-256 of the entry's sequence, previous hash and record.
+func (e Entry) computeHash() string {
+	h := sha256.New()
+	h.Write([]byte(strconv.FormatUint(e.Sequence, 10)))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(e.PrevHash))
+	h.Write([]byte{'\n'})
+	h.Write(e.Record)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,104 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"scrollwork/internal/audit"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRecord struct {
+	Model     string `json:"model"`
+	RiskLevel string `json:"risk_level"`
+	Tokens    int    `json:"tokens"`
+}
+
+func appendRecords(t *testing.T, config audit.FileSinkConfig, n int) {
+	t.Helper()
+
+	sink, err := audit.NewFileSink(config)
+	require.NoError(t, err)
+
+	for i := range n {
+		require.NoError(t, sink.Append(context.Background(), testRecord{Model: "claude-sonnet-4-20250514", RiskLevel: "low", Tokens: i}))
+	}
+	require.NoError(t, sink.Close(context.Background()))
+}
+
+func TestFileSink(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	appendRecords(t, audit.FileSinkConfig{Path: path}, 3)
+
+	// Reopening the sink continues the chain
+	appendRecords(t, audit.FileSinkConfig{Path: path}, 2)
+
+	result, err := audit.Verify(path)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), result.Entries)
+	require.Equal(t, []string{path}, result.Files)
+	require.NotEqual(t, audit.GenesisHash, result.LastHash)
+}
+
+func TestFileSink_Rotate(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	appendRecords(t, audit.FileSinkConfig{Path: path, MaxBytes: 512}, 10)
+
+	files, err := audit.Files(path)
+	require.NoError(t, err)
+	require.Greater(t, len(files), 1)
+	require.Equal(t, path, files[len(files)-1])
+
+	result, err := audit.Verify(path)
+	require.NoError(t, err)
+	require.Equal(t, uint64(10), result.Entries)
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]func(lines [][]byte) [][]byte{
+		"modified record": func(lines [][]byte) [][]byte {
+			lines[1] = bytes.Replace(lines[1], []byte(`"risk_level":"low"`), []byte(`"risk_level":"high"`), 1)
+			return lines
+		},
+		"removed entry": func(lines [][]byte) [][]byte {
+			return append(lines[:1], lines[2:]...)
+		},
+		"reordered entries": func(lines [][]byte) [][]byte {
+			lines[0], lines[1] = lines[1], lines[0]
+			return lines
+		},
+		"truncated entry": func(lines [][]byte) [][]byte {
+			lines[2] = lines[2][:len(lines[2])/2]
+			return lines
+		},
+	}
+
+	for name, tamper := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+			appendRecords(t, audit.FileSinkConfig{Path: path}, 3)
+
+			b, err := os.ReadFile(path)
+			require.NoError(t, err)
+
+			lines := bytes.SplitAfter(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+			require.NoError(t, os.WriteFile(path, bytes.Join(tamper(lines), nil), 0o640))
+
+			_, err = audit.Verify(path)
+			require.Error(t, err)
+		})
+	}
+}
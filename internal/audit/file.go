@@ -0,0 +1,286 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	FileSinkConfig struct {
+		// Path is the JSONL file records are appended to.
+		Path string
+
+		// MaxBytes is the size past which the file is rotated. Defaults to 100 MiB.
+		MaxBytes int64
+	}
+
+	// FileSink appends audit records to a JSONL file. Once the file grows past MaxBytes it is renamed with the time
+	// of rotation appended to its name and a new file is started. The hash chain continues across rotated files.
+	FileSink struct {
+		config FileSinkConfig
+
+		file *os.File
+		size int64
+		last Entry
+		mu   sync.Mutex
+	}
+
+	// VerifyResult summarizes an audit log that was successfully verified.
+	VerifyResult struct {
+		Files    []string
+		Entries  uint64
+		LastHash string
+	}
+)
+
+const (
+	defaultMaxBytes = 100 * 1024 * 1024
+
+	rotatedTimeFormat = "20060102T150405.000000000Z"
+)
+
+var errSinkClosed = errors.New("audit sink is closed")
+
+// NewFileSink opens the audit log at config.Path, creating it if needed, and resumes its hash chain.
+func NewFileSink(config FileSinkConfig) (*FileSink, error) {
+	if config.Path == "" {
+		return nil, fmt.Errorf("NewFileSink failed: missing path")
+	}
+
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = defaultMaxBytes
+	}
+
+	files, err := Files(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("NewFileSink failed: %w", err)
+	}
+
+	last := Entry{Hash: GenesisHash}
+	for i := len(files) - 1; i >= 0; i-- {
+		entry, ok, err := lastEntry(files[i])
+		if err != nil {
+			return nil, fmt.Errorf("NewFileSink failed: %w", err)
+		}
+
+		if ok {
+			last = entry
+			break
+		}
+	}
+
+	s := &FileSink{config: config, last: last}
+	if err := s.open(); err != nil {
+		return nil, fmt.Errorf("NewFileSink failed: %w", err)
+	}
+
+	return s, nil
+}
+
+// Append appends record to the audit log, rotating the file first if it would grow past MaxBytes.
+func (s *FileSink) Append(ctx context.Context, record any) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("Append failed: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("Append failed: %w", errSinkClosed)
+	}
+
+	entry := newEntry(s.last, b)
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("Append failed: %w", err)
+	}
+	line = append(line, '\n')
+
+	if s.size > 0 && s.size+int64(len(line)) > s.config.MaxBytes {
+		if err := s.rotate(); err != nil {
+			return fmt.Errorf("Append failed: %w", err)
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("Append failed: %w", err)
+	}
+
+	s.last = entry
+
+	return nil
+}
+
+// Close syncs the audit log to disk and closes it.
+func (s *FileSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := errors.Join(s.file.Sync(), s.file.Close())
+	s.file = nil
+	if err != nil {
+		return fmt.Errorf("Close failed: %w", err)
+	}
+
+	return nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	s.file = f
+	s.size = info.Size()
+
+	return nil
+}
+
+func (s *FileSink) rotate() error {
+	if err := errors.Join(s.file.Sync(), s.file.Close()); err != nil {
+		return err
+	}
+	s.file = nil
+
+	rotated := s.config.Path + "." + time.Now().UTC().Format(rotatedTimeFormat)
+	if err := os.Rename(s.config.Path, rotated); err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+// Files returns the rotated files of the audit log at path, oldest first, followed by path itself if it exists.
+func Files(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, fmt.Errorf("Files failed: %w", err)
+	}
+
+	var files []string
+	for _, match := range matches {
+		if _, err := time.Parse(rotatedTimeFormat, strings.TrimPrefix(match, path+".")); err == nil {
+			files = append(files, match)
+		}
+	}
+	sort.Strings(files)
+
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("Files failed: %w", err)
+	}
+
+	return files, nil
+}
+
+// Verify checks the hash chain of the audit log at path across all of its rotated files.
+//
+// The returned error identifies the first file and line where the chain is broken.
+func Verify(path string) (VerifyResult, error) {
+	files, err := Files(path)
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("Verify failed: %w", err)
+	}
+
+	if len(files) == 0 {
+		return VerifyResult{}, fmt.Errorf("Verify failed: no audit log found at %s", path)
+	}
+
+	result := VerifyResult{Files: files, LastHash: GenesisHash}
+	prev := Entry{Hash: GenesisHash}
+	for _, file := range files {
+		err := readEntries(file, func(line int, entry Entry) error {
+			switch {
+			case entry.Sequence != prev.Sequence+1:
+				return fmt.Errorf("%s:%d: expected sequence %d, got %d", file, line, prev.Sequence+1, entry.Sequence)
+			case entry.PrevHash != prev.Hash:
+				return fmt.Errorf("%s:%d: entry %d does not follow entry %d", file, line, entry.Sequence, prev.Sequence)
+			case entry.Hash != entry.computeHash():
+				return fmt.Errorf("%s:%d: entry %d has been modified", file, line, entry.Sequence)
+			}
+
+			prev = entry
+			return nil
+		})
+		if err != nil {
+			return VerifyResult{}, fmt.Errorf("Verify failed: %w", err)
+		}
+	}
+
+	result.Entries = prev.Sequence
+	result.LastHash = prev.Hash
+
+	return result, nil
+}
+
+// lastEntry returns the last entry of file. It returns false if file has no entries.
+func lastEntry(file string) (Entry, bool, error) {
+	var last Entry
+	var ok bool
+	err := readEntries(file, func(_ int, entry Entry) error {
+		last = entry
+		ok = true
+		return nil
+	})
+
+	return last, ok, err
+}
+
+// readEntries calls fn with every entry of file and its line number.
+func readEntries(file string, fn func(line int, entry Entry) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(b) > 0 {
+				return fmt.Errorf("%s:%d: incomplete entry", file, line)
+			}
+
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(b, &entry); err != nil {
+			return fmt.Errorf("%s:%d: invalid entry: %w", file, line, err)
+		}
+
+		if err := fn(line, entry); err != nil {
+			return err
+		}
+	}
+}
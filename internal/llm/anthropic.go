@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -23,9 +24,27 @@ type (
 		Results    []messageUsage `json:"results"`
 	}
 
+	anthropicCountTokensRequest struct {
+		Model    string             `json:"model"`
+		System   Content            `json:"system,omitempty"`
+		Messages []anthropicMessage `json:"messages"`
+		Tools    []Tool             `json:"tools,omitempty"`
+	}
+
+	anthropicMessage struct {
+		Role    MessageRole `json:"role"`
+		Content Content     `json:"content"`
+	}
+
 	messageUsage struct {
-		Model               string `json:"model"`
-		UncachedInputTokens int    `json:"uncached_input_tokens"`
+		Model                string `json:"model"`
+		UncachedInputTokens  int    `json:"uncached_input_tokens"`
+		CacheReadInputTokens int    `json:"cache_read_input_tokens"`
+		CacheCreation        struct {
+			Ephemeral1hInputTokens int `json:"ephemeral_1h_input_tokens"`
+			Ephemeral5mInputTokens int `json:"ephemeral_5m_input_tokens"`
+		} `json:"cache_creation"`
+		OutputTokens int `json:"output_tokens"`
 	}
 )
 
@@ -33,11 +52,19 @@ const (
 	anthropicVersion                   = "2023-06-01"
 	organizationInfoPath               = "/v1/organizations/me"
 	organizationMessagsUsageReportPath = "/v1/organizations/usage_report/messages"
+	countTokensPath                    = "/v1/messages/count_tokens"
+
+	// usageReportPageLimit is the maximum number of daily buckets returned per usage report page.
+	usageReportPageLimit = 31
 )
 
+// AnthropicClient is the Provider of models served by the Anthropic API.
+var _ Provider = (*AnthropicClient)(nil)
+
 func NewAnthropicClient(apiKey string, adminKey string) *AnthropicClient {
 	messagesClient := anthropic.NewClient(option.WithAPIKey(apiKey))
-	adminClient := anthropic.NewClient(option.WithAPIKey(adminKey))
+	// Usage fetches are retried by the usage worker
+	adminClient := anthropic.NewClient(option.WithAPIKey(adminKey), option.WithMaxRetries(0))
 
 	return &AnthropicClient{
 		messagesClient: &messagesClient,
@@ -46,6 +73,10 @@ func NewAnthropicClient(apiKey string, adminKey string) *AnthropicClient {
 	}
 }
 
+func (a *AnthropicClient) Name() string {
+	return "anthropic"
+}
+
 // HealthCheck fetches the current organization. It is used to verify the API Key and AnthropicClient.
 func (a *AnthropicClient) HealthCheck(ctx context.Context) error {
 	if a.adminClient == nil {
@@ -64,53 +95,94 @@ func (a *AnthropicClient) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
-// GetOrganizationMessageUsageReport fetches the current number of uncached input tokens for all messages by model.
-func (a *AnthropicClient) GetOrganizationMessageUsageReport(ctx context.Context) (map[string]int, error) {
-	usage := make(map[string]int)
+// GetOrganizationMessageUsageReport fetches the token usage for all messages by model between startingAt and endingAt.
+func (a *AnthropicClient) GetOrganizationMessageUsageReport(ctx context.Context, startingAt time.Time, endingAt time.Time) (map[string]TokenUsage, error) {
+	usage := make(map[string]TokenUsage)
 
 	if a.adminClient == nil {
 		return usage, fmt.Errorf("GetOrganizationMessageUsageReport failed: anthropic admin client is nil")
 	}
 
-	startingAt := time.Now().Truncate(24 * time.Hour).Format(time.RFC3339)
-	endingAt := time.Now().Add(24 * time.Hour).Truncate(24 * time.Hour).Format(time.RFC3339)
-
 	// TODO: This data is assuming an anthropic shape but OpenAI is slightly different
 	// https://platform.openai.com/docs/api-reference/usage/completions
 	// UsageData should be our shape of data that is built using specific responses from Anthropic or OpenAI
 	d := struct {
-		Data []usageData `json:"data"`
+		Data     []usageData `json:"data"`
+		HasMore  bool        `json:"has_more"`
+		NextPage string      `json:"next_page"`
 	}{}
 
 	q := url.Values{}
-	q.Add("starting_at", startingAt)
-	q.Add("ending_at", endingAt)
-	qs := q.Encode()
+	q.Add("starting_at", startingAt.UTC().Format(time.RFC3339))
+	q.Add("ending_at", endingAt.UTC().Format(time.RFC3339))
+	q.Add("bucket_width", "1d")
+	// Results are only attributed to a model when grouped by model
+	q.Add("group_by[]", "model")
+	q.Add("limit", strconv.Itoa(usageReportPageLimit))
+
+	for {
+		path := organizationMessagsUsageReportPath + "?" + q.Encode()
+
+		d.Data, d.HasMore, d.NextPage = nil, false, ""
+		err := a.adminClient.Get(ctx, path, nil, &d)
+		if err != nil {
+			return usage, err
+		}
 
-	path := organizationMessagsUsageReportPath + "?" + qs
+		for _, d := range d.Data {
+			for _, result := range d.Results {
+				u := usage[result.Model]
+				u.UncachedInput += result.UncachedInputTokens
+				u.CacheReadInput += result.CacheReadInputTokens
+				u.CacheCreationInput += result.CacheCreation.Ephemeral1hInputTokens + result.CacheCreation.Ephemeral5mInputTokens
+				u.Output += result.OutputTokens
+				usage[result.Model] = u
+			}
+		}
 
-	err := a.adminClient.Get(ctx, path, nil, &d)
-	if err != nil {
-		return usage, err
+		if !d.HasMore || d.NextPage == "" {
+			return usage, nil
+		}
+
+		q.Set("page", d.NextPage)
 	}
+}
+
+// Usage fetches the organization's message usage report. Usage is reported for every model used by the
+// organization, not only models.
+func (a *AnthropicClient) Usage(ctx context.Context, models []string, startingAt time.Time, endingAt time.Time) (map[string]TokenUsage, error) {
+	return a.GetOrganizationMessageUsageReport(ctx, startingAt, endingAt)
+}
 
-	if len(d.Data) == 0 {
-		return usage, nil
+// CountTokens counts the input tokens of prompt with the Anthropic token counting endpoint.
+func (a *AnthropicClient) CountTokens(ctx context.Context, model string, prompt Prompt) (int, error) {
+	if a.messagesClient == nil {
+		return 0, fmt.Errorf("CountTokens failed: anthropic messages client is nil")
 	}
 
-	for _, d := range d.Data {
-		for _, result := range d.Results {
-			usage[result.Model] += result.UncachedInputTokens
-		}
+	var resp struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := a.messagesClient.Post(ctx, countTokensPath, newAnthropicCountTokensRequest(model, prompt), &resp); err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
 	}
 
-	return usage, nil
+	return resp.InputTokens, nil
 }
 
-func (a *AnthropicClient) CountTokens(ctx context.Context, messages []Message) (int, error) {
-	if a.messagesClient == nil {
-		return 0, fmt.Errorf("CountTokens failed: anthropic messages client is nil")
+// newAnthropicCountTokensRequest returns the token counting request of prompt. Message names are not part of the
+// Messages API and are left out.
+func newAnthropicCountTokensRequest(model string, prompt Prompt) anthropicCountTokensRequest {
+	req := anthropicCountTokensRequest{
+		Model:    model,
+		System:   prompt.System,
+		Messages: make([]anthropicMessage, 0, len(prompt.Messages)),
+		Tools:    prompt.Tools,
+	}
+
+	for _, message := range prompt.Messages {
+		req.Messages = append(req.Messages, anthropicMessage{Role: message.Role, Content: message.Content})
 	}
 
-	return 0, nil
+	return req
 }
@@ -0,0 +1,91 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"scrollwork/internal/llm"
+)
+
+func TestAnthropicClient_CountTokens(t *testing.T) {
+	api := newFakeAPI(t, map[string]fakeRoute{
+		"/v1/messages/count_tokens": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			check.Equal("test-key", r.Header.Get("X-Api-Key"))
+
+			var body map[string]json.RawMessage
+			check.NoError(json.NewDecoder(r.Body).Decode(&body))
+			check.JSONEq(`"claude-sonnet-4-20250514"`, string(body["model"]))
+			check.JSONEq(`[{"type": "text", "text": "Be brief."}]`, string(body["system"]))
+			// The Messages API rejects message names
+			check.JSONEq(`[{"role": "user", "content": [{"type": "text", "text": "What is the weather in Paris?"}]}]`, string(body["messages"]))
+			check.JSONEq(`[{"name": "get_weather", "input_schema": {"type": "object"}}]`, string(body["tools"]))
+
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"input_tokens": 412}`)
+		},
+	})
+
+	// The SDK reads its base URL from the environment
+	t.Setenv("ANTHROPIC_BASE_URL", api.URL)
+	client := llm.NewAnthropicClient("test-key", "test-admin-key")
+
+	tokens, err := client.CountTokens(context.Background(), "claude-sonnet-4-20250514", llm.Prompt{
+		System:   llm.TextContent("Be brief."),
+		Messages: []llm.Message{{Role: llm.MessageRoleUser, Name: "alice", Content: llm.TextContent("What is the weather in Paris?")}},
+		Tools:    []llm.Tool{{Name: "get_weather", InputSchema: json.RawMessage(`{"type": "object"}`)}},
+	})
+	api.requireMatched(t)
+	require.NoError(t, err)
+	require.Equal(t, 412, tokens)
+}
+
+func TestAnthropicClient_Usage(t *testing.T) {
+	api := newFakeAPI(t, map[string]fakeRoute{
+		"/v1/organizations/usage_report/messages": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			check.Equal("test-admin-key", r.Header.Get("X-Api-Key"))
+
+			q := r.URL.Query()
+			check.Equal([]string{"model"}, q["group_by[]"])
+			check.Equal("1d", q.Get("bucket_width"))
+			check.Equal("2025-06-01T00:00:00Z", q.Get("starting_at"))
+			check.Equal("2025-06-03T00:00:00Z", q.Get("ending_at"))
+
+			w.Header().Set("Content-Type", "application/json")
+			switch q.Get("page") {
+			case "":
+				fmt.Fprint(w, `{"data": [{"starting_at": "2025-06-01T00:00:00Z", "ending_at": "2025-06-02T00:00:00Z", "results": [
+					{"model": "claude-sonnet-4-20250514", "uncached_input_tokens": 100, "cache_read_input_tokens": 10, "cache_creation": {"ephemeral_1h_input_tokens": 1, "ephemeral_5m_input_tokens": 2}, "output_tokens": 50},
+					{"model": "claude-opus-4-1-20250805", "uncached_input_tokens": 7, "output_tokens": 3}
+				]}], "has_more": true, "next_page": "page-2"}`)
+			case "page-2":
+				fmt.Fprint(w, `{"data": [{"starting_at": "2025-06-02T00:00:00Z", "ending_at": "2025-06-03T00:00:00Z", "results": [
+					{"model": "claude-sonnet-4-20250514", "uncached_input_tokens": 200, "cache_read_input_tokens": 20, "cache_creation": {"ephemeral_5m_input_tokens": 4}, "output_tokens": 60}
+				]}], "has_more": false, "next_page": null}`)
+			default:
+				check.Fail("unexpected page", q.Get("page"))
+			}
+		},
+	})
+
+	// The SDK reads its base URL from the environment
+	t.Setenv("ANTHROPIC_BASE_URL", api.URL)
+	client := llm.NewAnthropicClient("test-key", "test-admin-key")
+
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	usage, err := client.Usage(context.Background(), []string{"claude-sonnet-4-20250514"}, start, start.Add(48*time.Hour))
+	api.requireMatched(t)
+	require.NoError(t, err)
+
+	// Every page is summed by model
+	require.Equal(t, map[string]llm.TokenUsage{
+		"claude-sonnet-4-20250514": {UncachedInput: 300, CacheReadInput: 30, CacheCreationInput: 7, Output: 110},
+		"claude-opus-4-1-20250805": {UncachedInput: 7, Output: 3},
+	}, usage)
+}
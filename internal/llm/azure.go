@@ -0,0 +1,275 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+type (
+	AzureConfig struct {
+		// Endpoint is the Azure OpenAI resource, such as https://my-resource.openai.azure.com.
+		Endpoint string
+		APIKey   string
+		// APIVersion is the Azure OpenAI API version requests are made with. Defaults to 2024-10-21.
+		APIVersion string
+
+		// Deployments maps each deployment name to the catalogued model it serves, such as gpt-4o-2024-08-06. Prompts
+		// are tokenized and priced as the mapped model.
+		Deployments map[string]string
+
+		// ResourceID is the Azure resource id of the Azure OpenAI resource usage metrics are read from, such as
+		// /subscriptions/<id>/resourceGroups/<group>/providers/Microsoft.CognitiveServices/accounts/<name>.
+		ResourceID string
+		// TenantID, ClientID and ClientSecret are the Microsoft Entra ID service principal usage metrics are read
+		// with. It needs the Monitoring Reader role on the resource.
+		TenantID     string
+		ClientID     string
+		ClientSecret string
+
+		// ManagementEndpoint is the Azure Resource Manager API. Defaults to https://management.azure.com.
+		ManagementEndpoint string
+		// TokenEndpoint is where client credentials are exchanged for access tokens.
+		// Defaults to https://login.microsoftonline.com/<tenant>/oauth2/v2.0/token.
+		TokenEndpoint string
+
+		HTTPClient *http.Client
+	}
+
+	// AzureClient is the Provider of OpenAI models deployed to Azure OpenAI.
+	//
+	// Azure OpenAI routes requests by deployment name rather than model id, so every deployment is mapped to the model
+	// it serves. Azure OpenAI has no token counting endpoint, and prompts are counted offline. Usage is read from the
+	// token metrics Azure Monitor keeps for each deployment.
+	AzureClient struct {
+		config AzureConfig
+		tokens *oauthTokenSource
+	}
+
+	azureMetricsResponse struct {
+		Value []struct {
+			Name struct {
+				Value string `json:"value"`
+			} `json:"name"`
+			Timeseries []struct {
+				MetadataValues []struct {
+					Name struct {
+						Value string `json:"value"`
+					} `json:"name"`
+					Value string `json:"value"`
+				} `json:"metadatavalues"`
+				Data []struct {
+					Total float64 `json:"total"`
+				} `json:"data"`
+			} `json:"timeseries"`
+		} `json:"value"`
+	}
+)
+
+const (
+	defaultAzureAPIVersion = "2024-10-21"
+	azureMetricsAPIVersion = "2023-10-01"
+	azureManagementScope   = "https://management.azure.com/.default"
+
+	azurePromptTokensMetric    = "ProcessedPromptTokens"
+	azureGeneratedTokensMetric = "GeneratedTokens"
+	azureDeploymentDimension   = "ModelDeploymentName"
+)
+
+var (
+	_ Provider = (*AzureClient)(nil)
+	_ Pricer   = (*AzureClient)(nil)
+)
+
+// NewAzureClient returns an AzureClient.
+func NewAzureClient(config AzureConfig) (*AzureClient, error) {
+	if config.Endpoint == "" || config.APIKey == "" {
+		return nil, fmt.Errorf("NewAzureClient failed: missing endpoint or API key")
+	}
+
+	for deployment, model := range config.Deployments {
+		m, err := ParseModel(model)
+		if err != nil {
+			return nil, fmt.Errorf("NewAzureClient failed: deployment %s: %w", deployment, err)
+		}
+
+		if m.Platform != PlatformOpenAI {
+			return nil, fmt.Errorf("NewAzureClient failed: deployment %s must serve an OpenAI model, got %s", deployment, model)
+		}
+	}
+
+	if config.ResourceID == "" || config.TenantID == "" || config.ClientID == "" || config.ClientSecret == "" {
+		return nil, fmt.Errorf("NewAzureClient failed: usage requires a resource id and service principal credentials")
+	}
+
+	if config.APIVersion == "" {
+		config.APIVersion = defaultAzureAPIVersion
+	}
+
+	if config.ManagementEndpoint == "" {
+		config.ManagementEndpoint = "https://management.azure.com"
+	}
+
+	if config.TokenEndpoint == "" {
+		config.TokenEndpoint = "https://login.microsoftonline.com/" + url.PathEscape(config.TenantID) + "/oauth2/v2.0/token"
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &AzureClient{
+		config: config,
+		tokens: newClientCredentialsTokenSource(config.TokenEndpoint, config.ClientID, config.ClientSecret, azureManagementScope, config.HTTPClient),
+	}, nil
+}
+
+func (a *AzureClient) Name() string {
+	return "azure"
+}
+
+// HealthCheck lists the models of the resource with the API key and exchanges the service principal credentials for
+// an access token.
+func (a *AzureClient) HealthCheck(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.apiURL("/openai/models"), nil)
+	if err != nil {
+		return fmt.Errorf("HealthCheck failed: %w", err)
+	}
+	req.Header.Set("api-key", a.config.APIKey)
+
+	if _, err := doRequest(a.config.HTTPClient, req); err != nil {
+		return fmt.Errorf("HealthCheck failed: %w", err)
+	}
+
+	if _, err := a.tokens.Token(ctx); err != nil {
+		return fmt.Errorf("HealthCheck failed: %w", err)
+	}
+
+	return nil
+}
+
+// CountTokens counts the input tokens of prompt offline with the tokenizer of the model a deployment serves.
+func (a *AzureClient) CountTokens(ctx context.Context, model string, prompt Prompt) (int, error) {
+	m, err := a.deploymentModel(model)
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	return TokenizerFor(m).CountTokens(prompt), nil
+}
+
+// Pricing returns the list price of the model a deployment serves.
+func (a *AzureClient) Pricing(model string) (Pricing, bool) {
+	m, err := a.deploymentModel(model)
+	if err != nil {
+		return Pricing{}, false
+	}
+
+	return LookupPricing(m.Key)
+}
+
+// Usage sums the prompt and generated token metrics of each deployment between startingAt and endingAt.
+func (a *AzureClient) Usage(ctx context.Context, models []string, startingAt time.Time, endingAt time.Time) (map[string]TokenUsage, error) {
+	usage := make(map[string]TokenUsage, len(models))
+
+	token, err := a.tokens.Token(ctx)
+	if err != nil {
+		return usage, fmt.Errorf("Usage failed: %w", err)
+	}
+
+	q := url.Values{}
+	q.Set("api-version", azureMetricsAPIVersion)
+	q.Set("metricnames", azurePromptTokensMetric+","+azureGeneratedTokensMetric)
+	q.Set("timespan", startingAt.UTC().Format(time.RFC3339)+"/"+endingAt.UTC().Format(time.RFC3339))
+	q.Set("interval", "PT1H")
+	q.Set("aggregation", "Total")
+	q.Set("$filter", azureDeploymentDimension+" eq '*'")
+
+	endpoint := strings.TrimSuffix(a.config.ManagementEndpoint, "/") + "/" + strings.TrimPrefix(a.config.ResourceID, "/") + "/providers/Microsoft.Insights/metrics?" + q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return usage, fmt.Errorf("Usage failed: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	body, err := doRequest(a.config.HTTPClient, req)
+	if err != nil {
+		return usage, fmt.Errorf("Usage failed: %w", err)
+	}
+
+	var resp azureMetricsResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return usage, fmt.Errorf("Usage failed: %w", err)
+	}
+
+	for _, metric := range resp.Value {
+		for _, series := range metric.Timeseries {
+			var deployment string
+			for _, dimension := range series.MetadataValues {
+				if strings.EqualFold(dimension.Name.Value, azureDeploymentDimension) {
+					deployment = dimension.Value
+				}
+			}
+
+			model := AzureModelPrefix + deployment
+			if deployment == "" || !slices.Contains(models, model) {
+				continue
+			}
+
+			var total float64
+			for _, point := range series.Data {
+				total += point.Total
+			}
+
+			u := usage[model]
+			switch metric.Name.Value {
+			case azurePromptTokensMetric:
+				u.UncachedInput += int(math.Round(total))
+			case azureGeneratedTokensMetric:
+				u.Output += int(math.Round(total))
+			}
+			usage[model] = u
+		}
+	}
+
+	return usage, nil
+}
+
+// deploymentModel returns the catalogued model the deployment of an azure/<deployment> id serves.
+func (a *AzureClient) deploymentModel(model string) (Model, error) {
+	deployment := strings.TrimPrefix(model, AzureModelPrefix)
+	mapped, ok := a.config.Deployments[deployment]
+	if !ok {
+		return Model{}, fmt.Errorf("deployment %s is not mapped to a model", deployment)
+	}
+
+	return ParseModel(mapped)
+}
+
+// apiURL returns the URL of an Azure OpenAI API path with the configured api-version.
+func (a *AzureClient) apiURL(path string) string {
+	return strings.TrimSuffix(a.config.Endpoint, "/") + path + "?api-version=" + url.QueryEscape(a.config.APIVersion)
+}
+
+// newClientCredentialsTokenSource returns a token source that exchanges client credentials for access tokens to scope.
+func newClientCredentialsTokenSource(tokenURL string, clientID string, clientSecret string, scope string, httpClient *http.Client) *oauthTokenSource {
+	return &oauthTokenSource{
+		tokenURL:   tokenURL,
+		httpClient: httpClient,
+		grant: func(time.Time) (url.Values, error) {
+			form := url.Values{}
+			form.Set("grant_type", "client_credentials")
+			form.Set("client_id", clientID)
+			form.Set("client_secret", clientSecret)
+			form.Set("scope", scope)
+
+			return form, nil
+		},
+	}
+}
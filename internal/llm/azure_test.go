@@ -0,0 +1,147 @@
+package llm_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"scrollwork/internal/llm"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	azureModel      = "azure/gpt-4o-prod"
+	azureResourceID = "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.CognitiveServices/accounts/scrollwork"
+)
+
+// newAzureFake returns a client talking to a local fake of the Azure OpenAI, Entra ID token and Azure Monitor APIs,
+// along with the number of tokens exchanged.
+func newAzureFake(t *testing.T) (*llm.AzureClient, *fakeAPI, *atomic.Int32) {
+	t.Helper()
+
+	var exchanges atomic.Int32
+	api := newFakeAPI(t, map[string]fakeRoute{
+		"/token": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			check.NoError(r.ParseForm())
+			check.Equal("client_credentials", r.PostForm.Get("grant_type"))
+			check.Equal("client-id", r.PostForm.Get("client_id"))
+			check.Equal("client-secret", r.PostForm.Get("client_secret"))
+			check.Equal("https://management.azure.com/.default", r.PostForm.Get("scope"))
+
+			exchanges.Add(1)
+			fmt.Fprint(w, `{"access_token": "entra-token", "expires_in": 3600, "token_type": "Bearer"}`)
+		},
+		"/openai/models": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			check.Equal("test-key", r.Header.Get("api-key"))
+			check.Equal("2024-10-21", r.URL.Query().Get("api-version"))
+
+			fmt.Fprint(w, `{"data": []}`)
+		},
+		azureResourceID + "/providers/Microsoft.Insights/metrics": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			check.Equal("Bearer entra-token", r.Header.Get("Authorization"))
+			check.Equal("ProcessedPromptTokens,GeneratedTokens", r.URL.Query().Get("metricnames"))
+			check.Equal("ModelDeploymentName eq '*'", r.URL.Query().Get("$filter"))
+
+			fmt.Fprint(w, `{"value": [
+				{"name": {"value": "ProcessedPromptTokens"}, "timeseries": [
+					{"metadatavalues": [{"name": {"value": "modeldeploymentname"}, "value": "gpt-4o-prod"}], "data": [{"total": 1000}, {"total": 250}]},
+					{"metadatavalues": [{"name": {"value": "modeldeploymentname"}, "value": "other"}], "data": [{"total": 99999}]}
+				]},
+				{"name": {"value": "GeneratedTokens"}, "timeseries": [
+					{"metadatavalues": [{"name": {"value": "modeldeploymentname"}, "value": "gpt-4o-prod"}], "data": [{"total": 300}]}
+				]}
+			]}`)
+		},
+	})
+
+	client, err := llm.NewAzureClient(llm.AzureConfig{
+		Endpoint:           api.URL,
+		APIKey:             "test-key",
+		Deployments:        map[string]string{"gpt-4o-prod": "gpt-4o"},
+		ResourceID:         azureResourceID,
+		TenantID:           "tenant",
+		ClientID:           "client-id",
+		ClientSecret:       "client-secret",
+		ManagementEndpoint: api.URL,
+		TokenEndpoint:      api.URL + "/token",
+	})
+	require.NoError(t, err)
+
+	return client, api, &exchanges
+}
+
+func TestAzureClient_Usage(t *testing.T) {
+	t.Parallel()
+
+	client, api, exchanges := newAzureFake(t)
+
+	now := time.Now()
+	usage, err := client.Usage(context.Background(), []string{azureModel}, now.Add(-time.Hour), now)
+	api.requireMatched(t)
+	require.NoError(t, err)
+	require.Equal(t, map[string]llm.TokenUsage{azureModel: {UncachedInput: 1250, Output: 300}}, usage)
+
+	// The access token is cached
+	_, err = client.Usage(context.Background(), []string{azureModel}, now.Add(-time.Hour), now)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), exchanges.Load())
+
+	require.NoError(t, client.HealthCheck(context.Background()))
+	api.requireMatched(t)
+}
+
+func TestAzureClient_Deployments(t *testing.T) {
+	t.Parallel()
+
+	client, _, _ := newAzureFake(t)
+	prompt := llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("Hello, world")}}}
+
+	tokens, err := client.CountTokens(context.Background(), azureModel, prompt)
+	require.NoError(t, err)
+	require.Equal(t, 9, tokens)
+
+	pricing, ok := client.Pricing(azureModel)
+	require.True(t, ok)
+	require.Equal(t, 2.5, pricing.UncachedInput)
+
+	_, err = client.CountTokens(context.Background(), "azure/unmapped", prompt)
+	require.Error(t, err)
+
+	_, ok = client.Pricing("azure/unmapped")
+	require.False(t, ok)
+}
+
+func TestNewAzureClient_Error(t *testing.T) {
+	t.Parallel()
+
+	valid := llm.AzureConfig{
+		Endpoint:     "http://localhost",
+		APIKey:       "test-key",
+		ResourceID:   azureResourceID,
+		TenantID:     "tenant",
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+	}
+
+	tc := map[string]func(c *llm.AzureConfig){
+		"missing api key":         func(c *llm.AzureConfig) { c.APIKey = "" },
+		"missing resource id":     func(c *llm.AzureConfig) { c.ResourceID = "" },
+		"unknown mapped model":    func(c *llm.AzureConfig) { c.Deployments = map[string]string{"prod": "gpt-9"} },
+		"non OpenAI mapped model": func(c *llm.AzureConfig) { c.Deployments = map[string]string{"prod": "claude-sonnet-4-20250514"} },
+	}
+
+	for name, mutate := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			config := valid
+			mutate(&config)
+			_, err := llm.NewAzureClient(config)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	BedrockConfig struct {
+		// Region is the AWS region models are invoked in, such as us-east-1.
+		Region      string
+		Credentials AWSCredentials
+
+		// RuntimeEndpoint is the Bedrock runtime API. Defaults to https://bedrock-runtime.<region>.amazonaws.com.
+		RuntimeEndpoint string
+		// CloudWatchEndpoint is the CloudWatch API usage metrics are read from.
+		// Defaults to https://monitoring.<region>.amazonaws.com.
+		CloudWatchEndpoint string
+
+		HTTPClient *http.Client
+	}
+
+	// BedrockClient is the Provider of Anthropic models served by Amazon Bedrock.
+	//
+	// Bedrock has no usage report. Usage is read from the token count metrics Bedrock publishes to CloudWatch for
+	// every model invocation.
+	BedrockClient struct {
+		config BedrockConfig
+	}
+
+	bedrockCountTokensRequest struct {
+		Input struct {
+			Converse bedrockConverseRequest `json:"converse"`
+		} `json:"input"`
+	}
+
+	bedrockConverseRequest struct {
+		System     []bedrockContentBlock `json:"system,omitempty"`
+		Messages   []bedrockMessage      `json:"messages"`
+		ToolConfig *bedrockToolConfig    `json:"toolConfig,omitempty"`
+	}
+
+	bedrockMessage struct {
+		Role    MessageRole           `json:"role"`
+		Content []bedrockContentBlock `json:"content"`
+	}
+
+	// bedrockContentBlock is a content block of the Converse API. Exactly one of its fields is set.
+	bedrockContentBlock struct {
+		Text       string             `json:"text,omitempty"`
+		Image      *bedrockMedia      `json:"image,omitempty"`
+		Document   *bedrockMedia      `json:"document,omitempty"`
+		ToolUse    *bedrockToolUse    `json:"toolUse,omitempty"`
+		ToolResult *bedrockToolResult `json:"toolResult,omitempty"`
+	}
+
+	bedrockMedia struct {
+		Format string `json:"format"`
+		Name   string `json:"name,omitempty"`
+		Source struct {
+			Bytes string `json:"bytes"`
+		} `json:"source"`
+	}
+
+	bedrockToolUse struct {
+		ToolUseID string          `json:"toolUseId"`
+		Name      string          `json:"name"`
+		Input     json.RawMessage `json:"input"`
+	}
+
+	bedrockToolResult struct {
+		ToolUseID string                `json:"toolUseId"`
+		Content   []bedrockContentBlock `json:"content"`
+		Status    string                `json:"status,omitempty"`
+	}
+
+	bedrockToolConfig struct {
+		Tools []bedrockTool `json:"tools"`
+	}
+
+	bedrockTool struct {
+		ToolSpec struct {
+			Name        string `json:"name"`
+			Description string `json:"description,omitempty"`
+			InputSchema struct {
+				JSON json.RawMessage `json:"json"`
+			} `json:"inputSchema"`
+		} `json:"toolSpec"`
+	}
+
+	getMetricStatisticsResponse struct {
+		Datapoints []struct {
+			Sum float64 `xml:"Sum"`
+		} `xml:"GetMetricStatisticsResult>Datapoints>member"`
+	}
+)
+
+const (
+	bedrockService    = "bedrock"
+	cloudWatchService = "monitoring"
+
+	cloudWatchVersion   = "2010-08-01"
+	bedrockMetricsSpace = "AWS/Bedrock"
+
+	// bedrockUsagePeriod is the granularity of CloudWatch datapoints. Hourly datapoints are kept for 455 days and
+	// keep a monthly window under the limit of 1440 datapoints per request.
+	bedrockUsagePeriod = time.Hour
+)
+
+// bedrockUsageMetrics are the CloudWatch metrics of each TokenCategory.
+var bedrockUsageMetrics = map[TokenCategory]string{
+	TokenCategoryUncachedInput:      "InputTokenCount",
+	TokenCategoryCacheReadInput:     "CacheReadInputTokenCount",
+	TokenCategoryCacheCreationInput: "CacheWriteInputTokenCount",
+	TokenCategoryOutput:             "OutputTokenCount",
+}
+
+// bedrockMediaFormats are the Converse formats of the MIME types whose subtype is not the format.
+var bedrockMediaFormats = map[string]string{
+	"text/plain":         "txt",
+	"text/markdown":      "md",
+	"application/msword": "doc",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": "docx",
+	"application/vnd.ms-excel": "xls",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": "xlsx",
+}
+
+var _ Provider = (*BedrockClient)(nil)
+
+// IsBedrockModel reports whether model is a Bedrock model id, such as anthropic.claude-sonnet-4-20250514-v1:0, or a
+// cross-region inference profile id, such as us.anthropic.claude-sonnet-4-20250514-v1:0.
+func IsBedrockModel(model string) bool {
+	m, err := ParseModel(model)
+	return err == nil && m.Platform == PlatformBedrock
+}
+
+// NewBedrockClient returns a BedrockClient.
+func NewBedrockClient(config BedrockConfig) (*BedrockClient, error) {
+	if config.Region == "" {
+		return nil, fmt.Errorf("NewBedrockClient failed: missing region")
+	}
+
+	if config.Credentials.AccessKeyID == "" || config.Credentials.SecretAccessKey == "" {
+		return nil, fmt.Errorf("NewBedrockClient failed: missing AWS credentials")
+	}
+
+	if config.RuntimeEndpoint == "" {
+		config.RuntimeEndpoint = "https://bedrock-runtime." + config.Region + ".amazonaws.com"
+	}
+
+	if config.CloudWatchEndpoint == "" {
+		config.CloudWatchEndpoint = "https://monitoring." + config.Region + ".amazonaws.com"
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &BedrockClient{config: config}, nil
+}
+
+func (b *BedrockClient) Name() string {
+	return "bedrock"
+}
+
+// HealthCheck lists the Bedrock metrics in CloudWatch. It is used to verify the AWS credentials.
+func (b *BedrockClient) HealthCheck(ctx context.Context) error {
+	q := url.Values{}
+	q.Set("Action", "ListMetrics")
+	q.Set("Namespace", bedrockMetricsSpace)
+
+	if _, err := b.cloudWatch(ctx, q); err != nil {
+		return fmt.Errorf("HealthCheck failed: %w", err)
+	}
+
+	return nil
+}
+
+// CountTokens counts the input tokens of prompt with the Bedrock CountTokens API. Blocks the Converse API cannot
+// take, such as images and documents behind a URL, are estimated with the model's offline tokenizer.
+func (b *BedrockClient) CountTokens(ctx context.Context, model string, prompt Prompt) (int, error) {
+	converse, offline := newBedrockConverseRequest(prompt)
+	if len(converse.Messages) == 0 {
+		// The Converse API requires a message, so a prompt without one it can take is estimated as a whole
+		return offlineTokenizer(model).CountTokens(prompt), nil
+	}
+
+	var body bedrockCountTokensRequest
+	body.Input.Converse = converse
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	u, err := url.Parse(b.config.RuntimeEndpoint)
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+	// Model ids contain a colon, which the Bedrock runtime expects to be escaped
+	basePath, baseRawPath := strings.TrimSuffix(u.Path, "/"), strings.TrimSuffix(u.EscapedPath(), "/")
+	u.Path = basePath + "/model/" + model + "/count-tokens"
+	u.RawPath = baseRawPath + "/model/" + uriEncode(model) + "/count-tokens"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	signV4(req, payload, b.config.Credentials, b.config.Region, bedrockService, time.Now())
+
+	respBody, err := doRequest(b.config.HTTPClient, req)
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	var resp struct {
+		InputTokens int `json:"inputTokens"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	return resp.InputTokens + offlineTokenizer(model).countContent(offline), nil
+}
+
+// newBedrockConverseRequest converts prompt into a Converse request. It also returns the content blocks the request
+// leaves out. Messages left without content are dropped.
+func newBedrockConverseRequest(prompt Prompt) (bedrockConverseRequest, Content) {
+	var req bedrockConverseRequest
+
+	system, offline := bedrockContent(prompt.System)
+	req.System = system
+
+	for _, message := range prompt.Messages {
+		content, left := bedrockContent(message.Content)
+		offline = append(offline, left...)
+		if len(content) == 0 {
+			continue
+		}
+
+		req.Messages = append(req.Messages, bedrockMessage{Role: message.Role, Content: content})
+	}
+
+	if len(prompt.Tools) > 0 {
+		req.ToolConfig = &bedrockToolConfig{}
+	}
+	for _, tool := range prompt.Tools {
+		var t bedrockTool
+		t.ToolSpec.Name = tool.Name
+		t.ToolSpec.Description = tool.Description
+		t.ToolSpec.InputSchema.JSON = tool.InputSchema
+		req.ToolConfig.Tools = append(req.ToolConfig.Tools, t)
+	}
+
+	return req, offline
+}
+
+// bedrockContent converts content blocks into Converse content blocks. The Converse API only takes images and
+// documents as bytes, and text documents are sent as text. Images and documents with other sources are returned as
+// the content left out.
+func bedrockContent(content Content) ([]bedrockContentBlock, Content) {
+	blocks := make([]bedrockContentBlock, 0, len(content))
+	var offline Content
+	for i, block := range content {
+		switch block.Type {
+		case ContentBlockText:
+			blocks = append(blocks, bedrockContentBlock{Text: block.Text})
+		case ContentBlockImage, ContentBlockDocument:
+			if block.Source != nil && block.Source.Type == SourceText {
+				blocks = append(blocks, bedrockContentBlock{Text: block.Source.Data})
+				continue
+			}
+
+			if block.Source == nil || block.Source.Type != SourceBase64 {
+				offline = append(offline, block)
+				continue
+			}
+
+			media := &bedrockMedia{Format: mediaFormat(block.Source.MediaType)}
+			media.Source.Bytes = block.Source.Data
+			if block.Type == ContentBlockImage {
+				blocks = append(blocks, bedrockContentBlock{Image: media})
+				continue
+			}
+
+			// Bedrock requires every document to be named
+			media.Name = block.Title
+			if media.Name == "" {
+				media.Name = "document-" + strconv.Itoa(i+1)
+			}
+			blocks = append(blocks, bedrockContentBlock{Document: media})
+		case ContentBlockToolUse:
+			input := block.Input
+			if len(input) == 0 {
+				input = json.RawMessage("{}")
+			}
+			blocks = append(blocks, bedrockContentBlock{ToolUse: &bedrockToolUse{ToolUseID: block.ID, Name: block.Name, Input: input}})
+		case ContentBlockToolResult:
+			content, left := bedrockContent(block.Content)
+			offline = append(offline, left...)
+
+			result := &bedrockToolResult{ToolUseID: block.ToolUseID, Content: content}
+			if block.IsError {
+				result.Status = "error"
+			}
+			blocks = append(blocks, bedrockContentBlock{ToolResult: result})
+		}
+	}
+
+	return blocks, offline
+}
+
+// mediaFormat returns the Converse format of a MIME type, which is its subtype, such as png for image/png, unless
+// Bedrock names the format differently.
+func mediaFormat(mediaType string) string {
+	if format, ok := bedrockMediaFormats[mediaType]; ok {
+		return format
+	}
+
+	_, format, _ := strings.Cut(mediaType, "/")
+	return format
+}
+
+// Usage sums the token count metrics of each model between startingAt and endingAt.
+func (b *BedrockClient) Usage(ctx context.Context, models []string, startingAt time.Time, endingAt time.Time) (map[string]TokenUsage, error) {
+	usage := make(map[string]TokenUsage, len(models))
+
+	for _, model := range models {
+		tokens := make(map[TokenCategory]int, len(bedrockUsageMetrics))
+		for category, metric := range bedrockUsageMetrics {
+			sum, err := b.metricSum(ctx, model, metric, startingAt, endingAt)
+			if err != nil {
+				return usage, fmt.Errorf("Usage failed: %s %s: %w", model, metric, err)
+			}
+			tokens[category] = sum
+		}
+
+		usage[model] = TokenUsage{
+			UncachedInput:      tokens[TokenCategoryUncachedInput],
+			CacheReadInput:     tokens[TokenCategoryCacheReadInput],
+			CacheCreationInput: tokens[TokenCategoryCacheCreationInput],
+			Output:             tokens[TokenCategoryOutput],
+		}
+	}
+
+	return usage, nil
+}
+
+func (b *BedrockClient) metricSum(ctx context.Context, model string, metric string, startingAt time.Time, endingAt time.Time) (int, error) {
+	q := url.Values{}
+	q.Set("Action", "GetMetricStatistics")
+	q.Set("Namespace", bedrockMetricsSpace)
+	q.Set("MetricName", metric)
+	q.Set("Dimensions.member.1.Name", "ModelId")
+	q.Set("Dimensions.member.1.Value", model)
+	q.Set("StartTime", startingAt.UTC().Format(time.RFC3339))
+	q.Set("EndTime", endingAt.UTC().Format(time.RFC3339))
+	q.Set("Period", strconv.Itoa(int(bedrockUsagePeriod.Seconds())))
+	q.Set("Statistics.member.1", "Sum")
+
+	body, err := b.cloudWatch(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+
+	var resp getMetricStatisticsResponse
+	if err := xml.Unmarshal(body, &resp); err != nil {
+		return 0, err
+	}
+
+	var sum float64
+	for _, datapoint := range resp.Datapoints {
+		sum += datapoint.Sum
+	}
+
+	return int(math.Round(sum)), nil
+}
+
+// cloudWatch calls a CloudWatch query API action.
+func (b *BedrockClient) cloudWatch(ctx context.Context, q url.Values) ([]byte, error) {
+	q.Set("Version", cloudWatchVersion)
+	payload := []byte(q.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.config.CloudWatchEndpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signV4(req, payload, b.config.Credentials, b.config.Region, cloudWatchService, time.Now())
+
+	return doRequest(b.config.HTTPClient, req)
+}
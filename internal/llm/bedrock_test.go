@@ -0,0 +1,206 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"scrollwork/internal/llm"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bedrockModel = "us.anthropic.claude-sonnet-4-20250514-v1:0"
+
+// newBedrockStandIn returns a client talking to a local stand-in for the Bedrock runtime and CloudWatch APIs.
+func newBedrockStandIn(t *testing.T) (*llm.BedrockClient, *fakeAPI) {
+	t.Helper()
+
+	checkSigned := func(r *http.Request, service string, check *assert.Assertions) {
+		auth := r.Header.Get("Authorization")
+		check.True(strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"), auth)
+		check.Contains(auth, "/us-east-1/"+service+"/aws4_request")
+		check.Contains(auth, "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token")
+		check.Equal("session", r.Header.Get("X-Amz-Security-Token"))
+	}
+
+	api := newFakeAPI(t, map[string]fakeRoute{
+		"/model/" + bedrockModel + "/count-tokens": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			checkSigned(r, "bedrock", check)
+			check.Equal("/model/us.anthropic.claude-sonnet-4-20250514-v1%3A0/count-tokens", r.URL.EscapedPath())
+
+			var body struct {
+				Input struct {
+					Converse struct {
+						Messages []struct {
+							Role    string `json:"role"`
+							Content []struct {
+								Text     string `json:"text"`
+								Document *struct {
+									Format string `json:"format"`
+								} `json:"document"`
+							} `json:"content"`
+						} `json:"messages"`
+					} `json:"converse"`
+				} `json:"input"`
+			}
+			if check.NoError(json.NewDecoder(r.Body).Decode(&body)) && check.Len(body.Input.Converse.Messages, 1) {
+				content := body.Input.Converse.Messages[0].Content
+				check.Equal("Hello, Claude", content[0].Text)
+				for _, block := range content[1:] {
+					check.NotNil(block.Document)
+					check.Equal("txt", block.Document.Format)
+				}
+			}
+
+			fmt.Fprint(w, `{"inputTokens": 12}`)
+		},
+		// CloudWatch
+		"/": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			checkSigned(r, "monitoring", check)
+
+			b, err := io.ReadAll(r.Body)
+			check.NoError(err)
+			q, err := url.ParseQuery(string(b))
+			check.NoError(err)
+
+			if q.Get("Action") == "ListMetrics" {
+				fmt.Fprint(w, `<ListMetricsResponse><ListMetricsResult><Metrics/></ListMetricsResult></ListMetricsResponse>`)
+				return
+			}
+
+			check.Equal("GetMetricStatistics", q.Get("Action"))
+			check.Equal(bedrockModel, q.Get("Dimensions.member.1.Value"))
+
+			sums := map[string]string{
+				"InputTokenCount":           "100",
+				"OutputTokenCount":          "40",
+				"CacheReadInputTokenCount":  "20",
+				"CacheWriteInputTokenCount": "10",
+			}
+			fmt.Fprintf(w, `<GetMetricStatisticsResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">
+  <GetMetricStatisticsResult>
+    <Datapoints>
+      <member><Sum>%[1]s</Sum><Unit>Count</Unit></member>
+      <member><Sum>%[1]s</Sum><Unit>Count</Unit></member>
+    </Datapoints>
+    <Label>%[2]s</Label>
+  </GetMetricStatisticsResult>
+</GetMetricStatisticsResponse>`, sums[q.Get("MetricName")], q.Get("MetricName"))
+		},
+	})
+
+	client, err := llm.NewBedrockClient(llm.BedrockConfig{
+		Region: "us-east-1",
+		Credentials: llm.AWSCredentials{
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			SessionToken:    "session",
+		},
+		RuntimeEndpoint:    api.URL,
+		CloudWatchEndpoint: api.URL,
+	})
+	require.NoError(t, err)
+
+	return client, api
+}
+
+func TestBedrockClient(t *testing.T) {
+	t.Parallel()
+
+	client, api := newBedrockStandIn(t)
+	ctx := context.Background()
+
+	require.NoError(t, client.HealthCheck(ctx))
+
+	tokens, err := client.CountTokens(ctx, bedrockModel, llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("Hello, Claude")}}})
+	api.requireMatched(t)
+	require.NoError(t, err)
+	require.Equal(t, 12, tokens)
+
+	now := time.Now()
+	usage, err := client.Usage(ctx, []string{bedrockModel}, now.Add(-time.Hour), now)
+	api.requireMatched(t)
+	require.NoError(t, err)
+	require.Equal(t, llm.TokenUsage{UncachedInput: 200, CacheReadInput: 40, CacheCreationInput: 20, Output: 80}, usage[bedrockModel])
+}
+
+func TestBedrockClient_CountTokens_Offline(t *testing.T) {
+	t.Parallel()
+
+	model, err := llm.ParseModel(bedrockModel)
+	require.NoError(t, err)
+	tokenizer := llm.TokenizerFor(model)
+
+	hello := llm.TextContent("Hello, Claude")
+	image := llm.ContentBlock{Type: llm.ContentBlockImage, Source: &llm.ContentSource{Type: llm.SourceURL, URL: "https://example.com/cat.png"}}
+	document := llm.ContentBlock{Type: llm.ContentBlockDocument, Source: &llm.ContentSource{Type: llm.SourceBase64, MediaType: "text/plain", Data: "SGVsbG8="}}
+	prompt := func(content ...llm.ContentBlock) llm.Prompt {
+		return llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: content}}}
+	}
+
+	tc := map[string]struct {
+		prompt   llm.Prompt
+		expected int
+	}{
+		"text document": {prompt: prompt(hello[0], document), expected: 12},
+		"url image": {
+			prompt:   prompt(hello[0], image),
+			expected: 12 + tokenizer.CountTokens(prompt(hello[0], image)) - tokenizer.CountTokens(prompt(hello[0])),
+		},
+		"nothing to send": {prompt: prompt(image), expected: tokenizer.CountTokens(prompt(image))},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			client, api := newBedrockStandIn(t)
+			tokens, err := client.CountTokens(context.Background(), bedrockModel, td.prompt)
+			api.requireMatched(t)
+			require.NoError(t, err)
+			require.Equal(t, td.expected, tokens)
+		})
+	}
+}
+
+func TestBedrockClient_Error(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		http.Error(w, `{"message":"Too many requests"}`, http.StatusTooManyRequests)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := llm.NewBedrockClient(llm.BedrockConfig{
+		Region:             "us-east-1",
+		Credentials:        llm.AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"},
+		RuntimeEndpoint:    server.URL,
+		CloudWatchEndpoint: server.URL,
+	})
+	require.NoError(t, err)
+
+	_, err = client.Usage(context.Background(), []string{bedrockModel}, time.Now().Add(-time.Hour), time.Now())
+	require.Error(t, err)
+
+	retryAfter, ok := llm.RetryAfter(err)
+	require.True(t, ok)
+	require.Equal(t, 3*time.Second, retryAfter)
+}
+
+func TestIsBedrockModel(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, llm.IsBedrockModel("anthropic.claude-opus-4-1-20250805-v1:0"))
+	require.True(t, llm.IsBedrockModel(bedrockModel))
+	require.False(t, llm.IsBedrockModel("claude-opus-4-1-20250805"))
+	require.False(t, llm.IsBedrockModel("claude-opus-4-1@20250805"))
+}
@@ -0,0 +1,226 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	ModelProviderAnthropic ModelProvider = "anthropic"
+	ModelProviderOpenAI    ModelProvider = "openai"
+	ModelProviderGoogle    ModelProvider = "google"
+	// ModelProviderSelfHosted is any model served by our own inference cluster.
+	ModelProviderSelfHosted ModelProvider = "self-hosted"
+
+	PlatformAnthropic Platform = "anthropic"
+	PlatformBedrock   Platform = "bedrock"
+	PlatformVertex    Platform = "vertex"
+	PlatformOpenAI    Platform = "openai"
+	PlatformGemini    Platform = "gemini"
+	PlatformLocal     Platform = "local"
+	PlatformAzure     Platform = "azure"
+
+	// LocalModelPrefix marks the ids of self-hosted models, such as local/meta-llama/Llama-3.1-8B-Instruct. The
+	// rest of the id is the name the model is served under.
+	LocalModelPrefix = "local/"
+	// AzureModelPrefix marks the ids of Azure OpenAI deployments, such as azure/gpt-4o-prod. The rest of the id is the
+	// deployment name, which is mapped to the model it serves by the Azure provider.
+	AzureModelPrefix = "azure/"
+)
+
+type (
+	// ModelProvider is the company that trained a model.
+	ModelProvider string
+
+	// Platform is the API a model is served by.
+	Platform string
+
+	// Model is a model id parsed with the catalog.
+	Model struct {
+		// ID is the model id as it was configured, such as us.anthropic.claude-sonnet-4-20250514-v1:0.
+		ID string
+		// Key is the canonical id of the model snapshot, such as claude-sonnet-4-20250514. It is shared by the
+		// aliases and platform specific ids of a snapshot, and is what usage, pricing and thresholds are keyed by.
+		Key string
+
+		Provider ModelProvider
+		// Family is the model line, such as sonnet or gpt-4o-mini.
+		Family string
+		// Version is the version of the family, such as 3.7 or 4.1. OpenAI families carry their version in their name.
+		Version string
+		// Date is the day the snapshot was released.
+		Date time.Time
+
+		Platform Platform
+		// Region is the geography of a Bedrock cross-region inference profile, such as us or eu.
+		Region string
+
+		// CacheMinTokens is the shortest prompt prefix the model writes to its prompt cache, or zero when it is not
+		// known.
+		CacheMinTokens int
+	}
+
+	catalogModel struct {
+		key      string
+		provider ModelProvider
+		family   string
+		version  string
+		date     time.Time
+		// aliases are the ids that resolve to the snapshot, such as claude-3-7-sonnet-latest.
+		aliases []string
+		pricing Pricing
+		// cacheMinTokens is the shortest prompt prefix the model caches. Models without prompt caching have none.
+		cacheMinTokens int
+	}
+)
+
+// ErrUnknownModel is returned for model ids that are not in the catalog.
+var ErrUnknownModel = errors.New("unknown model")
+
+var (
+	// bedrockModelID matches Bedrock model ids, such as anthropic.claude-sonnet-4-20250514-v1:0, and cross-region
+	// inference profile ids, such as us.anthropic.claude-sonnet-4-20250514-v1:0.
+	bedrockModelID = regexp.MustCompile(`^(?:([a-z-]+)\.)?anthropic\.(claude-[a-z0-9-]+?)-v\d+(?::\d+)?$`)
+	// vertexModelID matches Vertex AI model ids, such as claude-sonnet-4@20250514.
+	vertexModelID = regexp.MustCompile(`^(claude-[a-z0-9-]+)@(\d{8})$`)
+)
+
+var (
+	opus45Pricing  = Pricing{UncachedInput: 5, CacheReadInput: 0.5, CacheCreationInput: 6.25, Output: 25}
+	opusPricing    = Pricing{UncachedInput: 15, CacheReadInput: 1.5, CacheCreationInput: 18.75, Output: 75}
+	sonnetPricing  = Pricing{UncachedInput: 3, CacheReadInput: 0.3, CacheCreationInput: 3.75, Output: 15}
+	sonnet4Pricing = Pricing{
+		UncachedInput: 3, CacheReadInput: 0.3, CacheCreationInput: 3.75, Output: 15,
+		LongContext: &Pricing{UncachedInput: 6, CacheReadInput: 0.6, CacheCreationInput: 7.5, Output: 22.5},
+	}
+	haiku45Pricing     = Pricing{UncachedInput: 1, CacheReadInput: 0.1, CacheCreationInput: 1.25, Output: 5}
+	haiku35Pricing     = Pricing{UncachedInput: 0.8, CacheReadInput: 0.08, CacheCreationInput: 1, Output: 4}
+	haiku3Pricing      = Pricing{UncachedInput: 0.25, CacheReadInput: 0.03, CacheCreationInput: 0.3, Output: 1.25}
+	gpt4oPricing       = Pricing{UncachedInput: 2.5, CacheReadInput: 1.25, Output: 10}
+	gpt4oMiniPricing   = Pricing{UncachedInput: 0.15, CacheReadInput: 0.075, Output: 0.6}
+	gpt41Pricing       = Pricing{UncachedInput: 2, CacheReadInput: 0.5, Output: 8}
+	gpt41MiniPricing   = Pricing{UncachedInput: 0.4, CacheReadInput: 0.1, Output: 1.6}
+	gpt41NanoPricing   = Pricing{UncachedInput: 0.1, CacheReadInput: 0.025, Output: 0.4}
+	gpt5Pricing        = Pricing{UncachedInput: 1.25, CacheReadInput: 0.125, Output: 10}
+	gpt5MiniPricing    = Pricing{UncachedInput: 0.25, CacheReadInput: 0.025, Output: 2}
+	gemini25ProPricing = Pricing{
+		UncachedInput: 1.25, CacheReadInput: 0.125, Output: 10,
+		LongContext: &Pricing{UncachedInput: 2.5, CacheReadInput: 0.25, Output: 15},
+	}
+	gemini25FlashPricing     = Pricing{UncachedInput: 0.3, CacheReadInput: 0.03, Output: 2.5}
+	gemini25FlashLitePricing = Pricing{UncachedInput: 0.1, CacheReadInput: 0.01, Output: 0.4}
+	gemini20FlashPricing     = Pricing{UncachedInput: 0.1, CacheReadInput: 0.025, Output: 0.4}
+)
+
+// catalog lists the model snapshots scrollwork knows about.
+var catalog = []catalogModel{
+	{key: "claude-opus-4-5-20251101", provider: ModelProviderAnthropic, family: "opus", version: "4.5", date: day(2025, 11, 1), aliases: []string{"claude-opus-4-5"}, pricing: opus45Pricing, cacheMinTokens: 4096},
+	{key: "claude-haiku-4-5-20251001", provider: ModelProviderAnthropic, family: "haiku", version: "4.5", date: day(2025, 10, 1), aliases: []string{"claude-haiku-4-5"}, pricing: haiku45Pricing, cacheMinTokens: 4096},
+	{key: "claude-sonnet-4-5-20250929", provider: ModelProviderAnthropic, family: "sonnet", version: "4.5", date: day(2025, 9, 29), aliases: []string{"claude-sonnet-4-5"}, pricing: sonnet4Pricing, cacheMinTokens: 1024},
+	{key: "claude-opus-4-1-20250805", provider: ModelProviderAnthropic, family: "opus", version: "4.1", date: day(2025, 8, 5), aliases: []string{"claude-opus-4-1"}, pricing: opusPricing, cacheMinTokens: 1024},
+	{key: "claude-opus-4-20250514", provider: ModelProviderAnthropic, family: "opus", version: "4", date: day(2025, 5, 14), aliases: []string{"claude-opus-4-0"}, pricing: opusPricing, cacheMinTokens: 1024},
+	{key: "claude-sonnet-4-20250514", provider: ModelProviderAnthropic, family: "sonnet", version: "4", date: day(2025, 5, 14), aliases: []string{"claude-sonnet-4-0"}, pricing: sonnet4Pricing, cacheMinTokens: 1024},
+	{key: "claude-3-7-sonnet-20250219", provider: ModelProviderAnthropic, family: "sonnet", version: "3.7", date: day(2025, 2, 19), aliases: []string{"claude-3-7-sonnet-latest"}, pricing: sonnetPricing, cacheMinTokens: 1024},
+	// Vertex AI names the second Claude 3.5 Sonnet snapshot claude-3-5-sonnet-v2@20241022
+	{key: "claude-3-5-sonnet-20241022", provider: ModelProviderAnthropic, family: "sonnet", version: "3.5", date: day(2024, 10, 22), aliases: []string{"claude-3-5-sonnet-latest", "claude-3-5-sonnet-v2-20241022"}, pricing: sonnetPricing, cacheMinTokens: 1024},
+	{key: "claude-3-5-sonnet-20240620", provider: ModelProviderAnthropic, family: "sonnet", version: "3.5", date: day(2024, 6, 20), pricing: sonnetPricing, cacheMinTokens: 1024},
+	{key: "claude-3-5-haiku-20241022", provider: ModelProviderAnthropic, family: "haiku", version: "3.5", date: day(2024, 10, 22), aliases: []string{"claude-3-5-haiku-latest"}, pricing: haiku35Pricing, cacheMinTokens: 2048},
+	{key: "claude-3-opus-20240229", provider: ModelProviderAnthropic, family: "opus", version: "3", date: day(2024, 2, 29), aliases: []string{"claude-3-opus-latest"}, pricing: opusPricing, cacheMinTokens: 1024},
+	{key: "claude-3-haiku-20240307", provider: ModelProviderAnthropic, family: "haiku", version: "3", date: day(2024, 3, 7), pricing: haiku3Pricing, cacheMinTokens: 2048},
+
+	{key: "gpt-5-2025-08-07", provider: ModelProviderOpenAI, family: "gpt-5", date: day(2025, 8, 7), aliases: []string{"gpt-5"}, pricing: gpt5Pricing},
+	{key: "gpt-5-mini-2025-08-07", provider: ModelProviderOpenAI, family: "gpt-5-mini", date: day(2025, 8, 7), aliases: []string{"gpt-5-mini"}, pricing: gpt5MiniPricing},
+	{key: "gpt-4.1-2025-04-14", provider: ModelProviderOpenAI, family: "gpt-4.1", date: day(2025, 4, 14), aliases: []string{"gpt-4.1"}, pricing: gpt41Pricing},
+	{key: "gpt-4.1-mini-2025-04-14", provider: ModelProviderOpenAI, family: "gpt-4.1-mini", date: day(2025, 4, 14), aliases: []string{"gpt-4.1-mini"}, pricing: gpt41MiniPricing},
+	{key: "gpt-4.1-nano-2025-04-14", provider: ModelProviderOpenAI, family: "gpt-4.1-nano", date: day(2025, 4, 14), aliases: []string{"gpt-4.1-nano"}, pricing: gpt41NanoPricing},
+	{key: "gpt-4o-2024-11-20", provider: ModelProviderOpenAI, family: "gpt-4o", date: day(2024, 11, 20), pricing: gpt4oPricing},
+	{key: "gpt-4o-2024-08-06", provider: ModelProviderOpenAI, family: "gpt-4o", date: day(2024, 8, 6), aliases: []string{"gpt-4o"}, pricing: gpt4oPricing},
+	{key: "gpt-4o-mini-2024-07-18", provider: ModelProviderOpenAI, family: "gpt-4o-mini", date: day(2024, 7, 18), aliases: []string{"gpt-4o-mini"}, pricing: gpt4oMiniPricing},
+
+	{key: "gemini-2.5-pro", provider: ModelProviderGoogle, family: "pro", version: "2.5", date: day(2025, 6, 17), pricing: gemini25ProPricing},
+	{key: "gemini-2.5-flash", provider: ModelProviderGoogle, family: "flash", version: "2.5", date: day(2025, 6, 17), pricing: gemini25FlashPricing},
+	{key: "gemini-2.5-flash-lite", provider: ModelProviderGoogle, family: "flash-lite", version: "2.5", date: day(2025, 7, 22), pricing: gemini25FlashLitePricing},
+	{key: "gemini-2.0-flash-001", provider: ModelProviderGoogle, family: "flash", version: "2.0", date: day(2025, 2, 5), aliases: []string{"gemini-2.0-flash"}, pricing: gemini20FlashPricing},
+}
+
+// catalogIndex is the catalog keyed by the canonical key and every alias of each snapshot.
+var catalogIndex = func() map[string]catalogModel {
+	index := make(map[string]catalogModel)
+	for _, m := range catalog {
+		index[m.key] = m
+		for _, alias := range m.aliases {
+			index[alias] = m
+		}
+	}
+
+	return index
+}()
+
+// ParseModel parses a model id of any platform with the catalog, resolving aliases such as claude-3-7-sonnet-latest
+// to the snapshot they currently point at. It returns ErrUnknownModel if the model is not in the catalog.
+//
+// Self-hosted models and Azure OpenAI deployments are not catalogued. Any id with the LocalModelPrefix or the
+// AzureModelPrefix is one, keyed by its id.
+func ParseModel(id string) (Model, error) {
+	if name, ok := strings.CutPrefix(id, LocalModelPrefix); ok && name != "" {
+		return Model{ID: id, Key: id, Provider: ModelProviderSelfHosted, Family: name, Platform: PlatformLocal}, nil
+	}
+
+	if name, ok := strings.CutPrefix(id, AzureModelPrefix); ok && name != "" {
+		return Model{ID: id, Key: id, Provider: ModelProviderOpenAI, Family: name, Platform: PlatformAzure}, nil
+	}
+
+	name, platform, region := id, Platform(""), ""
+
+	if m := bedrockModelID.FindStringSubmatch(id); m != nil {
+		name, platform, region = m[2], PlatformBedrock, m[1]
+	} else if m := vertexModelID.FindStringSubmatch(id); m != nil {
+		name, platform = m[1]+"-"+m[2], PlatformVertex
+	}
+
+	entry, ok := catalogIndex[name]
+	if !ok {
+		return Model{}, fmt.Errorf("ParseModel failed: %w %q", ErrUnknownModel, id)
+	}
+
+	if platform == "" {
+		switch entry.provider {
+		case ModelProviderAnthropic:
+			platform = PlatformAnthropic
+		case ModelProviderOpenAI:
+			platform = PlatformOpenAI
+		case ModelProviderGoogle:
+			platform = PlatformGemini
+		}
+	}
+
+	return Model{
+		ID:       id,
+		Key:      entry.key,
+		Provider: entry.provider,
+		Family:   entry.family,
+		Version:  entry.version,
+		Date:     entry.date,
+		Platform: platform,
+		Region:   region,
+
+		CacheMinTokens: entry.cacheMinTokens,
+	}, nil
+}
+
+// ModelKey returns the canonical key of a model id, or the id itself if it is not in the catalog.
+func ModelKey(id string) string {
+	model, err := ParseModel(id)
+	if err != nil {
+		return id
+	}
+
+	return model.Key
+}
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
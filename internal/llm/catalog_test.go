@@ -0,0 +1,123 @@
+package llm_test
+
+import (
+	"scrollwork/internal/llm"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModel(t *testing.T) {
+	t.Parallel()
+
+	tc := []struct {
+		id       string
+		expected llm.Model
+	}{
+		{
+			id:       "claude-sonnet-4-20250514",
+			expected: llm.Model{Key: "claude-sonnet-4-20250514", Provider: llm.ModelProviderAnthropic, Family: "sonnet", Version: "4", Platform: llm.PlatformAnthropic, CacheMinTokens: 1024},
+		},
+		{
+			id:       "us.anthropic.claude-sonnet-4-20250514-v1:0",
+			expected: llm.Model{Key: "claude-sonnet-4-20250514", Provider: llm.ModelProviderAnthropic, Family: "sonnet", Version: "4", Platform: llm.PlatformBedrock, Region: "us", CacheMinTokens: 1024},
+		},
+		{
+			id:       "claude-sonnet-4@20250514",
+			expected: llm.Model{Key: "claude-sonnet-4-20250514", Provider: llm.ModelProviderAnthropic, Family: "sonnet", Version: "4", Platform: llm.PlatformVertex, CacheMinTokens: 1024},
+		},
+		{
+			id:       "claude-3-7-sonnet-latest",
+			expected: llm.Model{Key: "claude-3-7-sonnet-20250219", Provider: llm.ModelProviderAnthropic, Family: "sonnet", Version: "3.7", Platform: llm.PlatformAnthropic, CacheMinTokens: 1024},
+		},
+		{
+			id:       "anthropic.claude-3-5-sonnet-20241022-v2:0",
+			expected: llm.Model{Key: "claude-3-5-sonnet-20241022", Provider: llm.ModelProviderAnthropic, Family: "sonnet", Version: "3.5", Platform: llm.PlatformBedrock, CacheMinTokens: 1024},
+		},
+		{
+			id:       "claude-3-5-sonnet-v2@20241022",
+			expected: llm.Model{Key: "claude-3-5-sonnet-20241022", Provider: llm.ModelProviderAnthropic, Family: "sonnet", Version: "3.5", Platform: llm.PlatformVertex, CacheMinTokens: 1024},
+		},
+		{
+			id:       "gpt-4o",
+			expected: llm.Model{Key: "gpt-4o-2024-08-06", Provider: llm.ModelProviderOpenAI, Family: "gpt-4o", Platform: llm.PlatformOpenAI},
+		},
+		{
+			id:       "gemini-2.0-flash",
+			expected: llm.Model{Key: "gemini-2.0-flash-001", Provider: llm.ModelProviderGoogle, Family: "flash", Version: "2.0", Platform: llm.PlatformGemini},
+		},
+	}
+
+	for _, td := range tc {
+		t.Run(td.id, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := llm.ParseModel(td.id)
+			require.NoError(t, err)
+			require.False(t, m.Date.IsZero())
+
+			td.expected.ID = td.id
+			td.expected.Date = m.Date
+			require.Equal(t, td.expected, m)
+		})
+	}
+}
+
+func TestParseModel_Local(t *testing.T) {
+	t.Parallel()
+
+	m, err := llm.ParseModel("local/meta-llama/Llama-3.1-8B-Instruct")
+	require.NoError(t, err)
+	require.Equal(t, llm.PlatformLocal, m.Platform)
+	require.Equal(t, llm.ModelProviderSelfHosted, m.Provider)
+	require.Equal(t, "local/meta-llama/Llama-3.1-8B-Instruct", m.Key)
+	require.Equal(t, "meta-llama/Llama-3.1-8B-Instruct", m.Family)
+
+	_, err = llm.ParseModel("local/")
+	require.ErrorIs(t, err, llm.ErrUnknownModel)
+}
+
+func TestParseModel_Date(t *testing.T) {
+	t.Parallel()
+
+	m, err := llm.ParseModel("claude-opus-4-1")
+	require.NoError(t, err)
+	require.Equal(t, "claude-opus-4-1-20250805", m.Key)
+	require.Equal(t, time.Date(2025, 8, 5, 0, 0, 0, 0, time.UTC), m.Date)
+}
+
+func TestParseModel_Error(t *testing.T) {
+	t.Parallel()
+
+	tc := []string{
+		"",
+		"claude-unknown-9-20300101",
+		"text-embedding-3-small",
+		"anthropic.claude-unknown-v1:0",
+		"claude-sonnet-4@20300101",
+		"gpt-test",
+	}
+
+	for _, td := range tc {
+		_, err := llm.ParseModel(td)
+		require.ErrorIs(t, err, llm.ErrUnknownModel, td)
+	}
+}
+
+func TestModelKey(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "claude-sonnet-4-20250514", llm.ModelKey("eu.anthropic.claude-sonnet-4-20250514-v1:0"))
+	require.Equal(t, "claude-sonnet-4-20250514", llm.ModelKey("claude-sonnet-4-0"))
+	require.Equal(t, "unknown-model", llm.ModelKey("unknown-model"))
+}
+
+func TestIsOpenAIModel(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, llm.IsOpenAIModel("gpt-4o-mini"))
+	require.True(t, llm.IsOpenAIModel("gpt-4.1-2025-04-14"))
+	require.False(t, llm.IsOpenAIModel("text-embedding-3-small"))
+	require.False(t, llm.IsOpenAIModel("claude-sonnet-4-20250514"))
+}
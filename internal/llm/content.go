@@ -0,0 +1,125 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	ContentBlockText       ContentBlockType = "text"
+	ContentBlockImage      ContentBlockType = "image"
+	ContentBlockDocument   ContentBlockType = "document"
+	ContentBlockToolUse    ContentBlockType = "tool_use"
+	ContentBlockToolResult ContentBlockType = "tool_result"
+
+	SourceBase64 SourceType = "base64"
+	SourceURL    SourceType = "url"
+	SourceText   SourceType = "text"
+)
+
+type (
+	// Prompt is everything sent to a model that consumes input tokens.
+	Prompt struct {
+		System   Content   `json:"system,omitempty"`
+		Messages []Message `json:"messages"`
+		Tools    []Tool    `json:"tools,omitempty"`
+	}
+
+	// Content is a list of content blocks in the shape of the Anthropic Messages API. It is encoded as a list of
+	// blocks, and a plain string decodes as a single text block.
+	Content []ContentBlock
+
+	ContentBlockType string
+
+	// ContentBlock is a block of message content. Only the fields of its Type are set.
+	ContentBlock struct {
+		Type ContentBlockType `json:"type"`
+
+		// Text is the text of a text block.
+		Text string `json:"text,omitempty"`
+
+		// Source is the data of an image or document block.
+		Source *ContentSource `json:"source,omitempty"`
+		// Title is the title of a document block.
+		Title string `json:"title,omitempty"`
+
+		// ID, Name and Input are the call of a tool_use block.
+		ID    string          `json:"id,omitempty"`
+		Name  string          `json:"name,omitempty"`
+		Input json.RawMessage `json:"input,omitempty"`
+
+		// ToolUseID, Content and IsError are the result of the tool call of a tool_result block.
+		ToolUseID string  `json:"tool_use_id,omitempty"`
+		Content   Content `json:"content,omitempty"`
+		IsError   bool    `json:"is_error,omitempty"`
+
+		// CacheControl marks the prompt up to and including the block as a prompt cache breakpoint.
+		CacheControl *CacheControl `json:"cache_control,omitempty"`
+	}
+
+	SourceType string
+
+	// ContentSource is the data of an image or document.
+	ContentSource struct {
+		Type SourceType `json:"type"`
+		// MediaType is the MIME type of base64 data, such as image/png or application/pdf.
+		MediaType string `json:"media_type,omitempty"`
+		// Data is the base64 encoded data of a base64 source, or the text of a text source.
+		Data string `json:"data,omitempty"`
+		URL  string `json:"url,omitempty"`
+	}
+
+	// Tool is a tool the model may call.
+	Tool struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description,omitempty"`
+		InputSchema json.RawMessage `json:"input_schema"`
+
+		// CacheControl marks the tools up to and including the tool as a prompt cache breakpoint.
+		CacheControl *CacheControl `json:"cache_control,omitempty"`
+	}
+)
+
+// TextContent returns content of a single text block.
+func TextContent(text string) Content {
+	return Content{{Type: ContentBlockText, Text: text}}
+}
+
+// UnmarshalJSON decodes a list of content blocks or a plain string.
+func (c *Content) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+
+		*c = TextContent(text)
+		return nil
+	}
+
+	var blocks []ContentBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return fmt.Errorf("content must be a string or a list of content blocks: %w", err)
+	}
+
+	*c = blocks
+	return nil
+}
+
+// Text returns the text of every text block, and of the text blocks of tool results, separated by newlines.
+func (c Content) Text() string {
+	var texts []string
+	for _, block := range c {
+		switch block.Type {
+		case ContentBlockText:
+			texts = append(texts, block.Text)
+		case ContentBlockToolResult:
+			texts = append(texts, block.Content.Text())
+		}
+	}
+
+	return strings.Join(texts, "\n")
+}
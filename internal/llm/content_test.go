@@ -0,0 +1,68 @@
+package llm_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"scrollwork/internal/llm"
+)
+
+func TestContent_UnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]struct {
+		data     string
+		expected llm.Content
+		err      bool
+	}{
+		"string": {
+			data:     `"Hello"`,
+			expected: llm.TextContent("Hello"),
+		},
+		"blocks": {
+			data: `[{"type": "text", "text": "What is in this image?"}, {"type": "image", "source": {"type": "url", "url": "https://example.com/cat.png"}}]`,
+			expected: llm.Content{
+				{Type: llm.ContentBlockText, Text: "What is in this image?"},
+				{Type: llm.ContentBlockImage, Source: &llm.ContentSource{Type: llm.SourceURL, URL: "https://example.com/cat.png"}},
+			},
+		},
+		"tool result with string content": {
+			data:     `[{"type": "tool_result", "tool_use_id": "toolu_01", "content": "15 degrees"}]`,
+			expected: llm.Content{{Type: llm.ContentBlockToolResult, ToolUseID: "toolu_01", Content: llm.TextContent("15 degrees")}},
+		},
+		"number": {
+			data: `42`,
+			err:  true,
+		},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var content llm.Content
+			err := json.Unmarshal([]byte(td.data), &content)
+			if td.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, td.expected, content)
+		})
+	}
+}
+
+func TestContent_Text(t *testing.T) {
+	t.Parallel()
+
+	content := llm.Content{
+		{Type: llm.ContentBlockText, Text: "Here is the weather:"},
+		{Type: llm.ContentBlockToolUse, ID: "toolu_01", Name: "get_weather", Input: json.RawMessage(`{}`)},
+		{Type: llm.ContentBlockToolResult, ToolUseID: "toolu_01", Content: llm.TextContent("15 degrees")},
+	}
+
+	require.Equal(t, "Here is the weather:\n15 degrees", content.Text())
+}
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v2"
+)
+
+// RetryAfter returns how long a provider asked to wait before retrying a rate limited request.
+// It returns false if err is not a 429 response with a valid Retry-After header.
+func RetryAfter(err error) (time.Duration, bool) {
+	var response *http.Response
+
+	var anthropicErr *anthropic.Error
+	var openAIErr *openai.Error
+	var httpErr *HTTPError
+	switch {
+	case errors.As(err, &httpErr):
+		response = &http.Response{StatusCode: httpErr.StatusCode, Header: httpErr.Header}
+	case errors.As(err, &anthropicErr):
+		response = anthropicErr.Response
+	case errors.As(err, &openAIErr):
+		response = openAIErr.Response
+	default:
+		return 0, false
+	}
+
+	if response == nil || response.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	return parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,57 @@
+package llm_test
+
+import (
+	"fmt"
+	"net/http"
+	"scrollwork/internal/llm"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name       string
+		StatusCode int
+		RetryAfter string
+		Expected   time.Duration
+		ExpectedOK bool
+	}{
+		{Name: "seconds", StatusCode: http.StatusTooManyRequests, RetryAfter: "30", Expected: 30 * time.Second, ExpectedOK: true},
+		{Name: "past date", StatusCode: http.StatusTooManyRequests, RetryAfter: "Mon, 01 Sep 2025 12:00:00 GMT", Expected: 0, ExpectedOK: true},
+		{Name: "missing header", StatusCode: http.StatusTooManyRequests},
+		{Name: "invalid header", StatusCode: http.StatusTooManyRequests, RetryAfter: "soon"},
+		{Name: "not rate limited", StatusCode: http.StatusInternalServerError, RetryAfter: "30"},
+	}
+
+	for _, td := range tt {
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+
+			h := http.Header{}
+			if td.RetryAfter != "" {
+				h.Set("Retry-After", td.RetryAfter)
+			}
+
+			err := fmt.Errorf("fetch failed: %w", &anthropic.Error{
+				StatusCode: td.StatusCode,
+				Response:   &http.Response{StatusCode: td.StatusCode, Header: h},
+			})
+
+			retryAfter, ok := llm.RetryAfter(err)
+			require.Equal(t, td.ExpectedOK, ok)
+			require.Equal(t, td.Expected, retryAfter)
+		})
+	}
+}
+
+func TestRetryAfter_OtherError(t *testing.T) {
+	t.Parallel()
+
+	_, ok := llm.RetryAfter(fmt.Errorf("connection refused"))
+	require.False(t, ok)
+}
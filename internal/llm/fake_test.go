@@ -0,0 +1,65 @@
+package llm_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// fakeAPI is a local fake of a provider API. Its routes run on the server's goroutines, where a failed require
+	// cannot stop the test, so they check requests with assertions that record mismatches instead. Tests require
+	// that every request matched with requireMatched.
+	fakeAPI struct {
+		*httptest.Server
+
+		mu         sync.Mutex
+		mismatches []string
+	}
+
+	// fakeRoute serves the requests to a path of a fakeAPI, checking them with check.
+	fakeRoute func(w http.ResponseWriter, r *http.Request, check *assert.Assertions)
+)
+
+// newFakeAPI returns a fakeAPI serving routes by path. Requests to other paths are not found.
+func newFakeAPI(t *testing.T, routes map[string]fakeRoute) *fakeAPI {
+	t.Helper()
+
+	api := &fakeAPI{}
+	check := assert.New(api)
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, ok := routes[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		route(w, r, check)
+	}))
+	t.Cleanup(api.Close)
+
+	return api
+}
+
+// Errorf records a mismatch. It implements assert.TestingT.
+func (f *fakeAPI) Errorf(format string, args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.mismatches = append(f.mismatches, fmt.Sprintf(format, args...))
+}
+
+// requireMatched fails the test if a request served so far did not match what its route expected.
+func (f *fakeAPI) requireMatched(t *testing.T) {
+	t.Helper()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	require.Empty(t, f.mismatches, "the fake API received unexpected requests")
+}
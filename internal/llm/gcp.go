@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type (
+	// serviceAccount is a Google Cloud service account key file.
+	serviceAccount struct {
+		Type         string `json:"type"`
+		ProjectID    string `json:"project_id"`
+		PrivateKeyID string `json:"private_key_id"`
+		PrivateKey   string `json:"private_key"`
+		ClientEmail  string `json:"client_email"`
+		TokenURI     string `json:"token_uri"`
+	}
+)
+
+const (
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+	googleTokenURL     = "https://oauth2.googleapis.com/token"
+	jwtBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+	// accessTokenLifetime is the lifetime requested for access tokens. Google allows at most one hour.
+	accessTokenLifetime = time.Hour
+)
+
+// parseServiceAccount parses a service account key file.
+func parseServiceAccount(credentials []byte) (serviceAccount, *rsa.PrivateKey, error) {
+	var account serviceAccount
+	if err := json.Unmarshal(credentials, &account); err != nil {
+		return serviceAccount{}, nil, fmt.Errorf("invalid service account credentials: %w", err)
+	}
+
+	if account.Type != "service_account" {
+		return serviceAccount{}, nil, fmt.Errorf("credentials must be a service account key, got %q", account.Type)
+	}
+
+	if account.ClientEmail == "" {
+		return serviceAccount{}, nil, fmt.Errorf("service account credentials are missing client_email")
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return serviceAccount{}, nil, fmt.Errorf("service account private key is not PEM encoded")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return serviceAccount{}, nil, fmt.Errorf("invalid service account private key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return serviceAccount{}, nil, fmt.Errorf("service account private key must be an RSA key")
+	}
+
+	return account, key, nil
+}
+
+// newServiceAccountTokenSource returns a token source that exchanges JWTs signed by the service account for access
+// tokens to scope.
+func newServiceAccountTokenSource(account serviceAccount, key *rsa.PrivateKey, tokenURL string, scope string, httpClient *http.Client) *oauthTokenSource {
+	return &oauthTokenSource{
+		tokenURL:   tokenURL,
+		httpClient: httpClient,
+		grant: func(now time.Time) (url.Values, error) {
+			assertion, err := jwtAssertion(account, key, tokenURL, scope, now)
+			if err != nil {
+				return nil, err
+			}
+
+			form := url.Values{}
+			form.Set("grant_type", jwtBearerGrantType)
+			form.Set("assertion", assertion)
+
+			return form, nil
+		},
+	}
+}
+
+// jwtAssertion returns a JWT signed by the service account requesting an access token for scope from tokenURL.
+func jwtAssertion(account serviceAccount, key *rsa.PrivateKey, tokenURL string, scope string, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": account.PrivateKeyID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]any{
+		"iss":   account.ClientEmail,
+		"scope": scope,
+		"aud":   tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(accessTokenLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,245 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type (
+	GeminiConfig struct {
+		APIKey string
+
+		// Endpoint is the Gemini API. Defaults to https://generativelanguage.googleapis.com.
+		Endpoint string
+
+		// Ledger is where the usage reported by clients is kept.
+		Ledger *Ledger
+
+		HTTPClient *http.Client
+	}
+
+	// GeminiClient is the Provider of Google Gemini models served by the Gemini API.
+	//
+	// The Gemini API has no usage report. Usage is read from a Ledger of the usage clients report once their
+	// requests complete.
+	GeminiClient struct {
+		ledgerUsage
+
+		config GeminiConfig
+	}
+
+	// geminiCountTokensRequest counts the tokens of a full generateContent request, as contents alone leave out
+	// the system instruction and tools.
+	geminiCountTokensRequest struct {
+		GenerateContentRequest geminiGenerateContentRequest `json:"generateContentRequest"`
+	}
+
+	geminiGenerateContentRequest struct {
+		Model             string          `json:"model"`
+		Contents          []geminiContent `json:"contents"`
+		SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+		Tools             []geminiTool    `json:"tools,omitempty"`
+	}
+
+	geminiContent struct {
+		Role  string       `json:"role,omitempty"`
+		Parts []geminiPart `json:"parts"`
+	}
+
+	// geminiPart is a part of Gemini content. Exactly one of its fields is set.
+	geminiPart struct {
+		Text             string                  `json:"text,omitempty"`
+		InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+		FileData         *geminiFileData         `json:"fileData,omitempty"`
+		FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+		FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+	}
+
+	geminiBlob struct {
+		MimeType string `json:"mimeType"`
+		Data     string `json:"data"`
+	}
+
+	geminiFileData struct {
+		MimeType string `json:"mimeType,omitempty"`
+		FileURI  string `json:"fileUri"`
+	}
+
+	geminiFunctionCall struct {
+		Name string          `json:"name"`
+		Args json.RawMessage `json:"args,omitempty"`
+	}
+
+	geminiFunctionResponse struct {
+		Name     string `json:"name"`
+		Response struct {
+			Content string `json:"content"`
+		} `json:"response"`
+	}
+
+	geminiTool struct {
+		FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+	}
+
+	geminiFunctionDeclaration struct {
+		Name                 string          `json:"name"`
+		Description          string          `json:"description,omitempty"`
+		ParametersJSONSchema json.RawMessage `json:"parametersJsonSchema,omitempty"`
+	}
+)
+
+var (
+	_ Provider      = (*GeminiClient)(nil)
+	_ UsageRecorder = (*GeminiClient)(nil)
+)
+
+// NewGeminiClient returns a GeminiClient.
+func NewGeminiClient(config GeminiConfig) (*GeminiClient, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("NewGeminiClient failed: missing API key")
+	}
+
+	if config.Ledger == nil {
+		return nil, fmt.Errorf("NewGeminiClient failed: missing usage ledger")
+	}
+
+	if config.Endpoint == "" {
+		config.Endpoint = "https://generativelanguage.googleapis.com"
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &GeminiClient{ledgerUsage: ledgerUsage{ledger: config.Ledger}, config: config}, nil
+}
+
+func (g *GeminiClient) Name() string {
+	return "gemini"
+}
+
+// HealthCheck lists the available models. It is used to verify the API key.
+func (g *GeminiClient) HealthCheck(ctx context.Context) error {
+	if _, err := g.do(ctx, http.MethodGet, "/v1beta/models?pageSize=1", nil); err != nil {
+		return fmt.Errorf("HealthCheck failed: %w", err)
+	}
+
+	return nil
+}
+
+// CountTokens counts the input tokens of prompt with the Gemini countTokens endpoint.
+func (g *GeminiClient) CountTokens(ctx context.Context, model string, prompt Prompt) (int, error) {
+	payload, err := json.Marshal(geminiCountTokensRequest{GenerateContentRequest: newGeminiGenerateContentRequest(model, prompt)})
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	respBody, err := g.do(ctx, http.MethodPost, "/v1beta/models/"+url.PathEscape(model)+":countTokens", payload)
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	var resp struct {
+		TotalTokens int `json:"totalTokens"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	return resp.TotalTokens, nil
+}
+
+// newGeminiGenerateContentRequest converts prompt into a generateContent request.
+func newGeminiGenerateContentRequest(model string, prompt Prompt) geminiGenerateContentRequest {
+	req := geminiGenerateContentRequest{Model: "models/" + model}
+
+	// Function responses are matched to their call by name rather than id
+	toolNames := make(map[string]string)
+	for _, message := range prompt.Messages {
+		for _, block := range message.Content {
+			if block.Type == ContentBlockToolUse {
+				toolNames[block.ID] = block.Name
+			}
+		}
+	}
+
+	if len(prompt.System) > 0 {
+		req.SystemInstruction = &geminiContent{Parts: geminiParts(prompt.System, toolNames)}
+	}
+
+	for _, message := range prompt.Messages {
+		// Gemini calls the assistant the model
+		role := "user"
+		if message.Role == MessageRoleAssistant {
+			role = "model"
+		}
+
+		req.Contents = append(req.Contents, geminiContent{Role: role, Parts: geminiParts(message.Content, toolNames)})
+	}
+
+	if len(prompt.Tools) > 0 {
+		declarations := make([]geminiFunctionDeclaration, 0, len(prompt.Tools))
+		for _, tool := range prompt.Tools {
+			declarations = append(declarations, geminiFunctionDeclaration{
+				Name:                 tool.Name,
+				Description:          tool.Description,
+				ParametersJSONSchema: tool.InputSchema,
+			})
+		}
+
+		req.Tools = []geminiTool{{FunctionDeclarations: declarations}}
+	}
+
+	return req
+}
+
+// geminiParts converts content blocks into Gemini parts.
+func geminiParts(content Content, toolNames map[string]string) []geminiPart {
+	parts := make([]geminiPart, 0, len(content))
+	for _, block := range content {
+		switch block.Type {
+		case ContentBlockText:
+			parts = append(parts, geminiPart{Text: block.Text})
+		case ContentBlockImage, ContentBlockDocument:
+			if block.Source == nil {
+				continue
+			}
+
+			switch block.Source.Type {
+			case SourceBase64:
+				parts = append(parts, geminiPart{InlineData: &geminiBlob{MimeType: block.Source.MediaType, Data: block.Source.Data}})
+			case SourceURL:
+				parts = append(parts, geminiPart{FileData: &geminiFileData{MimeType: block.Source.MediaType, FileURI: block.Source.URL}})
+			case SourceText:
+				parts = append(parts, geminiPart{Text: block.Source.Data})
+			}
+		case ContentBlockToolUse:
+			parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: block.Name, Args: block.Input}})
+		case ContentBlockToolResult:
+			response := &geminiFunctionResponse{Name: toolNames[block.ToolUseID]}
+			response.Response.Content = block.Content.Text()
+			parts = append(parts, geminiPart{FunctionResponse: response})
+		}
+	}
+
+	return parts
+}
+
+func (g *GeminiClient) do(ctx context.Context, method string, path string, payload []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(g.config.Endpoint, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-goog-api-key", g.config.APIKey)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return doRequest(g.config.HTTPClient, req)
+}
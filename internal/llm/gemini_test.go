@@ -0,0 +1,125 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"scrollwork/internal/llm"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const geminiModel = "gemini-2.5-pro"
+
+// newGeminiFake returns a client talking to a local fake of the Gemini API.
+func newGeminiFake(t *testing.T, ledger *llm.Ledger) (*llm.GeminiClient, *fakeAPI) {
+	t.Helper()
+
+	checkKey := func(r *http.Request, check *assert.Assertions) {
+		check.Equal("test-key", r.Header.Get("x-goog-api-key"))
+	}
+
+	api := newFakeAPI(t, map[string]fakeRoute{
+		"/v1beta/models": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			checkKey(r, check)
+			fmt.Fprint(w, `{"models": []}`)
+		},
+		"/v1beta/models/" + geminiModel + ":countTokens": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			checkKey(r, check)
+
+			type content struct {
+				Role  string `json:"role"`
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			}
+			var body struct {
+				GenerateContentRequest struct {
+					Model             string    `json:"model"`
+					Contents          []content `json:"contents"`
+					SystemInstruction content   `json:"systemInstruction"`
+					Tools             []struct {
+						FunctionDeclarations []struct {
+							Name string `json:"name"`
+						} `json:"functionDeclarations"`
+					} `json:"tools"`
+				} `json:"generateContentRequest"`
+			}
+			check.NoError(json.NewDecoder(r.Body).Decode(&body))
+
+			req := body.GenerateContentRequest
+			check.Equal("models/"+geminiModel, req.Model)
+			if check.Len(req.Contents, 2) && check.NotEmpty(req.Contents[0].Parts) && check.NotEmpty(req.SystemInstruction.Parts) && check.NotEmpty(req.Tools) {
+				check.Equal("user", req.Contents[0].Role)
+				check.Equal("model", req.Contents[1].Role)
+				check.Equal("Hello", req.Contents[0].Parts[0].Text)
+				check.Equal("Be brief.", req.SystemInstruction.Parts[0].Text)
+				check.Equal("get_weather", req.Tools[0].FunctionDeclarations[0].Name)
+			}
+
+			fmt.Fprint(w, `{"totalTokens": 17}`)
+		},
+	})
+
+	client, err := llm.NewGeminiClient(llm.GeminiConfig{APIKey: "test-key", Endpoint: api.URL, Ledger: ledger})
+	require.NoError(t, err)
+
+	return client, api
+}
+
+func TestGeminiClient_CountTokens(t *testing.T) {
+	t.Parallel()
+
+	ledger, err := llm.NewLedger(llm.LedgerConfig{})
+	require.NoError(t, err)
+	client, api := newGeminiFake(t, ledger)
+
+	tokens, err := client.CountTokens(context.Background(), geminiModel, llm.Prompt{
+		System: llm.TextContent("Be brief."),
+		Messages: []llm.Message{
+			{Role: llm.MessageRoleUser, Content: llm.TextContent("Hello")},
+			{Role: llm.MessageRoleAssistant, Content: llm.TextContent("Hi!")},
+		},
+		Tools: []llm.Tool{{Name: "get_weather", InputSchema: json.RawMessage(`{"type": "object"}`)}},
+	})
+	api.requireMatched(t)
+	require.NoError(t, err)
+	require.Equal(t, 17, tokens)
+
+	require.NoError(t, client.HealthCheck(context.Background()))
+	api.requireMatched(t)
+}
+
+func TestGeminiClient_Usage(t *testing.T) {
+	t.Parallel()
+
+	ledger, err := llm.NewLedger(llm.LedgerConfig{})
+	require.NoError(t, err)
+	client, _ := newGeminiFake(t, ledger)
+
+	now := time.Now()
+	require.NoError(t, client.RecordUsage(geminiModel, now.Add(-2*time.Hour), llm.TokenUsage{UncachedInput: 100, Output: 10}))
+	require.NoError(t, client.RecordUsage(geminiModel, now.Add(-time.Hour), llm.TokenUsage{UncachedInput: 50, CacheReadInput: 20, Output: 5}))
+	require.NoError(t, client.RecordUsage("gemini-2.5-flash", now.Add(-time.Hour), llm.TokenUsage{UncachedInput: 1000}))
+
+	usage, err := client.Usage(context.Background(), []string{geminiModel}, now.Add(-90*time.Minute), now)
+	require.NoError(t, err)
+	require.Equal(t, map[string]llm.TokenUsage{geminiModel: {UncachedInput: 50, CacheReadInput: 20, Output: 5}}, usage)
+}
+
+func TestNewGeminiClient_Error(t *testing.T) {
+	t.Parallel()
+
+	ledger, err := llm.NewLedger(llm.LedgerConfig{})
+	require.NoError(t, err)
+
+	_, err = llm.NewGeminiClient(llm.GeminiConfig{Ledger: ledger})
+	require.Error(t, err)
+
+	_, err = llm.NewGeminiClient(llm.GeminiConfig{APIKey: "test-key"})
+	require.Error(t, err)
+}
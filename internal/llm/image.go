@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"math"
+	"regexp"
+
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+const (
+	// defaultImageTokens are assumed for images whose dimensions cannot be read, such as URL sources. It is about
+	// the most an image costs once Anthropic downsizes it.
+	defaultImageTokens = 1_600
+
+	// pdfPageTokens are assumed for every page of a PDF. Pages are billed both as text and as an image, and text
+	// heavy pages are at the upper end of this.
+	pdfPageTokens = 3_000
+)
+
+// pdfPage matches the page objects of a PDF, but not its page tree nodes.
+var pdfPage = regexp.MustCompile(`/Type\s*/Page\b`)
+
+// imageDimensions decodes the width and height of a base64 PNG, JPEG or GIF image.
+func imageDimensions(source *ContentSource) (int, int, bool) {
+	if source == nil || source.Type != SourceBase64 {
+		return 0, 0, false
+	}
+
+	data, err := base64.StdEncoding.DecodeString(source.Data)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, false
+	}
+
+	return config.Width, config.Height, true
+}
+
+// pdfPages counts the pages of a base64 PDF. It returns false when the document cannot be read.
+func pdfPages(source *ContentSource) (int, bool) {
+	if source == nil || source.Type != SourceBase64 || source.MediaType != "application/pdf" {
+		return 0, false
+	}
+
+	data, err := base64.StdEncoding.DecodeString(source.Data)
+	if err != nil {
+		return 0, false
+	}
+
+	pages := len(pdfPage.FindAll(data, -1))
+	if pages == 0 {
+		return 0, false
+	}
+
+	return pages, true
+}
+
+// anthropicImageTokens estimates the tokens of an image as Claude sees it. Images are downsized to fit 1568 pixels on
+// their long edge and about 1.15 megapixels, and cost a token per 750 pixels.
+func anthropicImageTokens(width int, height int) int {
+	w, h := fit(float64(width), float64(height), 1568)
+	if pixels := w * h; pixels > 1_150_000 {
+		scale := math.Sqrt(1_150_000 / pixels)
+		w, h = w*scale, h*scale
+	}
+
+	return int(math.Ceil(w * h / 750))
+}
+
+// openAIImageTokens estimates the tokens of a high detail image as GPT-4o sees it. Images are downsized to fit 2048
+// pixels, then their short side to 768 pixels, and cost 170 tokens per 512 pixel tile plus 85.
+func openAIImageTokens(width int, height int) int {
+	w, h := fit(float64(width), float64(height), 2048)
+	if short := math.Min(w, h); short > 768 {
+		w, h = w*768/short, h*768/short
+	}
+
+	tiles := math.Ceil(w/512) * math.Ceil(h/512)
+	return 85 + int(tiles)*170
+}
+
+// geminiImageTokens estimates the tokens of an image as Gemini sees it. Images that fit 384 pixels cost 258 tokens,
+// and larger images 258 tokens per 768 pixel tile.
+func geminiImageTokens(width int, height int) int {
+	if width <= 384 && height <= 384 {
+		return 258
+	}
+
+	tiles := math.Ceil(float64(width)/768) * math.Ceil(float64(height)/768)
+	return int(tiles) * 258
+}
+
+// fit scales width and height down so neither exceeds size.
+func fit(width float64, height float64, size float64) (float64, float64) {
+	if long := math.Max(width, height); long > size {
+		return width * size / long, height * size / long
+	}
+
+	return width, height
+}
@@ -0,0 +1,285 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+type (
+	LedgerConfig struct {
+		// Path is the JSON lines file usage is recorded in. Usage is only kept in memory when empty.
+		Path string
+		// Retention is how long usage is kept. Defaults to 45 days, which covers a monthly usage window.
+		Retention time.Duration
+		// CompactInterval is how often expired entries are removed from the file as usage is recorded. Defaults to a
+		// day.
+		CompactInterval time.Duration
+	}
+
+	// Ledger keeps the usage clients report for providers that have no usage API, such as Gemini.
+	//
+	// Entries older than the retention are dropped from memory as usage is recorded, and from the file when the
+	// ledger is opened and every CompactInterval after.
+	Ledger struct {
+		config LedgerConfig
+		file   *os.File
+
+		mu      sync.Mutex
+		entries []LedgerEntry
+		// lines is the number of entries in the file, expired ones included.
+		lines       int
+		compactedAt time.Time
+	}
+
+	// LedgerEntry is the usage of a single completed LLM request.
+	LedgerEntry struct {
+		Model              string    `json:"model"`
+		At                 time.Time `json:"at"`
+		UncachedInput      int       `json:"uncached_input,omitempty"`
+		CacheReadInput     int       `json:"cache_read_input,omitempty"`
+		CacheCreationInput int       `json:"cache_creation_input,omitempty"`
+		Output             int       `json:"output,omitempty"`
+	}
+
+	// UsageRecorder is implemented by providers whose usage is kept in a Ledger. Clients report the usage of their
+	// completed requests to it.
+	UsageRecorder interface {
+		RecordUsage(model string, at time.Time, usage TokenUsage) error
+	}
+
+	// ledgerUsage is embedded by providers that have no usage API to read their usage from a Ledger and record the
+	// usage clients report to it.
+	ledgerUsage struct {
+		ledger *Ledger
+	}
+)
+
+const (
+	defaultLedgerRetention       = 45 * 24 * time.Hour
+	defaultLedgerCompactInterval = 24 * time.Hour
+)
+
+// NewLedger opens the ledger at config.Path, loading the usage it retains.
+func NewLedger(config LedgerConfig) (*Ledger, error) {
+	if config.Retention <= 0 {
+		config.Retention = defaultLedgerRetention
+	}
+	if config.CompactInterval <= 0 {
+		config.CompactInterval = defaultLedgerCompactInterval
+	}
+
+	l := &Ledger{config: config}
+	if config.Path == "" {
+		return l, nil
+	}
+
+	entries, torn, err := readLedger(config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("NewLedger failed: %w", err)
+	}
+
+	cutoff := time.Now().Add(-config.Retention)
+	l.entries = slices.DeleteFunc(entries, func(e LedgerEntry) bool { return e.At.Before(cutoff) })
+	l.lines = len(entries)
+
+	// Compact the file so expired entries do not accumulate across restarts, and so a line torn by a crash is not
+	// followed by the next entry
+	if l.lines > len(l.entries) || torn {
+		if err := l.compact(); err != nil {
+			return nil, fmt.Errorf("NewLedger failed: %w", err)
+		}
+	} else if err := l.open(); err != nil {
+		return nil, fmt.Errorf("NewLedger failed: %w", err)
+	}
+	l.compactedAt = time.Now()
+
+	return l, nil
+}
+
+// Record adds the usage of model at the given time.
+func (l *Ledger) Record(model string, at time.Time, usage TokenUsage) error {
+	entry := LedgerEntry{
+		Model:              model,
+		At:                 at.UTC(),
+		UncachedInput:      usage.UncachedInput,
+		CacheReadInput:     usage.CacheReadInput,
+		CacheCreationInput: usage.CacheCreationInput,
+		Output:             usage.Output,
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("Record failed: %w", err)
+		}
+
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("Record failed: %w", err)
+		}
+		l.lines++
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-l.config.Retention)
+	l.entries = slices.DeleteFunc(l.entries, func(e LedgerEntry) bool { return e.At.Before(cutoff) })
+	l.entries = append(l.entries, entry)
+
+	if l.file != nil && l.lines > len(l.entries) && now.Sub(l.compactedAt) >= l.config.CompactInterval {
+		if err := l.compact(); err != nil {
+			return fmt.Errorf("Record failed: %w", err)
+		}
+		l.compactedAt = now
+	}
+
+	return nil
+}
+
+// Usage sums the usage recorded for each model between startingAt and endingAt.
+func (l *Ledger) Usage(models []string, startingAt time.Time, endingAt time.Time) map[string]TokenUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	usage := make(map[string]TokenUsage, len(models))
+	for _, entry := range l.entries {
+		if !slices.Contains(models, entry.Model) || entry.At.Before(startingAt) || !entry.At.Before(endingAt) {
+			continue
+		}
+
+		usage[entry.Model] = usage[entry.Model].Add(TokenUsage{
+			UncachedInput:      entry.UncachedInput,
+			CacheReadInput:     entry.CacheReadInput,
+			CacheCreationInput: entry.CacheCreationInput,
+			Output:             entry.Output,
+		})
+	}
+
+	return usage
+}
+
+// Usage sums the usage recorded in the ledger for each model between startingAt and endingAt.
+func (u ledgerUsage) Usage(ctx context.Context, models []string, startingAt time.Time, endingAt time.Time) (map[string]TokenUsage, error) {
+	return u.ledger.Usage(models, startingAt, endingAt), nil
+}
+
+// RecordUsage records the usage of a completed request in the ledger.
+func (u ledgerUsage) RecordUsage(model string, at time.Time, usage TokenUsage) error {
+	return u.ledger.Record(model, at, usage)
+}
+
+// Close closes the ledger file.
+func (l *Ledger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+
+	return err
+}
+
+// compact replaces the ledger file with the retained entries and reopens it for appending.
+func (l *Ledger) compact() error {
+	if err := writeLedger(l.config.Path, l.entries); err != nil {
+		return err
+	}
+	l.lines = len(l.entries)
+
+	// The open file was replaced and appending to it would be lost
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+
+	return l.open()
+}
+
+func (l *Ledger) open() error {
+	file, err := os.OpenFile(l.config.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	l.file = file
+
+	return nil
+}
+
+// readLedger returns the entries of the ledger file. An incomplete last line, left by a crash in the middle of a
+// write, is dropped and reported as torn.
+func readLedger(path string) (entries []LedgerEntry, torn bool, err error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	var lineErr error
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		// Only the last line may be incomplete
+		if lineErr != nil {
+			return nil, false, lineErr
+		}
+
+		var entry LedgerEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			lineErr = fmt.Errorf("%s:%d: %w", path, line, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, lineErr != nil, scanner.Err()
+}
+
+// writeLedger replaces the ledger file with entries.
+func writeLedger(path string, entries []LedgerEntry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		w.Write(append(line, '\n'))
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
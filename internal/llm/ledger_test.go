@@ -0,0 +1,101 @@
+package llm_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"scrollwork/internal/llm"
+)
+
+func TestLedger_Persistence(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	now := time.Now()
+
+	ledger, err := llm.NewLedger(llm.LedgerConfig{Path: path, Retention: 24 * time.Hour})
+	require.NoError(t, err)
+	require.NoError(t, ledger.Record(geminiModel, now.Add(-48*time.Hour), llm.TokenUsage{UncachedInput: 1000}))
+	require.NoError(t, ledger.Record(geminiModel, now.Add(-time.Hour), llm.TokenUsage{UncachedInput: 100, Output: 10}))
+	require.NoError(t, ledger.Close())
+
+	// Usage past the retention is dropped when the ledger is reopened
+	reopened, err := llm.NewLedger(llm.LedgerConfig{Path: path, Retention: 24 * time.Hour})
+	require.NoError(t, err)
+	t.Cleanup(func() { reopened.Close() })
+
+	usage := reopened.Usage([]string{geminiModel}, now.Add(-72*time.Hour), now)
+	require.Equal(t, llm.TokenUsage{UncachedInput: 100, Output: 10}, usage[geminiModel])
+}
+
+func TestLedger_TornLine(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	entry := `{"model":"` + geminiModel + `","at":"` + now.Add(-time.Hour).Format(time.RFC3339) + `","uncached_input":100}`
+
+	tc := map[string]struct {
+		content string
+		err     bool
+	}{
+		"torn last line":    {content: entry + "\n" + `{"model":"gem`},
+		"corrupt past line": {content: `{"model":"gem` + "\n" + entry + "\n", err: true},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "usage.jsonl")
+			require.NoError(t, os.WriteFile(path, []byte(td.content), 0o600))
+
+			ledger, err := llm.NewLedger(llm.LedgerConfig{Path: path})
+			if td.err {
+				require.ErrorContains(t, err, "NewLedger failed")
+				return
+			}
+			require.NoError(t, err)
+			t.Cleanup(func() { ledger.Close() })
+
+			// The torn line is dropped and the next entry starts on a line of its own
+			require.NoError(t, ledger.Record(geminiModel, now, llm.TokenUsage{UncachedInput: 10}))
+			require.NoError(t, ledger.Close())
+
+			reopened, err := llm.NewLedger(llm.LedgerConfig{Path: path})
+			require.NoError(t, err)
+			t.Cleanup(func() { reopened.Close() })
+
+			usage := reopened.Usage([]string{geminiModel}, now.Add(-2*time.Hour), now.Add(time.Second))
+			require.Equal(t, llm.TokenUsage{UncachedInput: 110}, usage[geminiModel])
+		})
+	}
+}
+
+func TestLedger_Compaction(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	now := time.Now()
+
+	ledger, err := llm.NewLedger(llm.LedgerConfig{Path: path, Retention: 24 * time.Hour, CompactInterval: time.Nanosecond})
+	require.NoError(t, err)
+	t.Cleanup(func() { ledger.Close() })
+
+	require.NoError(t, ledger.Record(geminiModel, now.Add(-48*time.Hour), llm.TokenUsage{UncachedInput: 1000}))
+	require.NoError(t, ledger.Record(geminiModel, now.Add(-time.Hour), llm.TokenUsage{UncachedInput: 100}))
+
+	// The expired entry is removed from the file while the ledger is open
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(data), "\n"))
+
+	require.NoError(t, ledger.Record(geminiModel, now, llm.TokenUsage{UncachedInput: 10}))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(data), "\n"))
+}
@@ -0,0 +1,296 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	LocalConfig struct {
+		// BaseURL is the root of the OpenAI-compatible server, such as http://inference.internal:8000.
+		BaseURL string
+		// APIKey is sent as a bearer token when set.
+		APIKey string
+
+		// Tokenizer counts tokens offline. The server's /tokenize endpoint, as served by vLLM, is used when nil.
+		Tokenizer Tokenizer
+
+		// GPU is the cost of serving models on the cluster. Assessments have no cost estimate when it is zero.
+		GPU GPUCost
+
+		// Ledger is where the usage reported by clients is kept.
+		Ledger *Ledger
+
+		HTTPClient *http.Client
+	}
+
+	// GPUCost is the cost of the GPU time a self-hosted model needs to process tokens.
+	GPUCost struct {
+		// HourlyUSD is the cost of the GPUs serving a model for an hour.
+		HourlyUSD float64
+		// InputTokensPerSecond is the prompt processing (prefill) throughput of the GPUs.
+		InputTokensPerSecond float64
+		// OutputTokensPerSecond is the generation (decode) throughput of the GPUs.
+		OutputTokensPerSecond float64
+	}
+
+	// LocalClient is the Provider of self-hosted models served by an OpenAI-compatible server, such as vLLM.
+	//
+	// Their cost is the GPU time they take rather than provider billing, and their usage is read from a Ledger of
+	// the usage clients report once their requests complete.
+	LocalClient struct {
+		ledgerUsage
+
+		config LocalConfig
+	}
+
+	localTokenizeRequest struct {
+		Model               string              `json:"model"`
+		Messages            []openAIChatMessage `json:"messages"`
+		Tools               []openAIChatTool    `json:"tools,omitempty"`
+		AddGenerationPrompt bool                `json:"add_generation_prompt"`
+	}
+
+	// openAIChatMessage is a message of the OpenAI Chat Completions API. Content is a string when every part is
+	// text, as some chat templates only take strings.
+	openAIChatMessage struct {
+		Role       string           `json:"role"`
+		Content    any              `json:"content"`
+		ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+		ToolCallID string           `json:"tool_call_id,omitempty"`
+	}
+
+	openAIContentPart struct {
+		Type     string `json:"type"`
+		Text     string `json:"text,omitempty"`
+		ImageURL *struct {
+			URL string `json:"url"`
+		} `json:"image_url,omitempty"`
+	}
+
+	openAIToolCall struct {
+		ID       string `json:"id"`
+		Type     string `json:"type"`
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	}
+
+	openAIChatTool struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description,omitempty"`
+			Parameters  json.RawMessage `json:"parameters,omitempty"`
+		} `json:"function"`
+	}
+)
+
+var (
+	_ Provider      = (*LocalClient)(nil)
+	_ UsageRecorder = (*LocalClient)(nil)
+	_ Pricer        = (*LocalClient)(nil)
+)
+
+// NewLocalClient returns a LocalClient.
+func NewLocalClient(config LocalConfig) (*LocalClient, error) {
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("NewLocalClient failed: missing base URL")
+	}
+
+	if config.Ledger == nil {
+		return nil, fmt.Errorf("NewLocalClient failed: missing usage ledger")
+	}
+
+	if config.GPU.HourlyUSD < 0 || config.GPU.InputTokensPerSecond < 0 || config.GPU.OutputTokensPerSecond < 0 {
+		return nil, fmt.Errorf("NewLocalClient failed: GPU cost must not be negative")
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &LocalClient{ledgerUsage: ledgerUsage{ledger: config.Ledger}, config: config}, nil
+}
+
+func (l *LocalClient) Name() string {
+	return "local"
+}
+
+// HealthCheck lists the models served by the server.
+func (l *LocalClient) HealthCheck(ctx context.Context) error {
+	if _, err := l.do(ctx, http.MethodGet, "/v1/models", nil); err != nil {
+		return fmt.Errorf("HealthCheck failed: %w", err)
+	}
+
+	return nil
+}
+
+// CountTokens counts the input tokens of prompt with the configured Tokenizer, or with the server's tokenize
+// endpoint, which applies the model's chat template. Blocks the endpoint cannot take, such as PDF documents, are
+// estimated offline.
+func (l *LocalClient) CountTokens(ctx context.Context, model string, prompt Prompt) (int, error) {
+	if l.config.Tokenizer != nil {
+		return l.config.Tokenizer.CountTokens(prompt), nil
+	}
+
+	messages, tools, offline := newOpenAIChatMessages(prompt)
+	payload, err := json.Marshal(localTokenizeRequest{
+		Model:               strings.TrimPrefix(model, LocalModelPrefix),
+		Messages:            messages,
+		Tools:               tools,
+		AddGenerationPrompt: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	body, err := l.do(ctx, http.MethodPost, "/tokenize", payload)
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	var resp struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	return resp.Count + offlineTokenizer(model).countContent(offline), nil
+}
+
+// newOpenAIChatMessages converts prompt into Chat Completions messages and tools. The system prompt becomes the first
+// message and every tool result a tool message of its own. It also returns the image and document blocks that have no
+// content part.
+func newOpenAIChatMessages(prompt Prompt) ([]openAIChatMessage, []openAIChatTool, Content) {
+	var messages []openAIChatMessage
+	var offline Content
+	if len(prompt.System) > 0 {
+		messages = append(messages, openAIChatMessage{Role: "system", Content: prompt.System.Text()})
+	}
+
+	for _, message := range prompt.Messages {
+		var parts []openAIContentPart
+		var toolCalls []openAIToolCall
+		for _, block := range message.Content {
+			switch block.Type {
+			case ContentBlockText:
+				parts = append(parts, openAIContentPart{Type: "text", Text: block.Text})
+			case ContentBlockImage, ContentBlockDocument:
+				part, ok := openAIMediaPart(block.Source)
+				if !ok {
+					offline = append(offline, block)
+					continue
+				}
+				parts = append(parts, part)
+			case ContentBlockToolUse:
+				call := openAIToolCall{ID: block.ID, Type: "function"}
+				call.Function.Name = block.Name
+				call.Function.Arguments = string(block.Input)
+				toolCalls = append(toolCalls, call)
+			case ContentBlockToolResult:
+				messages = append(messages, openAIChatMessage{Role: "tool", Content: block.Content.Text(), ToolCallID: block.ToolUseID})
+			}
+		}
+
+		if len(parts) == 0 && len(toolCalls) == 0 {
+			continue
+		}
+
+		messages = append(messages, openAIChatMessage{Role: string(message.Role), Content: openAIContent(parts), ToolCalls: toolCalls})
+	}
+
+	tools := make([]openAIChatTool, 0, len(prompt.Tools))
+	for _, tool := range prompt.Tools {
+		t := openAIChatTool{Type: "function"}
+		t.Function.Name = tool.Name
+		t.Function.Description = tool.Description
+		t.Function.Parameters = tool.InputSchema
+		tools = append(tools, t)
+	}
+
+	return messages, tools, offline
+}
+
+// openAIMediaPart converts an image or text document source into a content part. Other documents have no
+// counterpart.
+func openAIMediaPart(source *ContentSource) (openAIContentPart, bool) {
+	if source == nil {
+		return openAIContentPart{}, false
+	}
+
+	var url string
+	switch {
+	case source.Type == SourceText:
+		return openAIContentPart{Type: "text", Text: source.Data}, true
+	case source.Type == SourceURL:
+		url = source.URL
+	case source.Type == SourceBase64 && strings.HasPrefix(source.MediaType, "image/"):
+		url = "data:" + source.MediaType + ";base64," + source.Data
+	default:
+		return openAIContentPart{}, false
+	}
+
+	part := openAIContentPart{Type: "image_url"}
+	part.ImageURL = &struct {
+		URL string `json:"url"`
+	}{URL: url}
+
+	return part, true
+}
+
+// openAIContent returns parts as a string when they are all text, and as a list of parts otherwise.
+func openAIContent(parts []openAIContentPart) any {
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part.Type != "text" {
+			return parts
+		}
+		texts = append(texts, part.Text)
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+// Pricing returns the GPU cost of every model served by the cluster. It returns false when GPU cost is not configured.
+func (l *LocalClient) Pricing(model string) (Pricing, bool) {
+	return l.config.GPU.Pricing()
+}
+
+// Pricing converts the GPU cost into a price per million tokens. It returns false unless the hourly cost and both
+// throughputs are set.
+func (g GPUCost) Pricing() (Pricing, bool) {
+	if g.HourlyUSD <= 0 || g.InputTokensPerSecond <= 0 || g.OutputTokensPerSecond <= 0 {
+		return Pricing{}, false
+	}
+
+	perMTok := func(tokensPerSecond float64) float64 {
+		return g.HourlyUSD / (tokensPerSecond * 3600) * 1_000_000
+	}
+
+	// Cached prompt tokens skip prefill, so reading them costs nothing but the memory they take
+	input := perMTok(g.InputTokensPerSecond)
+	return Pricing{UncachedInput: input, CacheCreationInput: input, Output: perMTok(g.OutputTokensPerSecond)}, true
+}
+
+func (l *LocalClient) do(ctx context.Context, method string, path string, payload []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(l.config.BaseURL, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	if l.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+l.config.APIKey)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return doRequest(l.config.HTTPClient, req)
+}
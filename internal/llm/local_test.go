@@ -0,0 +1,127 @@
+package llm_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"scrollwork/internal/llm"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const localModel = "local/meta-llama/Llama-3.1-8B-Instruct"
+
+// newLocalFake returns a local fake of a vLLM server.
+func newLocalFake(t *testing.T) *fakeAPI {
+	t.Helper()
+
+	checkKey := func(r *http.Request, check *assert.Assertions) {
+		check.Equal("Bearer test-key", r.Header.Get("Authorization"))
+	}
+
+	return newFakeAPI(t, map[string]fakeRoute{
+		"/v1/models": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			checkKey(r, check)
+			fmt.Fprint(w, `{"object": "list", "data": []}`)
+		},
+		"/tokenize": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			checkKey(r, check)
+
+			var body struct {
+				Model               string        `json:"model"`
+				Messages            []llm.Message `json:"messages"`
+				AddGenerationPrompt bool          `json:"add_generation_prompt"`
+			}
+			check.NoError(json.NewDecoder(r.Body).Decode(&body))
+			check.Equal("meta-llama/Llama-3.1-8B-Instruct", body.Model)
+			check.True(body.AddGenerationPrompt)
+			check.Len(body.Messages, 1)
+
+			fmt.Fprint(w, `{"count": 42, "max_model_len": 131072, "tokens": []}`)
+		},
+	})
+}
+
+func TestLocalClient_CountTokens(t *testing.T) {
+	t.Parallel()
+
+	ledger, err := llm.NewLedger(llm.LedgerConfig{})
+	require.NoError(t, err)
+
+	tc := map[string]struct {
+		tokenizer llm.Tokenizer
+		expected  int
+	}{
+		"tokenize endpoint": {expected: 42},
+		"offline tokenizer": {tokenizer: llm.EstimateTokenizer{}, expected: 10},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			api := newLocalFake(t)
+			client, err := llm.NewLocalClient(llm.LocalConfig{BaseURL: api.URL, APIKey: "test-key", Tokenizer: td.tokenizer, Ledger: ledger})
+			require.NoError(t, err)
+			require.NoError(t, client.HealthCheck(context.Background()))
+
+			tokens, err := client.CountTokens(context.Background(), localModel, llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("Hello, world")}}})
+			api.requireMatched(t)
+			require.NoError(t, err)
+			require.Equal(t, td.expected, tokens)
+		})
+	}
+}
+
+func TestLocalClient_CountTokens_Offline(t *testing.T) {
+	t.Parallel()
+
+	ledger, err := llm.NewLedger(llm.LedgerConfig{})
+	require.NoError(t, err)
+
+	api := newLocalFake(t)
+	client, err := llm.NewLocalClient(llm.LocalConfig{BaseURL: api.URL, APIKey: "test-key", Ledger: ledger})
+	require.NoError(t, err)
+
+	// The server cannot take PDF documents, which are estimated offline
+	hello := llm.TextContent("Hello, world")
+	pdf := llm.ContentBlock{Type: llm.ContentBlockDocument, Source: &llm.ContentSource{Type: llm.SourceBase64, MediaType: "application/pdf", Data: "JVBERi0xLjQ="}}
+	prompt := func(content llm.Content) llm.Prompt {
+		return llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: content}}}
+	}
+	tokenizer := llm.EstimateTokenizer{}
+
+	tokens, err := client.CountTokens(context.Background(), localModel, prompt(append(hello, pdf)))
+	api.requireMatched(t)
+	require.NoError(t, err)
+	require.Equal(t, 42+tokenizer.CountTokens(prompt(append(hello, pdf)))-tokenizer.CountTokens(prompt(hello)), tokens)
+}
+
+func TestGPUCost_Pricing(t *testing.T) {
+	t.Parallel()
+
+	// $3.60 an hour is $0.001 a second
+	p, ok := llm.GPUCost{HourlyUSD: 3.6, InputTokensPerSecond: 10_000, OutputTokensPerSecond: 1_000}.Pricing()
+	require.True(t, ok)
+	require.InDelta(t, 0.1, p.UncachedInput, 1e-9)
+	require.InDelta(t, 1, p.Output, 1e-9)
+	require.Zero(t, p.CacheReadInput)
+
+	_, ok = llm.GPUCost{HourlyUSD: 3.6}.Pricing()
+	require.False(t, ok)
+}
+
+func TestEstimateTokenizer(t *testing.T) {
+	t.Parallel()
+
+	tokenizer := llm.EstimateTokenizer{}
+
+	require.Equal(t, 3, tokenizer.CountTokens(llm.Prompt{}))
+	// Every message costs its overhead
+	require.Equal(t, 3+4+2, tokenizer.CountTokens(llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("Hi!")}}}))
+	// Long words are split and numbers are grouped by three digits
+	require.Equal(t, 3+4+3+2, tokenizer.CountTokens(llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("tokenization 123456")}}}))
+}
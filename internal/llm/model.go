@@ -3,19 +3,24 @@ package llm
 import (
 	"context"
 	"fmt"
-	"strings"
+	"time"
 )
 
 const (
 	MessageRoleUser      MessageRole = "user"
 	MessageRoleAssistant MessageRole = "assistant"
+
+	TokenCategoryUncachedInput      TokenCategory = "uncached_input"
+	TokenCategoryCacheReadInput     TokenCategory = "cache_read_input"
+	TokenCategoryCacheCreationInput TokenCategory = "cache_creation_input"
+	TokenCategoryOutput             TokenCategory = "output"
 )
 
 type (
 	Message struct {
-		Role    MessageRole
-		Name    string
-		Content string
+		Role    MessageRole `json:"role"`
+		Name    string      `json:"name,omitempty"`
+		Content Content     `json:"content"`
 	}
 
 	ClientConfig struct {
@@ -48,6 +53,16 @@ type (
 		CachedTotal   int
 	}
 
+	// TokenUsage is the number of tokens used by a model in each TokenCategory.
+	TokenUsage struct {
+		UncachedInput      int
+		CacheReadInput     int
+		CacheCreationInput int
+		Output             int
+	}
+
+	TokenCategory string
+
 	MessageRole string
 )
 
@@ -66,18 +81,18 @@ func NewAPIClient(config ClientConfig) *APIClient {
 	return c
 }
 
-// GetCurrentOrganizationUsage fetchs the current input token usage for all the configured models
-func (c *APIClient) GetCurrentOrganizationUsage(ctx context.Context) (map[string]int, error) {
+// GetCurrentOrganizationUsage fetchs the input token usage between startingAt and endingAt for all the configured models
+func (c *APIClient) GetCurrentOrganizationUsage(ctx context.Context, startingAt time.Time, endingAt time.Time) (map[string]int, error) {
 	u := map[string]int{}
 	for _, model := range c.config.Models {
 		switch {
 		case IsAnthropicModel(model):
-			usage, err := c.anthropic.GetOrganizationMessageUsageReport(ctx)
+			usage, err := c.anthropic.GetOrganizationMessageUsageReport(ctx, startingAt, endingAt)
 			if err != nil {
 				return u, err
 			}
 			if tokens, ok := usage[model]; ok {
-				u[model] = tokens
+				u[model] = tokens.UncachedInput
 			}
 		case IsOpenAIModel(model):
 			usage, err := c.openai.GetOrganizationCompletionsUsage(ctx)
@@ -93,10 +108,34 @@ func (c *APIClient) GetCurrentOrganizationUsage(ctx context.Context) (map[string
 	return u, nil
 }
 
+// Add returns the sum of u and other.
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		UncachedInput:      u.UncachedInput + other.UncachedInput,
+		CacheReadInput:     u.CacheReadInput + other.CacheReadInput,
+		CacheCreationInput: u.CacheCreationInput + other.CacheCreationInput,
+		Output:             u.Output + other.Output,
+	}
+}
+
+// ByCategory returns the tokens used in each TokenCategory.
+func (u TokenUsage) ByCategory() map[TokenCategory]int {
+	return map[TokenCategory]int{
+		TokenCategoryUncachedInput:      u.UncachedInput,
+		TokenCategoryCacheReadInput:     u.CacheReadInput,
+		TokenCategoryCacheCreationInput: u.CacheCreationInput,
+		TokenCategoryOutput:             u.Output,
+	}
+}
+
+// IsAnthropicModel reports whether model is a catalogued Anthropic model on any platform.
 func IsAnthropicModel(model string) bool {
-	return strings.Contains(model, "claude-")
+	m, err := ParseModel(model)
+	return err == nil && m.Provider == ModelProviderAnthropic
 }
 
+// IsOpenAIModel reports whether model is a catalogued OpenAI model.
 func IsOpenAIModel(model string) bool {
-	return strings.Contains(model, "gpt-") || strings.Contains(model, "text-")
+	m, err := ParseModel(model)
+	return err == nil && m.Provider == ModelProviderOpenAI
 }
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// oauthTokenSource exchanges a grant for OAuth access tokens at a token endpoint and caches them until they are
+	// about to expire.
+	oauthTokenSource struct {
+		tokenURL   string
+		httpClient *http.Client
+		// grant returns the form of a token request made at now, such as client credentials or a signed JWT
+		// assertion.
+		grant func(now time.Time) (url.Values, error)
+
+		token     string
+		expiresAt time.Time
+		mu        sync.Mutex
+	}
+)
+
+// accessTokenRefreshMargin is how long before it expires an access token is replaced.
+const accessTokenRefreshMargin = time.Minute
+
+// Token returns a valid access token, exchanging the grant again once the cached token is about to expire.
+func (s *oauthTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.token != "" && now.Add(accessTokenRefreshMargin).Before(s.expiresAt) {
+		return s.token, nil
+	}
+
+	form, err := s.grant(now)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := doRequest(s.httpClient, req)
+	if err != nil {
+		return "", fmt.Errorf("token exchange failed: %w", err)
+	}
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("token exchange failed: %w", err)
+	}
+
+	if resp.AccessToken == "" {
+		return "", fmt.Errorf("token exchange failed: missing access token")
+	}
+
+	s.token = resp.AccessToken
+	s.expiresAt = now.Add(time.Duration(resp.ExpiresIn) * time.Second)
+
+	return s.token, nil
+}
@@ -0,0 +1,44 @@
+package llm
+
+type (
+	// Pricing is the list price of a model in USD per million tokens of each TokenCategory.
+	Pricing struct {
+		UncachedInput      float64 `json:"uncached_input"`
+		CacheReadInput     float64 `json:"cache_read_input"`
+		CacheCreationInput float64 `json:"cache_creation_input"`
+		Output             float64 `json:"output"`
+
+		// LongContext is the price of prompts longer than LongContextTokens, for models that charge more for them.
+		LongContext *Pricing `json:"long_context,omitempty"`
+	}
+)
+
+// LongContextTokens is the prompt length past which long context pricing applies.
+const LongContextTokens = 200_000
+
+// LookupPricing returns the list price of model. It returns false if model is not in the catalog.
+func LookupPricing(model string) (Pricing, bool) {
+	entry, err := ParseModel(model)
+	if err != nil {
+		return Pricing{}, false
+	}
+
+	return catalogIndex[entry.Key].pricing, true
+}
+
+// ForPrompt returns the price tier of a prompt of promptTokens input tokens.
+func (p Pricing) ForPrompt(promptTokens int) Pricing {
+	if p.LongContext != nil && promptTokens > LongContextTokens {
+		return *p.LongContext
+	}
+
+	return p
+}
+
+// Cost returns the cost of usage in USD.
+func (p Pricing) Cost(usage TokenUsage) float64 {
+	return (float64(usage.UncachedInput)*p.UncachedInput +
+		float64(usage.CacheReadInput)*p.CacheReadInput +
+		float64(usage.CacheCreationInput)*p.CacheCreationInput +
+		float64(usage.Output)*p.Output) / 1_000_000
+}
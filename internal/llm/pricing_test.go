@@ -0,0 +1,62 @@
+package llm_test
+
+import (
+	"scrollwork/internal/llm"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupPricing(t *testing.T) {
+	t.Parallel()
+
+	tc := []struct {
+		model    string
+		expected float64
+		ok       bool
+	}{
+		{model: "claude-sonnet-4-20250514", expected: 3, ok: true},
+		{model: "anthropic.claude-opus-4-1-20250805-v1:0", expected: 15, ok: true},
+		{model: "claude-opus-4-5-20251101", expected: 5, ok: true},
+		{model: "gpt-4o-mini", expected: 0.15, ok: true},
+		{model: "gpt-4o-2024-08-06", expected: 2.5, ok: true},
+		{model: "unknown-model", ok: false},
+	}
+
+	for _, td := range tc {
+		p, ok := llm.LookupPricing(td.model)
+		require.Equal(t, td.ok, ok, td.model)
+		require.Equal(t, td.expected, p.UncachedInput, td.model)
+	}
+}
+
+func TestPricingCost(t *testing.T) {
+	t.Parallel()
+
+	p, ok := llm.LookupPricing("claude-sonnet-4-20250514")
+	require.True(t, ok)
+
+	cost := p.Cost(llm.TokenUsage{UncachedInput: 1_000_000, CacheReadInput: 1_000_000, CacheCreationInput: 1_000_000, Output: 1_000_000})
+	require.InDelta(t, 3+0.3+3.75+15, cost, 1e-9)
+}
+
+func TestPricingForPrompt(t *testing.T) {
+	t.Parallel()
+
+	tc := []struct {
+		model        string
+		promptTokens int
+		expected     float64
+	}{
+		{model: "gemini-2.5-pro", promptTokens: 200_000, expected: 1.25},
+		{model: "gemini-2.5-pro", promptTokens: 200_001, expected: 2.5},
+		{model: "claude-sonnet-4-20250514", promptTokens: 250_000, expected: 6},
+		{model: "gemini-2.5-flash", promptTokens: 500_000, expected: 0.3},
+	}
+
+	for _, td := range tc {
+		p, ok := llm.LookupPricing(td.model)
+		require.True(t, ok, td.model)
+		require.Equal(t, td.expected, p.ForPrompt(td.promptTokens).UncachedInput, td.model)
+	}
+}
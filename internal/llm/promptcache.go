@@ -0,0 +1,229 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// CacheControl is an Anthropic prompt cache breakpoint.
+	CacheControl struct {
+		Type string `json:"type"`
+		// TTL is how long the cached prefix lives, 5m or 1h. Defaults to 5m.
+		TTL string `json:"ttl,omitempty"`
+	}
+
+	PromptCacheConfig struct {
+		// MinTokens is the shortest prefix that is cached for models whose minimum is not in the catalog. Defaults to
+		// 1024, the minimum of most Claude models.
+		MinTokens int
+	}
+
+	// PromptCache estimates which prefix of a prompt is read from a model's prompt cache and which prefixes are
+	// written to it. It remembers the prefixes at the cache breakpoints of recent prompts until their TTL expires.
+	//
+	// Prompts are assumed to be sent once they are assessed. A prompt reads the longest prefix, up to its last
+	// breakpoint, that was cached by an earlier prompt, wherever that prompt put its breakpoint.
+	PromptCache struct {
+		config PromptCacheConfig
+
+		models map[string]map[[sha256.Size]byte]cachedPrefix
+		mu     sync.Mutex
+	}
+
+	// CacheEstimate splits the input tokens of a prompt between the prompt cache and uncached input.
+	CacheEstimate struct {
+		UncachedInputTokens      int `json:"uncached_input_tokens"`
+		CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+		// CacheCreation1hInputTokens are the cache writes of breakpoints with a 1h TTL. They are not part of
+		// CacheCreationInputTokens.
+		CacheCreation1hInputTokens int `json:"cache_creation_1h_input_tokens,omitempty"`
+	}
+
+	cachedPrefix struct {
+		ttl       time.Duration
+		expiresAt time.Time
+	}
+
+	// promptPrefix is the prompt up to and including a tool or content block.
+	promptPrefix struct {
+		prompt Prompt
+		hash   [sha256.Size]byte
+		// ttl is the TTL of the block's cache breakpoint, or zero when the block is not a breakpoint.
+		ttl time.Duration
+	}
+)
+
+const (
+	defaultCacheMinTokens = 1024
+	defaultCacheTTL       = 5 * time.Minute
+
+	// cacheWrite1hMultiplier is the price of 1h cache writes relative to uncached input.
+	cacheWrite1hMultiplier = 2
+)
+
+// NewPromptCache returns a PromptCache.
+func NewPromptCache(config PromptCacheConfig) *PromptCache {
+	if config.MinTokens <= 0 {
+		config.MinTokens = defaultCacheMinTokens
+	}
+
+	return &PromptCache{
+		config: config,
+		models: make(map[string]map[[sha256.Size]byte]cachedPrefix),
+	}
+}
+
+// Estimate splits the tokens of prompt, as counted by the provider of model, between cache reads, cache writes and
+// uncached input, and remembers the prefixes the prompt caches. The tokens of each prefix are estimated with tokenizer
+// in proportion to tokens. Prefixes shorter than the model's CacheMinTokens are not cached.
+func (c *PromptCache) Estimate(model Model, prompt Prompt, tokens int, tokenizer Tokenizer, now time.Time) CacheEstimate {
+	prefixes := promptPrefixes(prompt)
+
+	last := -1
+	for i, prefix := range prefixes {
+		if prefix.ttl > 0 {
+			last = i
+		}
+	}
+
+	if last < 0 {
+		return CacheEstimate{UncachedInputTokens: tokens}
+	}
+
+	scale := 1.0
+	if estimated := tokenizer.CountTokens(prompt); estimated > 0 {
+		scale = float64(tokens) / float64(estimated)
+	}
+	prefixTokens := func(prefix promptPrefix) int {
+		return min(tokens, int(math.Round(float64(tokenizer.CountTokens(prefix.prompt))*scale)))
+	}
+
+	minTokens := model.CacheMinTokens
+	if minTokens <= 0 {
+		minTokens = c.config.MinTokens
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.models[model.ID]
+	if !ok {
+		cached = make(map[[sha256.Size]byte]cachedPrefix)
+		c.models[model.ID] = cached
+	}
+	for hash, entry := range cached {
+		if !now.Before(entry.expiresAt) {
+			delete(cached, hash)
+		}
+	}
+
+	var estimate CacheEstimate
+
+	hit := -1
+	for i := last; i >= 0; i-- {
+		if entry, ok := cached[prefixes[i].hash]; ok {
+			hit = i
+			estimate.CacheReadInputTokens = prefixTokens(prefixes[i])
+
+			// Reading a prefix refreshes its TTL
+			entry.expiresAt = now.Add(entry.ttl)
+			cached[prefixes[i].hash] = entry
+			break
+		}
+	}
+
+	written := estimate.CacheReadInputTokens
+	for i := hit + 1; i <= last; i++ {
+		if prefixes[i].ttl == 0 {
+			continue
+		}
+
+		n := prefixTokens(prefixes[i])
+		if n < minTokens || n <= written {
+			continue
+		}
+
+		if prefixes[i].ttl >= time.Hour {
+			estimate.CacheCreation1hInputTokens += n - written
+		} else {
+			estimate.CacheCreationInputTokens += n - written
+		}
+		written = n
+
+		cached[prefixes[i].hash] = cachedPrefix{ttl: prefixes[i].ttl, expiresAt: now.Add(prefixes[i].ttl)}
+	}
+
+	estimate.UncachedInputTokens = max(0, tokens-written)
+	return estimate
+}
+
+// Cost returns the cost in USD of the estimated input and outputTokens output tokens. 1h cache writes cost twice as
+// much as uncached input.
+func (e CacheEstimate) Cost(pricing Pricing, outputTokens int) float64 {
+	return pricing.Cost(TokenUsage{
+		UncachedInput:      e.UncachedInputTokens,
+		CacheReadInput:     e.CacheReadInputTokens,
+		CacheCreationInput: e.CacheCreationInputTokens,
+		Output:             outputTokens,
+	}) + float64(e.CacheCreation1hInputTokens)*pricing.UncachedInput*cacheWrite1hMultiplier/1_000_000
+}
+
+// promptPrefixes returns the prefix of prompt at every tool and content block, in the order they are cached: tools,
+// then the system prompt, then messages. Prefixes are hashed without their cache breakpoints, so moving a breakpoint
+// does not change the prefixes before it.
+func promptPrefixes(prompt Prompt) []promptPrefix {
+	var prefixes []promptPrefix
+	hash := sha256.New()
+	add := func(prefix Prompt, section string, block any, control *CacheControl) {
+		data, _ := json.Marshal(block)
+		hash.Write([]byte(section))
+		hash.Write(data)
+
+		p := promptPrefix{prompt: prefix, ttl: control.duration()}
+		hash.Sum(p.hash[:0])
+		prefixes = append(prefixes, p)
+	}
+
+	for i, tool := range prompt.Tools {
+		control := tool.CacheControl
+		tool.CacheControl = nil
+		add(Prompt{Tools: prompt.Tools[:i+1]}, "tool", tool, control)
+	}
+
+	for i, block := range prompt.System {
+		control := block.CacheControl
+		block.CacheControl = nil
+		add(Prompt{Tools: prompt.Tools, System: prompt.System[:i+1]}, "system", block, control)
+	}
+
+	for i, message := range prompt.Messages {
+		for j, block := range message.Content {
+			messages := append(prompt.Messages[:i:i], Message{Role: message.Role, Content: message.Content[:j+1]})
+
+			control := block.CacheControl
+			block.CacheControl = nil
+			add(Prompt{Tools: prompt.Tools, System: prompt.System, Messages: messages}, string(message.Role)+strconv.Itoa(i), block, control)
+		}
+	}
+
+	return prefixes
+}
+
+// duration returns the TTL of the breakpoint, or zero when there is no breakpoint.
+func (c *CacheControl) duration() time.Duration {
+	if c == nil {
+		return 0
+	}
+
+	if ttl, err := time.ParseDuration(c.TTL); err == nil && ttl > 0 {
+		return ttl
+	}
+
+	return defaultCacheTTL
+}
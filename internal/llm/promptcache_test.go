@@ -0,0 +1,153 @@
+package llm_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"scrollwork/internal/llm"
+)
+
+func TestPromptCache_Estimate(t *testing.T) {
+	t.Parallel()
+
+	model, err := llm.ParseModel("claude-sonnet-4-20250514")
+	require.NoError(t, err)
+	tokenizer := llm.EstimateTokenizer{}
+	cache := llm.NewPromptCache(llm.PromptCacheConfig{})
+	now := time.Now()
+
+	system := llm.Content{{Type: llm.ContentBlockText, Text: strings.Repeat("word ", 2000), CacheControl: &llm.CacheControl{Type: "ephemeral"}}}
+	question := llm.Message{Role: llm.MessageRoleUser, Content: llm.TextContent("Summarize the document.")}
+
+	estimate := func(prompt llm.Prompt, at time.Time) (llm.CacheEstimate, int) {
+		tokens := tokenizer.CountTokens(prompt)
+		return cache.Estimate(model, prompt, tokens, tokenizer, at), tokens
+	}
+
+	// The first prompt writes the system prompt to the cache
+	first := llm.Prompt{System: system, Messages: []llm.Message{question}}
+	e, tokens := estimate(first, now)
+	require.Zero(t, e.CacheReadInputTokens)
+	require.Greater(t, e.CacheCreationInputTokens, 2000)
+	require.Equal(t, tokens, e.UncachedInputTokens+e.CacheCreationInputTokens)
+	written := e.CacheCreationInputTokens
+
+	// The same prompt reads it back
+	e, tokens = estimate(first, now.Add(time.Minute))
+	require.Equal(t, written, e.CacheReadInputTokens)
+	require.Zero(t, e.CacheCreationInputTokens)
+	require.Equal(t, tokens, e.UncachedInputTokens+e.CacheReadInputTokens)
+
+	// The next turn moves its breakpoint to the last message, reading the system prompt and writing the conversation
+	answer := llm.Message{Role: llm.MessageRoleAssistant, Content: llm.TextContent(strings.Repeat("summary ", 500))}
+	followUp := llm.Message{Role: llm.MessageRoleUser, Content: llm.Content{{Type: llm.ContentBlockText, Text: "Shorter.", CacheControl: &llm.CacheControl{Type: "ephemeral"}}}}
+	next := llm.Prompt{System: llm.TextContent(system[0].Text), Messages: []llm.Message{question, answer, followUp}}
+	e, tokens = estimate(next, now.Add(2*time.Minute))
+	require.Equal(t, written, e.CacheReadInputTokens)
+	require.Equal(t, tokens, e.CacheReadInputTokens+e.CacheCreationInputTokens)
+
+	e, tokens = estimate(next, now.Add(3*time.Minute))
+	require.Equal(t, tokens, e.CacheReadInputTokens)
+
+	// Reads refresh the TTL, which expires five minutes after the last read
+	e, _ = estimate(first, now.Add(6*time.Minute))
+	require.Equal(t, written, e.CacheReadInputTokens)
+	e, _ = estimate(first, now.Add(13*time.Minute))
+	require.Zero(t, e.CacheReadInputTokens)
+	require.Equal(t, written, e.CacheCreationInputTokens)
+}
+
+func TestPromptCache_Estimate_Breakpoints(t *testing.T) {
+	t.Parallel()
+
+	model, err := llm.ParseModel("claude-sonnet-4-20250514")
+	require.NoError(t, err)
+	tokenizer := llm.EstimateTokenizer{}
+	long := strings.Repeat("word ", 2000)
+
+	tc := map[string]struct {
+		prompt   llm.Prompt
+		expected func(tokens int) llm.CacheEstimate
+	}{
+		"no breakpoints": {
+			prompt:   llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent(long)}}},
+			expected: func(tokens int) llm.CacheEstimate { return llm.CacheEstimate{UncachedInputTokens: tokens} },
+		},
+		"too short to cache": {
+			prompt: llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.Content{
+				{Type: llm.ContentBlockText, Text: "Hello", CacheControl: &llm.CacheControl{Type: "ephemeral"}},
+			}}}},
+			expected: func(tokens int) llm.CacheEstimate { return llm.CacheEstimate{UncachedInputTokens: tokens} },
+		},
+		"1h ttl": {
+			prompt: llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.Content{
+				{Type: llm.ContentBlockText, Text: long, CacheControl: &llm.CacheControl{Type: "ephemeral", TTL: "1h"}},
+			}}}},
+			expected: func(tokens int) llm.CacheEstimate { return llm.CacheEstimate{CacheCreation1hInputTokens: tokens} },
+		},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tokens := tokenizer.CountTokens(td.prompt)
+			cache := llm.NewPromptCache(llm.PromptCacheConfig{})
+			require.Equal(t, td.expected(tokens), cache.Estimate(model, td.prompt, tokens, tokenizer, time.Now()))
+		})
+	}
+}
+
+func TestPromptCache_Estimate_MinTokens(t *testing.T) {
+	t.Parallel()
+
+	tokenizer := llm.EstimateTokenizer{}
+
+	tc := map[string]struct {
+		model  string
+		words  int
+		cached bool
+	}{
+		"sonnet 4":            {model: "claude-sonnet-4-20250514", words: 1500, cached: true},
+		"haiku 3.5":           {model: "claude-3-5-haiku-20241022", words: 3000, cached: true},
+		"haiku 3.5 too short": {model: "claude-3-5-haiku-20241022", words: 1500},
+		"haiku 4.5 too short": {model: "claude-haiku-4-5-20251001", words: 3000},
+		"opus 4.5 too short":  {model: "claude-opus-4-5-20251101", words: 3000},
+		"opus 4.5":            {model: "claude-opus-4-5-20251101", words: 5000, cached: true},
+		"bedrock haiku 3":     {model: "anthropic.claude-3-haiku-20240307-v1:0", words: 1500},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			model, err := llm.ParseModel(td.model)
+			require.NoError(t, err)
+
+			prompt := llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.Content{
+				{Type: llm.ContentBlockText, Text: strings.Repeat("word ", td.words), CacheControl: &llm.CacheControl{Type: "ephemeral"}},
+			}}}}
+			tokens := tokenizer.CountTokens(prompt)
+
+			estimate := llm.NewPromptCache(llm.PromptCacheConfig{}).Estimate(model, prompt, tokens, tokenizer, time.Now())
+			require.Equal(t, td.cached, estimate.CacheCreationInputTokens > 0)
+		})
+	}
+}
+
+func TestCacheEstimate_Cost(t *testing.T) {
+	t.Parallel()
+
+	pricing := llm.Pricing{UncachedInput: 3, CacheReadInput: 0.3, CacheCreationInput: 3.75, Output: 15}
+	estimate := llm.CacheEstimate{
+		UncachedInputTokens:        1_000_000,
+		CacheReadInputTokens:       1_000_000,
+		CacheCreationInputTokens:   1_000_000,
+		CacheCreation1hInputTokens: 1_000_000,
+	}
+
+	require.InDelta(t, 3+0.3+3.75+6+15, estimate.Cost(pricing, 1_000_000), 1e-9)
+}
@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+type (
+	// Provider serves LLM models. Usage is fetched from and prompts are counted by the provider of each model.
+	Provider interface {
+		// Name identifies the provider in logs, traces and metrics.
+		Name() string
+
+		// HealthCheck verifies the provider can be reached with its configured credentials.
+		HealthCheck(ctx context.Context) error
+
+		// CountTokens counts the input tokens of prompt when sent to model.
+		CountTokens(ctx context.Context, model string, prompt Prompt) (int, error)
+
+		// Usage fetches the token usage of models between startingAt and endingAt. Models without usage may be
+		// missing from the result.
+		Usage(ctx context.Context, models []string, startingAt time.Time, endingAt time.Time) (map[string]TokenUsage, error)
+	}
+
+	// Pricer is implemented by providers that price their models themselves rather than by catalog list price.
+	Pricer interface {
+		Pricing(model string) (Pricing, bool)
+	}
+
+	// HTTPError is returned by providers that call their APIs directly when a request does not succeed.
+	HTTPError struct {
+		StatusCode int
+		Header     http.Header
+		Body       string
+	}
+)
+
+// maxErrorBodySize bounds how much of an error response is kept in an HTTPError.
+const maxErrorBodySize = 4096
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// doRequest sends req and returns the response body. Responses other than 2xx are returned as an *HTTPError.
+func doRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Header: resp.Header, Body: string(body)}
+	}
+
+	return io.ReadAll(resp.Body)
+}
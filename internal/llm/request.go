@@ -0,0 +1,318 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// RequestSchemaAnthropic is the body of an Anthropic Messages API request, or MessageCreateParams.
+	RequestSchemaAnthropic RequestSchema = "anthropic_messages"
+	// RequestSchemaOpenAI is the body of an OpenAI Chat Completions request, or ChatCompletionNewParams.
+	RequestSchemaOpenAI RequestSchema = "openai_chat_completions"
+)
+
+type (
+	RequestSchema string
+
+	// Request is the part of a provider request body that is assessed.
+	Request struct {
+		Schema RequestSchema
+		// Model is the model the request is sent to. It is empty when the model is not part of the body, as with
+		// Bedrock and Vertex AI.
+		Model     string
+		Prompt    Prompt
+		MaxTokens int
+	}
+
+	anthropicRequest struct {
+		Model     string    `json:"model"`
+		System    Content   `json:"system"`
+		Messages  []Message `json:"messages"`
+		Tools     []Tool    `json:"tools"`
+		MaxTokens int       `json:"max_tokens"`
+	}
+
+	openAIRequest struct {
+		Model               string                 `json:"model"`
+		Messages            []openAIRequestMessage `json:"messages"`
+		Tools               []openAIChatTool       `json:"tools"`
+		MaxTokens           int                    `json:"max_tokens"`
+		MaxCompletionTokens int                    `json:"max_completion_tokens"`
+	}
+
+	openAIRequestMessage struct {
+		Role       string           `json:"role"`
+		Content    json.RawMessage  `json:"content"`
+		ToolCalls  []openAIToolCall `json:"tool_calls"`
+		ToolCallID string           `json:"tool_call_id"`
+	}
+
+	openAIRequestPart struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		ImageURL struct {
+			URL string `json:"url"`
+		} `json:"image_url"`
+		File struct {
+			FileData string `json:"file_data"`
+			Filename string `json:"filename"`
+		} `json:"file"`
+	}
+
+	// requestProbe holds the fields that tell the request schemas apart.
+	requestProbe struct {
+		Model               string          `json:"model"`
+		System              json.RawMessage `json:"system"`
+		MaxCompletionTokens json.RawMessage `json:"max_completion_tokens"`
+		Messages            []struct {
+			Role      string          `json:"role"`
+			Content   json.RawMessage `json:"content"`
+			ToolCalls json.RawMessage `json:"tool_calls"`
+		} `json:"messages"`
+		Tools []map[string]json.RawMessage `json:"tools"`
+	}
+)
+
+// anthropicOnlyBlocks and openAIOnlyParts are the content types only one of the schemas has.
+var (
+	anthropicOnlyBlocks = []ContentBlockType{ContentBlockImage, ContentBlockDocument, ContentBlockToolUse, ContentBlockToolResult}
+	openAIOnlyParts     = []string{"image_url", "input_audio", "file"}
+)
+
+// ParseRequest parses the body of a Messages API or Chat Completions request into the prompt it sends.
+func ParseRequest(body []byte) (Request, error) {
+	schema, err := DetectRequestSchema(body)
+	if err != nil {
+		return Request{}, fmt.Errorf("ParseRequest failed: %w", err)
+	}
+
+	var req Request
+	switch schema {
+	case RequestSchemaOpenAI:
+		req, err = parseOpenAIRequest(body)
+	default:
+		req, err = parseAnthropicRequest(body)
+	}
+	if err != nil {
+		return Request{}, fmt.Errorf("ParseRequest failed: %s: %w", schema, err)
+	}
+
+	if len(req.Prompt.Messages) == 0 {
+		return Request{}, fmt.Errorf("ParseRequest failed: %s: messages are required", schema)
+	}
+
+	return req, nil
+}
+
+// DetectRequestSchema tells a Messages API body from a Chat Completions body by the fields and content types only one
+// of them has. Bodies both schemas read the same way are told apart by the provider of their model, and are otherwise
+// read as Messages API bodies.
+func DetectRequestSchema(body []byte) (RequestSchema, error) {
+	var probe requestProbe
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return "", fmt.Errorf("DetectRequestSchema failed: %w", err)
+	}
+
+	if len(probe.System) > 0 {
+		return RequestSchemaAnthropic, nil
+	}
+	if len(probe.MaxCompletionTokens) > 0 {
+		return RequestSchemaOpenAI, nil
+	}
+
+	for _, tool := range probe.Tools {
+		if _, ok := tool["input_schema"]; ok {
+			return RequestSchemaAnthropic, nil
+		}
+		if _, ok := tool["function"]; ok {
+			return RequestSchemaOpenAI, nil
+		}
+	}
+
+	for _, message := range probe.Messages {
+		switch message.Role {
+		case "system", "developer", "tool", "function":
+			return RequestSchemaOpenAI, nil
+		}
+
+		if len(message.ToolCalls) > 0 {
+			return RequestSchemaOpenAI, nil
+		}
+
+		var parts []struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(message.Content, &parts) != nil {
+			continue
+		}
+		for _, part := range parts {
+			for _, blockType := range anthropicOnlyBlocks {
+				if part.Type == string(blockType) {
+					return RequestSchemaAnthropic, nil
+				}
+			}
+			for _, partType := range openAIOnlyParts {
+				if part.Type == partType {
+					return RequestSchemaOpenAI, nil
+				}
+			}
+		}
+	}
+
+	if m, err := ParseModel(probe.Model); err == nil && m.Provider == ModelProviderOpenAI {
+		return RequestSchemaOpenAI, nil
+	}
+
+	return RequestSchemaAnthropic, nil
+}
+
+func parseAnthropicRequest(body []byte) (Request, error) {
+	var r anthropicRequest
+	if err := json.Unmarshal(body, &r); err != nil {
+		return Request{}, err
+	}
+
+	return Request{
+		Schema:    RequestSchemaAnthropic,
+		Model:     r.Model,
+		Prompt:    Prompt{System: r.System, Messages: r.Messages, Tools: r.Tools},
+		MaxTokens: r.MaxTokens,
+	}, nil
+}
+
+// parseOpenAIRequest converts a Chat Completions body into a prompt. System and developer messages become the system
+// prompt, tool calls become tool_use blocks and tool messages tool_result blocks of a user message.
+func parseOpenAIRequest(body []byte) (Request, error) {
+	var r openAIRequest
+	if err := json.Unmarshal(body, &r); err != nil {
+		return Request{}, err
+	}
+
+	req := Request{Schema: RequestSchemaOpenAI, Model: r.Model, MaxTokens: r.MaxCompletionTokens}
+	if req.MaxTokens == 0 {
+		req.MaxTokens = r.MaxTokens
+	}
+
+	for i, message := range r.Messages {
+		content, err := openAIRequestContent(message.Content)
+		if err != nil {
+			return Request{}, fmt.Errorf("message %d: %w", i, err)
+		}
+
+		switch message.Role {
+		case "system", "developer":
+			req.Prompt.System = append(req.Prompt.System, content...)
+		case "assistant":
+			for _, call := range message.ToolCalls {
+				content = append(content, ContentBlock{Type: ContentBlockToolUse, ID: call.ID, Name: call.Function.Name, Input: toolCallInput(call.Function.Arguments)})
+			}
+			req.Prompt.Messages = append(req.Prompt.Messages, Message{Role: MessageRoleAssistant, Content: content})
+		case "tool":
+			result := ContentBlock{Type: ContentBlockToolResult, ToolUseID: message.ToolCallID, Content: content}
+
+			// Results of parallel tool calls are sent as a single user message
+			if n := len(req.Prompt.Messages); n > 0 && isToolResults(req.Prompt.Messages[n-1]) {
+				req.Prompt.Messages[n-1].Content = append(req.Prompt.Messages[n-1].Content, result)
+				continue
+			}
+			req.Prompt.Messages = append(req.Prompt.Messages, Message{Role: MessageRoleUser, Content: Content{result}})
+		default:
+			req.Prompt.Messages = append(req.Prompt.Messages, Message{Role: MessageRoleUser, Content: content})
+		}
+	}
+
+	for _, tool := range r.Tools {
+		req.Prompt.Tools = append(req.Prompt.Tools, Tool{Name: tool.Function.Name, Description: tool.Function.Description, InputSchema: tool.Function.Parameters})
+	}
+
+	return req, nil
+}
+
+// openAIRequestContent converts the content of a Chat Completions message, a string or a list of parts, into content
+// blocks. Image URLs and files are sent as base64 data when they are data URLs.
+func openAIRequestContent(data json.RawMessage) (Content, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+
+	if data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return nil, err
+		}
+
+		return TextContent(text), nil
+	}
+
+	var parts []openAIRequestPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return nil, fmt.Errorf("content must be a string or a list of content parts: %w", err)
+	}
+
+	content := make(Content, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			content = append(content, ContentBlock{Type: ContentBlockText, Text: part.Text})
+		case "image_url":
+			source := &ContentSource{Type: SourceURL, URL: part.ImageURL.URL}
+			if mediaType, data, ok := parseDataURL(part.ImageURL.URL); ok {
+				source = &ContentSource{Type: SourceBase64, MediaType: mediaType, Data: data}
+			}
+			content = append(content, ContentBlock{Type: ContentBlockImage, Source: source})
+		case "file":
+			block := ContentBlock{Type: ContentBlockDocument, Title: part.File.Filename}
+			if mediaType, data, ok := parseDataURL(part.File.FileData); ok {
+				block.Source = &ContentSource{Type: SourceBase64, MediaType: mediaType, Data: data}
+			}
+			content = append(content, block)
+		}
+	}
+
+	return content, nil
+}
+
+// parseDataURL returns the media type and base64 data of a base64 data URL, such as data:image/png;base64,iVBOR...
+func parseDataURL(u string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(u, "data:")
+	if !ok {
+		return "", "", false
+	}
+
+	mediaType, data, ok := strings.Cut(rest, ";base64,")
+	if !ok {
+		return "", "", false
+	}
+
+	return mediaType, data, true
+}
+
+// toolCallInput returns the arguments of a tool call, which are a JSON encoded string, as raw JSON. Arguments that
+// are not valid JSON are kept as a string.
+func toolCallInput(arguments string) json.RawMessage {
+	if json.Valid([]byte(arguments)) {
+		return json.RawMessage(arguments)
+	}
+
+	encoded, _ := json.Marshal(arguments)
+	return encoded
+}
+
+// isToolResults reports whether message is a user message of tool results only.
+func isToolResults(message Message) bool {
+	if message.Role != MessageRoleUser || len(message.Content) == 0 {
+		return false
+	}
+
+	for _, block := range message.Content {
+		if block.Type != ContentBlockToolResult {
+			return false
+		}
+	}
+
+	return true
+}
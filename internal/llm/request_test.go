@@ -0,0 +1,153 @@
+package llm_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"scrollwork/internal/llm"
+)
+
+func TestDetectRequestSchema(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]struct {
+		body     string
+		expected llm.RequestSchema
+	}{
+		"anthropic system": {
+			body:     `{"model": "gpt-4o", "system": "Be brief.", "messages": [{"role": "user", "content": "Hi"}]}`,
+			expected: llm.RequestSchemaAnthropic,
+		},
+		"anthropic tools": {
+			body:     `{"model": "my-model", "messages": [{"role": "user", "content": "Hi"}], "tools": [{"name": "get_weather", "input_schema": {}}]}`,
+			expected: llm.RequestSchemaAnthropic,
+		},
+		"anthropic content blocks": {
+			body:     `{"messages": [{"role": "user", "content": [{"type": "image", "source": {"type": "url", "url": "https://example.com/cat.png"}}]}]}`,
+			expected: llm.RequestSchemaAnthropic,
+		},
+		"openai system message": {
+			body:     `{"model": "claude-sonnet-4-20250514", "messages": [{"role": "developer", "content": "Be brief."}, {"role": "user", "content": "Hi"}]}`,
+			expected: llm.RequestSchemaOpenAI,
+		},
+		"openai max completion tokens": {
+			body:     `{"model": "local/llama", "messages": [{"role": "user", "content": "Hi"}], "max_completion_tokens": 100}`,
+			expected: llm.RequestSchemaOpenAI,
+		},
+		"openai content parts": {
+			body:     `{"messages": [{"role": "user", "content": [{"type": "image_url", "image_url": {"url": "https://example.com/cat.png"}}]}]}`,
+			expected: llm.RequestSchemaOpenAI,
+		},
+		"openai model": {
+			body:     `{"model": "gpt-4o", "messages": [{"role": "user", "content": "Hi"}], "max_tokens": 100}`,
+			expected: llm.RequestSchemaOpenAI,
+		},
+		"ambiguous": {
+			body:     `{"model": "claude-sonnet-4-20250514", "messages": [{"role": "user", "content": "Hi"}], "max_tokens": 100}`,
+			expected: llm.RequestSchemaAnthropic,
+		},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			schema, err := llm.DetectRequestSchema([]byte(td.body))
+			require.NoError(t, err)
+			require.Equal(t, td.expected, schema)
+		})
+	}
+}
+
+func TestParseRequest_Anthropic(t *testing.T) {
+	t.Parallel()
+
+	req, err := llm.ParseRequest([]byte(`{
+		"model": "claude-sonnet-4-20250514",
+		"max_tokens": 1024,
+		"system": [{"type": "text", "text": "Be brief."}],
+		"messages": [{"role": "user", "content": "What is the weather in Paris?"}],
+		"tools": [{"name": "get_weather", "description": "Returns the weather in a city.", "input_schema": {"type": "object"}}],
+		"temperature": 0.5
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, llm.Request{
+		Schema: llm.RequestSchemaAnthropic,
+		Model:  "claude-sonnet-4-20250514",
+		Prompt: llm.Prompt{
+			System:   llm.TextContent("Be brief."),
+			Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("What is the weather in Paris?")}},
+			Tools:    []llm.Tool{{Name: "get_weather", Description: "Returns the weather in a city.", InputSchema: json.RawMessage(`{"type": "object"}`)}},
+		},
+		MaxTokens: 1024,
+	}, req)
+}
+
+func TestParseRequest_OpenAI(t *testing.T) {
+	t.Parallel()
+
+	req, err := llm.ParseRequest([]byte(`{
+		"model": "gpt-4o",
+		"max_completion_tokens": 512,
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": [
+				{"type": "text", "text": "Where was this taken?"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,iVBORw0KGgo="}}
+			]},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\": \"Paris\"}"}},
+				{"id": "call_2", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\": \"Lyon\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "15 degrees"},
+			{"role": "tool", "tool_call_id": "call_2", "content": "18 degrees"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}}}]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, llm.Request{
+		Schema: llm.RequestSchemaOpenAI,
+		Model:  "gpt-4o",
+		Prompt: llm.Prompt{
+			System: llm.TextContent("Be brief."),
+			Messages: []llm.Message{
+				{Role: llm.MessageRoleUser, Content: llm.Content{
+					{Type: llm.ContentBlockText, Text: "Where was this taken?"},
+					{Type: llm.ContentBlockImage, Source: &llm.ContentSource{Type: llm.SourceBase64, MediaType: "image/png", Data: "iVBORw0KGgo="}},
+				}},
+				{Role: llm.MessageRoleAssistant, Content: llm.Content{
+					{Type: llm.ContentBlockToolUse, ID: "call_1", Name: "get_weather", Input: json.RawMessage(`{"city": "Paris"}`)},
+					{Type: llm.ContentBlockToolUse, ID: "call_2", Name: "get_weather", Input: json.RawMessage(`{"city": "Lyon"}`)},
+				}},
+				// Results of parallel tool calls are a single message
+				{Role: llm.MessageRoleUser, Content: llm.Content{
+					{Type: llm.ContentBlockToolResult, ToolUseID: "call_1", Content: llm.TextContent("15 degrees")},
+					{Type: llm.ContentBlockToolResult, ToolUseID: "call_2", Content: llm.TextContent("18 degrees")},
+				}},
+			},
+			Tools: []llm.Tool{{Name: "get_weather", InputSchema: json.RawMessage(`{"type": "object"}`)}},
+		},
+		MaxTokens: 512,
+	}, req)
+}
+
+func TestParseRequest_Error(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]string{
+		"not json":    `messages`,
+		"no messages": `{"model": "claude-sonnet-4-20250514", "max_tokens": 1024}`,
+		"bad content": `{"model": "gpt-4o", "messages": [{"role": "user", "content": 42}]}`,
+	}
+
+	for name, body := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := llm.ParseRequest([]byte(body))
+			require.ErrorContains(t, err, "ParseRequest failed")
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package llm
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	// Tokenizer counts the tokens of a prompt offline, without calling a provider.
+	Tokenizer interface {
+		CountTokens(prompt Prompt) int
+	}
+
+	// EstimateTokenizer approximates the token count of BPE tokenizers. Text is split into words, number groups and
+	// punctuation, and every piece is counted as its length over CharsPerToken tokens, rounded, but at least one.
+	//
+	// Images are estimated from their dimensions, PDFs from their number of pages, and tools from their definition.
+	// Estimates are rough. Prefer a provider's tokenizer whenever one can be reached.
+	EstimateTokenizer struct {
+		// CharsPerToken is the average number of characters per token of a word. Defaults to 4.
+		CharsPerToken float64
+		// MessageOverhead is the number of tokens a chat template adds to each message. Defaults to 4.
+		MessageOverhead int
+		// ToolsOverhead is the number of tokens a provider adds to the system prompt when tools are defined.
+		ToolsOverhead int
+		// ImageTokens estimates the tokens of an image from its dimensions. Defaults to Claude's image tokens.
+		ImageTokens func(width int, height int) int
+	}
+)
+
+const (
+	defaultCharsPerToken   = 4
+	defaultMessageOverhead = 4
+	// replyPrimingTokens are the tokens a chat template adds to prime the assistant's reply.
+	replyPrimingTokens = 3
+
+	// anthropicToolsOverhead is the size of the system prompt Claude models add when tools are defined.
+	anthropicToolsOverhead = 346
+)
+
+// pretokenizer splits text the way BPE tokenizers do before merging, with leading whitespace attached to each piece.
+var pretokenizer = regexp.MustCompile(`\s*[\p{L}\p{M}]+|\s*\p{N}{1,3}|\s*[^\s\p{L}\p{M}\p{N}]+|\s+`)
+
+var _ Tokenizer = EstimateTokenizer{}
+
+// CountTokens estimates the number of input tokens of prompt.
+func (e EstimateTokenizer) CountTokens(prompt Prompt) int {
+	overhead := e.MessageOverhead
+	if overhead <= 0 {
+		overhead = defaultMessageOverhead
+	}
+
+	tokens := replyPrimingTokens
+	if len(prompt.System) > 0 {
+		tokens += overhead + e.countContent(prompt.System)
+	}
+
+	for _, message := range prompt.Messages {
+		tokens += overhead + e.countContent(message.Content)
+	}
+
+	if len(prompt.Tools) > 0 {
+		tokens += e.ToolsOverhead
+	}
+	for _, tool := range prompt.Tools {
+		tokens += overhead + e.countText(tool.Name) + e.countText(tool.Description) + e.countText(string(tool.InputSchema))
+	}
+
+	return tokens
+}
+
+func (e EstimateTokenizer) countContent(content Content) int {
+	var tokens int
+	for _, block := range content {
+		switch block.Type {
+		case ContentBlockText:
+			tokens += e.countText(block.Text)
+		case ContentBlockImage:
+			tokens += e.countImage(block.Source)
+		case ContentBlockDocument:
+			tokens += e.countText(block.Title) + e.countDocument(block.Source)
+		case ContentBlockToolUse:
+			tokens += e.countText(block.Name) + e.countText(string(block.Input))
+		case ContentBlockToolResult:
+			tokens += e.countContent(block.Content)
+		}
+	}
+
+	return tokens
+}
+
+func (e EstimateTokenizer) countImage(source *ContentSource) int {
+	width, height, ok := imageDimensions(source)
+	if !ok {
+		return defaultImageTokens
+	}
+
+	if e.ImageTokens != nil {
+		return e.ImageTokens(width, height)
+	}
+
+	return anthropicImageTokens(width, height)
+}
+
+func (e EstimateTokenizer) countDocument(source *ContentSource) int {
+	if source != nil && source.Type == SourceText {
+		return e.countText(source.Data)
+	}
+
+	if pages, ok := pdfPages(source); ok {
+		return pages * pdfPageTokens
+	}
+
+	return pdfPageTokens
+}
+
+func (e EstimateTokenizer) countText(text string) int {
+	charsPerToken := e.CharsPerToken
+	if charsPerToken <= 0 {
+		charsPerToken = defaultCharsPerToken
+	}
+
+	var tokens int
+	for _, piece := range pretokenizer.FindAllString(text, -1) {
+		trimmed := strings.TrimLeft(piece, " ")
+		if trimmed == "" {
+			tokens++
+			continue
+		}
+
+		tokens += int(math.Max(1, math.Round(float64(utf8.RuneCountInString(trimmed))/charsPerToken)))
+	}
+
+	return tokens
+}
+
+// offlineTokenizer returns the offline tokenizer of a model id, which is the default one when the model is unknown.
+func offlineTokenizer(model string) EstimateTokenizer {
+	parsed, _ := ParseModel(model)
+	if tokenizer, ok := TokenizerFor(parsed).(EstimateTokenizer); ok {
+		return tokenizer
+	}
+
+	return EstimateTokenizer{}
+}
+
+// TokenizerFor returns the offline tokenizer that best approximates the tokenizer of model.
+func TokenizerFor(model Model) Tokenizer {
+	switch model.Provider {
+	case ModelProviderAnthropic:
+		return EstimateTokenizer{ToolsOverhead: anthropicToolsOverhead, ImageTokens: anthropicImageTokens}
+	case ModelProviderOpenAI:
+		// OpenAI chat formats add 3 tokens to each message
+		return EstimateTokenizer{MessageOverhead: 3, ImageTokens: openAIImageTokens}
+	case ModelProviderGoogle:
+		return EstimateTokenizer{ImageTokens: geminiImageTokens}
+	default:
+		return EstimateTokenizer{}
+	}
+}
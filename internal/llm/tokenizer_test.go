@@ -0,0 +1,111 @@
+package llm_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"scrollwork/internal/llm"
+)
+
+func pngSource(t *testing.T, width int, height int) *llm.ContentSource {
+	t.Helper()
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))))
+
+	return &llm.ContentSource{Type: llm.SourceBase64, MediaType: "image/png", Data: base64.StdEncoding.EncodeToString(buf.Bytes())}
+}
+
+func TestTokenizerFor_Images(t *testing.T) {
+	t.Parallel()
+
+	prompt := func(source *llm.ContentSource) llm.Prompt {
+		return llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.Content{{Type: llm.ContentBlockImage, Source: source}}}}}
+	}
+	square := pngSource(t, 1000, 1000)
+
+	tc := map[string]struct {
+		model    string
+		source   *llm.ContentSource
+		expected int
+	}{
+		// A token per 750 pixels
+		"claude":  {model: "claude-sonnet-4-20250514", source: square, expected: 1334},
+		"bedrock": {model: "anthropic.claude-sonnet-4-20250514-v1:0", source: square, expected: 1334},
+		// Downsized to 768x768, or four 512 pixel tiles
+		"gpt-4o": {model: "gpt-4o", source: square, expected: 85 + 4*170},
+		// Four 768 pixel tiles
+		"gemini": {model: "gemini-2.5-pro", source: square, expected: 4 * 258},
+		// Downsized to fit 1568 pixels and 1.15 megapixels
+		"large claude": {model: "claude-sonnet-4-20250514", source: pngSource(t, 4000, 3000), expected: 1534},
+		"url source":   {model: "claude-sonnet-4-20250514", source: &llm.ContentSource{Type: llm.SourceURL, URL: "https://example.com/cat.png"}, expected: 1_600},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			model, err := llm.ParseModel(td.model)
+			require.NoError(t, err)
+
+			tokenizer := llm.TokenizerFor(model)
+			empty := tokenizer.CountTokens(llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser}}})
+			require.Equal(t, td.expected, tokenizer.CountTokens(prompt(td.source))-empty)
+		})
+	}
+}
+
+func TestEstimateTokenizer_Documents(t *testing.T) {
+	t.Parallel()
+
+	pdf := []byte("%PDF-1.4\n1 0 obj << /Type /Pages /Kids [2 0 R 3 0 R] /Count 2 >> endobj\n2 0 obj << /Type /Page >> endobj\n3 0 obj << /Type/Page >> endobj\n%%EOF")
+
+	tc := map[string]struct {
+		source   *llm.ContentSource
+		expected int
+	}{
+		"pdf":  {source: &llm.ContentSource{Type: llm.SourceBase64, MediaType: "application/pdf", Data: base64.StdEncoding.EncodeToString(pdf)}, expected: 2 * 3_000},
+		"text": {source: &llm.ContentSource{Type: llm.SourceText, MediaType: "text/plain", Data: "Hello, world"}, expected: 3},
+		"url":  {source: &llm.ContentSource{Type: llm.SourceURL, URL: "https://example.com/report.pdf"}, expected: 3_000},
+	}
+
+	tokenizer := llm.EstimateTokenizer{}
+	empty := tokenizer.CountTokens(llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser}}})
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			prompt := llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.Content{{Type: llm.ContentBlockDocument, Source: td.source}}}}}
+			require.Equal(t, td.expected, tokenizer.CountTokens(prompt)-empty)
+		})
+	}
+}
+
+func TestEstimateTokenizer_Tools(t *testing.T) {
+	t.Parallel()
+
+	model, err := llm.ParseModel("claude-sonnet-4-20250514")
+	require.NoError(t, err)
+	tokenizer := llm.TokenizerFor(model)
+
+	messages := []llm.Message{
+		{Role: llm.MessageRoleUser, Content: llm.TextContent("What is the weather in Paris?")},
+		{Role: llm.MessageRoleAssistant, Content: llm.Content{{Type: llm.ContentBlockToolUse, ID: "toolu_01", Name: "get_weather", Input: json.RawMessage(`{"city": "Paris"}`)}}},
+		{Role: llm.MessageRoleUser, Content: llm.Content{{Type: llm.ContentBlockToolResult, ToolUseID: "toolu_01", Content: llm.TextContent("15 degrees")}}},
+	}
+	tools := []llm.Tool{{Name: "get_weather", Description: "Returns the weather in a city.", InputSchema: json.RawMessage(`{"type": "object", "properties": {"city": {"type": "string"}}}`)}}
+
+	withoutTools := tokenizer.CountTokens(llm.Prompt{Messages: messages})
+	withTools := tokenizer.CountTokens(llm.Prompt{System: llm.TextContent("Be brief."), Messages: messages, Tools: tools})
+
+	// Defining tools adds Claude's tool use system prompt on top of the definitions
+	require.Greater(t, withTools-withoutTools, 346)
+	require.Greater(t, withoutTools, llm.EstimateTokenizer{}.CountTokens(llm.Prompt{Messages: messages[:1]}))
+}
@@ -0,0 +1,249 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	VertexConfig struct {
+		// Credentials is the JSON key file of the service account models are invoked with.
+		Credentials []byte
+
+		// ProjectID is the Google Cloud project models are invoked in. Defaults to the project of the service account.
+		ProjectID string
+		// Region is the region models are invoked in, such as us-east5 or global. Defaults to global.
+		Region string
+
+		// APIEndpoint is the Vertex AI API. Defaults to https://<region>-aiplatform.googleapis.com, or
+		// https://aiplatform.googleapis.com in the global region.
+		APIEndpoint string
+		// TokenEndpoint is where JWT assertions are exchanged for access tokens. Defaults to the token_uri of the
+		// service account.
+		TokenEndpoint string
+		// MonitoringEndpoint is the Cloud Monitoring API usage metrics are read from.
+		// Defaults to https://monitoring.googleapis.com.
+		MonitoringEndpoint string
+
+		HTTPClient *http.Client
+	}
+
+	// VertexClient is the Provider of Anthropic models served by Google Cloud Vertex AI.
+	//
+	// Vertex AI has no usage report. Usage is read from the token count metrics Vertex AI publishes to Cloud
+	// Monitoring for every publisher model, and attributed to configured models by their model id and version.
+	VertexClient struct {
+		config VertexConfig
+		tokens *oauthTokenSource
+	}
+
+	timeSeriesResponse struct {
+		TimeSeries []struct {
+			Metric struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metric"`
+			Resource struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"resource"`
+			Points []struct {
+				Value struct {
+					Int64Value string `json:"int64Value"`
+				} `json:"value"`
+			} `json:"points"`
+		} `json:"timeSeries"`
+		NextPageToken string `json:"nextPageToken"`
+	}
+)
+
+const (
+	vertexGlobalRegion = "global"
+
+	vertexTokenCountMetric = "aiplatform.googleapis.com/publisher/online_serving/token_count"
+
+	// vertexUsageAlignment is the period Cloud Monitoring sums token counts over.
+	vertexUsageAlignment = time.Hour
+)
+
+var _ Provider = (*VertexClient)(nil)
+
+// IsVertexModel reports whether model is a Vertex AI model id, such as claude-sonnet-4@20250514.
+func IsVertexModel(model string) bool {
+	m, err := ParseModel(model)
+	return err == nil && m.Platform == PlatformVertex
+}
+
+// NewVertexClient returns a VertexClient.
+func NewVertexClient(config VertexConfig) (*VertexClient, error) {
+	account, key, err := parseServiceAccount(config.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("NewVertexClient failed: %w", err)
+	}
+
+	if config.ProjectID == "" {
+		config.ProjectID = account.ProjectID
+	}
+	if config.ProjectID == "" {
+		return nil, fmt.Errorf("NewVertexClient failed: missing project id")
+	}
+
+	if config.Region == "" {
+		config.Region = vertexGlobalRegion
+	}
+
+	if config.APIEndpoint == "" {
+		config.APIEndpoint = "https://" + config.Region + "-aiplatform.googleapis.com"
+		if config.Region == vertexGlobalRegion {
+			config.APIEndpoint = "https://aiplatform.googleapis.com"
+		}
+	}
+
+	if config.TokenEndpoint == "" {
+		config.TokenEndpoint = account.TokenURI
+	}
+	if config.TokenEndpoint == "" {
+		config.TokenEndpoint = googleTokenURL
+	}
+
+	if config.MonitoringEndpoint == "" {
+		config.MonitoringEndpoint = "https://monitoring.googleapis.com"
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return &VertexClient{
+		config: config,
+		tokens: newServiceAccountTokenSource(account, key, config.TokenEndpoint, cloudPlatformScope, config.HTTPClient),
+	}, nil
+}
+
+func (v *VertexClient) Name() string {
+	return "vertex"
+}
+
+// HealthCheck exchanges the service account credentials for an access token.
+func (v *VertexClient) HealthCheck(ctx context.Context) error {
+	if _, err := v.tokens.Token(ctx); err != nil {
+		return fmt.Errorf("HealthCheck failed: %w", err)
+	}
+
+	return nil
+}
+
+// CountTokens counts the input tokens of prompt with the Vertex AI count-tokens endpoint of Anthropic models, which
+// takes the same requests as the Anthropic API.
+func (v *VertexClient) CountTokens(ctx context.Context, model string, prompt Prompt) (int, error) {
+	payload, err := json.Marshal(newAnthropicCountTokensRequest(model, prompt))
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	path := fmt.Sprintf("/v1/projects/%s/locations/%s/publishers/anthropic/models/count-tokens:rawPredict", url.PathEscape(v.config.ProjectID), url.PathEscape(v.config.Region))
+	body, err := v.do(ctx, http.MethodPost, strings.TrimSuffix(v.config.APIEndpoint, "/")+path, payload)
+	if err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	var resp struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return 0, fmt.Errorf("CountTokens failed: %w", err)
+	}
+
+	return resp.InputTokens, nil
+}
+
+// Usage sums the input and output token counts of each model between startingAt and endingAt.
+func (v *VertexClient) Usage(ctx context.Context, models []string, startingAt time.Time, endingAt time.Time) (map[string]TokenUsage, error) {
+	usage := make(map[string]TokenUsage, len(models))
+
+	q := url.Values{}
+	q.Set("filter", fmt.Sprintf("metric.type = %q AND resource.labels.publisher = \"anthropic\"", vertexTokenCountMetric))
+	q.Set("interval.startTime", startingAt.UTC().Format(time.RFC3339))
+	q.Set("interval.endTime", endingAt.UTC().Format(time.RFC3339))
+	q.Set("aggregation.alignmentPeriod", strconv.Itoa(int(vertexUsageAlignment.Seconds()))+"s")
+	q.Set("aggregation.perSeriesAligner", "ALIGN_SUM")
+
+	for {
+		endpoint := fmt.Sprintf("%s/v3/projects/%s/timeSeries?%s", strings.TrimSuffix(v.config.MonitoringEndpoint, "/"), url.PathEscape(v.config.ProjectID), q.Encode())
+		body, err := v.do(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return usage, fmt.Errorf("Usage failed: %w", err)
+		}
+
+		var resp timeSeriesResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return usage, fmt.Errorf("Usage failed: %w", err)
+		}
+
+		for _, series := range resp.TimeSeries {
+			model, ok := attributeVertexModel(models, series.Resource.Labels["model_user_id"], series.Resource.Labels["model_version_id"])
+			if !ok {
+				continue
+			}
+
+			var tokens int
+			for _, point := range series.Points {
+				n, err := strconv.Atoi(point.Value.Int64Value)
+				if err != nil {
+					return usage, fmt.Errorf("Usage failed: invalid token count %q", point.Value.Int64Value)
+				}
+				tokens += n
+			}
+
+			u := usage[model]
+			switch series.Metric.Labels["type"] {
+			case "input":
+				u.UncachedInput += tokens
+			case "output":
+				u.Output += tokens
+			}
+			usage[model] = u
+		}
+
+		if resp.NextPageToken == "" {
+			return usage, nil
+		}
+		q.Set("pageToken", resp.NextPageToken)
+	}
+}
+
+// attributeVertexModel returns the configured model a time series of modelID and version belongs to.
+func attributeVertexModel(models []string, modelID string, version string) (string, bool) {
+	for _, model := range models {
+		name, modelVersion, _ := strings.Cut(model, "@")
+		if name == modelID && (version == "" || modelVersion == version) {
+			return model, true
+		}
+	}
+
+	return "", false
+}
+
+// do sends an authorized request to a Google Cloud API.
+func (v *VertexClient) do(ctx context.Context, method string, endpoint string, payload []byte) ([]byte, error) {
+	token, err := v.tokens.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return doRequest(v.config.HTTPClient, req)
+}
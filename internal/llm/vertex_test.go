@@ -0,0 +1,176 @@
+package llm_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"scrollwork/internal/llm"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const vertexModel = "claude-sonnet-4@20250514"
+
+// newVertexFakes returns a client talking to a local fake of the Google OAuth token, Vertex AI and Cloud Monitoring
+// APIs, along with the number of tokens exchanged.
+func newVertexFakes(t *testing.T) (*llm.VertexClient, *fakeAPI, *atomic.Int32) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	var exchanges atomic.Int32
+	var tokenURL string
+	checkAuthorized := func(r *http.Request, check *assert.Assertions) {
+		check.Equal("Bearer ya29.test", r.Header.Get("Authorization"))
+	}
+
+	api := newFakeAPI(t, map[string]fakeRoute{
+		"/token": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			check.NoError(r.ParseForm())
+			check.Equal("urn:ietf:params:oauth:grant-type:jwt-bearer", r.PostForm.Get("grant_type"))
+
+			parts := strings.Split(r.PostForm.Get("assertion"), ".")
+			if !check.Len(parts, 3) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+			check.NoError(err)
+			digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			check.NoError(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+			b, err := base64.RawURLEncoding.DecodeString(parts[1])
+			check.NoError(err)
+			var claims map[string]any
+			check.NoError(json.Unmarshal(b, &claims))
+			check.Equal("scrollwork@test-project.iam.gserviceaccount.com", claims["iss"])
+			check.Equal(tokenURL, claims["aud"])
+			check.Equal("https://www.googleapis.com/auth/cloud-platform", claims["scope"])
+
+			exchanges.Add(1)
+			fmt.Fprint(w, `{"access_token": "ya29.test", "expires_in": 3600, "token_type": "Bearer"}`)
+		},
+		"/v1/projects/test-project/locations/us-east5/publishers/anthropic/models/count-tokens:rawPredict": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			checkAuthorized(r, check)
+
+			var body struct {
+				Model    string        `json:"model"`
+				Messages []llm.Message `json:"messages"`
+			}
+			check.NoError(json.NewDecoder(r.Body).Decode(&body))
+			check.Equal(vertexModel, body.Model)
+			check.Len(body.Messages, 1)
+
+			fmt.Fprint(w, `{"input_tokens": 14}`)
+		},
+		"/v3/projects/test-project/timeSeries": func(w http.ResponseWriter, r *http.Request, check *assert.Assertions) {
+			checkAuthorized(r, check)
+			check.Contains(r.URL.Query().Get("filter"), "aiplatform.googleapis.com/publisher/online_serving/token_count")
+
+			if r.URL.Query().Get("pageToken") == "" {
+				fmt.Fprint(w, `{
+  "timeSeries": [
+    {"metric": {"labels": {"type": "input"}}, "resource": {"labels": {"model_user_id": "claude-sonnet-4", "model_version_id": "20250514"}}, "points": [{"value": {"int64Value": "100"}}, {"value": {"int64Value": "50"}}]},
+    {"metric": {"labels": {"type": "input"}}, "resource": {"labels": {"model_user_id": "claude-opus-4", "model_version_id": "20250514"}}, "points": [{"value": {"int64Value": "999"}}]}
+  ],
+  "nextPageToken": "page-2"
+}`)
+				return
+			}
+
+			fmt.Fprint(w, `{
+  "timeSeries": [
+    {"metric": {"labels": {"type": "output"}}, "resource": {"labels": {"model_user_id": "claude-sonnet-4", "model_version_id": "20250514"}}, "points": [{"value": {"int64Value": "30"}}]}
+  ]
+}`)
+		},
+	})
+	tokenURL = api.URL + "/token"
+
+	credentials, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "test-project",
+		"private_key_id": "key-1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "scrollwork@test-project.iam.gserviceaccount.com",
+		"token_uri":      tokenURL,
+	})
+	require.NoError(t, err)
+
+	client, err := llm.NewVertexClient(llm.VertexConfig{
+		Credentials:        credentials,
+		Region:             "us-east5",
+		APIEndpoint:        api.URL,
+		MonitoringEndpoint: api.URL,
+	})
+	require.NoError(t, err)
+
+	return client, api, &exchanges
+}
+
+func TestVertexClient(t *testing.T) {
+	t.Parallel()
+
+	client, api, exchanges := newVertexFakes(t)
+	ctx := context.Background()
+
+	require.NoError(t, client.HealthCheck(ctx))
+
+	tokens, err := client.CountTokens(ctx, vertexModel, llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("Hello, Claude")}}})
+	api.requireMatched(t)
+	require.NoError(t, err)
+	require.Equal(t, 14, tokens)
+
+	now := time.Now()
+	usage, err := client.Usage(ctx, []string{vertexModel}, now.Add(-time.Hour), now)
+	api.requireMatched(t)
+	require.NoError(t, err)
+	require.Equal(t, map[string]llm.TokenUsage{vertexModel: {UncachedInput: 150, Output: 30}}, usage)
+
+	// The access token is reused until it expires
+	require.Equal(t, int32(1), exchanges.Load())
+}
+
+func TestNewVertexClient_Error(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]string{
+		"invalid json":        `{`,
+		"not service account": `{"type": "authorized_user"}`,
+		"missing private key": `{"type": "service_account", "client_email": "a@b.c", "project_id": "p"}`,
+	}
+
+	for name, credentials := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := llm.NewVertexClient(llm.VertexConfig{Credentials: []byte(credentials)})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestIsVertexModel(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, llm.IsVertexModel(vertexModel))
+	require.False(t, llm.IsVertexModel("claude-sonnet-4-20250514"))
+	require.False(t, llm.IsVertexModel("anthropic.claude-sonnet-4-20250514-v1:0"))
+}
@@ -0,0 +1,84 @@
+// Package logging configures structured logging for the Scrollwork Agent.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type (
+	Config struct {
+		// Format is either FormatText or FormatJSON. Defaults to FormatText.
+		Format string
+		// Level is the minimum level logged: debug, info, warn or error. Defaults to info.
+		Level string
+	}
+
+	contextKey struct{}
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+
+	// Consistent attribute keys used across the agent.
+	KeyComponent = "component"
+	KeyModel     = "model"
+	KeyRequestID = "request_id"
+	KeyCaller    = "caller"
+	KeyDuration  = "duration"
+	KeyError     = "error"
+)
+
+// New returns a logger writing to w as configured.
+func New(w io.Writer, config Config) (*slog.Logger, error) {
+	var level slog.Level
+	if config.Level != "" {
+		if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+			return nil, fmt.Errorf("New failed: unknown log level %q", config.Level)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(config.Format) {
+	case "", FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("New failed: log format must be %s or %s, got %q", FormatText, FormatJSON, config.Format)
+	}
+}
+
+// Err returns the attribute used to log err.
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+// WithLogger returns ctx carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
+
+// NewRequestID returns a random id for a request that was not given one by its client.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,50 @@
+package logging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"scrollwork/internal/logging"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.Config{Format: logging.FormatJSON, Level: "warn"})
+	require.NoError(t, err)
+
+	logger.Info("ignored")
+	logger.Warn("usage fetch failed", logging.KeyComponent, "usage_worker", logging.Err(errors.New("unavailable")))
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "WARN", record["level"])
+	require.Equal(t, "usage fetch failed", record["msg"])
+	require.Equal(t, "usage_worker", record[logging.KeyComponent])
+	require.Equal(t, "unavailable", record[logging.KeyError])
+}
+
+func TestNew_Error(t *testing.T) {
+	t.Parallel()
+
+	_, err := logging.New(&bytes.Buffer{}, logging.Config{Format: "xml"})
+	require.Error(t, err)
+
+	_, err = logging.New(&bytes.Buffer{}, logging.Config{Level: "loud"})
+	require.Error(t, err)
+}
+
+func TestFromContext(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, slog.Default(), logging.FromContext(context.Background()))
+
+	logger := slog.New(slog.DiscardHandler)
+	require.Equal(t, logger, logging.FromContext(logging.WithLogger(context.Background(), logger)))
+}
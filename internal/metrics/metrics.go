@@ -0,0 +1,132 @@
+// Package metrics exports Scrollwork Agent metrics to Prometheus.
+package metrics
+
+import (
+	"net/http"
+	"scrollwork/internal/llm"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type (
+	// Metrics holds the collectors of a Scrollwork Agent. A nil *Metrics discards every observation.
+	Metrics struct {
+		registry *prometheus.Registry
+
+		usageTokens         *prometheus.GaugeVec
+		quotaUtilization    *prometheus.GaugeVec
+		assessments         *prometheus.CounterVec
+		countTokensDuration *prometheus.HistogramVec
+		usageFetchDuration  *prometheus.HistogramVec
+		usageFetchErrors    *prometheus.CounterVec
+	}
+)
+
+const namespace = "scrollwork"
+
+// New returns Metrics registered on a new registry along with the Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		usageTokens: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "usage_tokens",
+			Help:      "Tokens used by the organization within the quota window.",
+		}, []string{"model", "category"}),
+		quotaUtilization: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "quota_utilization_ratio",
+			Help:      "Fraction of the quota used within the quota window.",
+		}, []string{"model"}),
+		assessments: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "assessments_total",
+			Help:      "Prompt assessments by model and resulting risk level.",
+		}, []string{"model", "risk_level"}),
+		countTokensDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "count_tokens_duration_seconds",
+			Help:      "Latency of counting the tokens of a prompt.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"model"}),
+		usageFetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "usage_fetch_duration_seconds",
+			Help:      "Latency of fetching organization usage from a provider.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider"}),
+		usageFetchErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "usage_fetch_errors_total",
+			Help:      "Failed organization usage fetches by provider.",
+		}, []string{"provider"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.usageTokens,
+		m.quotaUtilization,
+		m.assessments,
+		m.countTokensDuration,
+		m.usageFetchDuration,
+		m.usageFetchErrors,
+	)
+
+	return m
+}
+
+// Handler returns the handler serving the /metrics endpoint.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// ObserveUsage records the tokens used by model within the quota window. Quota utilization is only recorded when
+// quotaTokens is positive.
+func (m *Metrics) ObserveUsage(model string, usage llm.TokenUsage, quotaTokens int) {
+	if m == nil {
+		return
+	}
+
+	for category, tokens := range usage.ByCategory() {
+		m.usageTokens.WithLabelValues(model, string(category)).Set(float64(tokens))
+	}
+
+	if quotaTokens > 0 {
+		m.quotaUtilization.WithLabelValues(model).Set(float64(usage.UncachedInput) / float64(quotaTokens))
+	}
+}
+
+// ObserveAssessment counts an assessment of a prompt for model.
+func (m *Metrics) ObserveAssessment(model string, riskLevel string) {
+	if m == nil {
+		return
+	}
+
+	m.assessments.WithLabelValues(model, riskLevel).Inc()
+}
+
+// ObserveCountTokens records how long counting the tokens of a prompt for model took.
+func (m *Metrics) ObserveCountTokens(model string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.countTokensDuration.WithLabelValues(model).Observe(duration.Seconds())
+}
+
+// ObserveUsageFetch records how long fetching usage from provider took and whether it failed.
+func (m *Metrics) ObserveUsageFetch(provider string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	m.usageFetchDuration.WithLabelValues(provider).Observe(duration.Seconds())
+	if err != nil {
+		m.usageFetchErrors.WithLabelValues(provider).Inc()
+	}
+}
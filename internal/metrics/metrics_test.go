@@ -0,0 +1,58 @@
+package metrics_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"scrollwork/internal/llm"
+	"scrollwork/internal/metrics"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := metrics.New()
+	m.ObserveUsage("claude-sonnet-4-20250514", llm.TokenUsage{UncachedInput: 250, Output: 100}, 1000)
+	m.ObserveAssessment("claude-sonnet-4-20250514", "low")
+	m.ObserveAssessment("claude-sonnet-4-20250514", "low")
+	m.ObserveCountTokens("claude-sonnet-4-20250514", 20*time.Millisecond)
+	m.ObserveUsageFetch("anthropic", time.Second, nil)
+	m.ObserveUsageFetch("anthropic", time.Second, errors.New("unavailable"))
+
+	body := scrape(t, m)
+	require.Contains(t, body, `scrollwork_usage_tokens{category="uncached_input",model="claude-sonnet-4-20250514"} 250`)
+	require.Contains(t, body, `scrollwork_usage_tokens{category="output",model="claude-sonnet-4-20250514"} 100`)
+	require.Contains(t, body, `scrollwork_quota_utilization_ratio{model="claude-sonnet-4-20250514"} 0.25`)
+	require.Contains(t, body, `scrollwork_assessments_total{model="claude-sonnet-4-20250514",risk_level="low"} 2`)
+	require.Contains(t, body, `scrollwork_count_tokens_duration_seconds_count{model="claude-sonnet-4-20250514"} 1`)
+	require.Contains(t, body, `scrollwork_usage_fetch_duration_seconds_count{provider="anthropic"} 2`)
+	require.Contains(t, body, `scrollwork_usage_fetch_errors_total{provider="anthropic"} 1`)
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	t.Parallel()
+
+	var m *metrics.Metrics
+	m.ObserveUsage("claude-sonnet-4-20250514", llm.TokenUsage{}, 0)
+	m.ObserveAssessment("claude-sonnet-4-20250514", "low")
+	m.ObserveCountTokens("claude-sonnet-4-20250514", time.Second)
+	m.ObserveUsageFetch("anthropic", time.Second, nil)
+}
@@ -0,0 +1,53 @@
+package retry
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+type (
+	// Backoff computes exponentially increasing delays between retry attempts.
+	Backoff struct {
+		// Initial is the delay before the first retry.
+		Initial time.Duration
+		// Max is the largest delay between attempts.
+		Max time.Duration
+		// Multiplier is the factor the delay grows by after every attempt.
+		Multiplier float64
+		// Jitter is the fraction of each delay that is randomized, between 0 and 1.
+		Jitter float64
+	}
+)
+
+// DefaultBackoff is used when a Backoff is not configured.
+var DefaultBackoff = Backoff{
+	Initial:    time.Second,
+	Max:        30 * time.Second,
+	Multiplier: 2,
+	Jitter:     0.5,
+}
+
+// Delay returns how long to wait before retrying after the given number of failed attempts, starting at 0.
+//
+// The delay is reduced by a random amount of up to Jitter of its value so that retries from many agents spread out.
+func (b Backoff) Delay(attempt int) time.Duration {
+	if attempt < 0 {
+		attempt = 0
+	}
+
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(attempt))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+
+	jitter := min(max(b.Jitter, 0), 1)
+	delay -= delay * jitter * rand.Float64()
+
+	return time.Duration(delay)
+}
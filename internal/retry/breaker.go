@@ -0,0 +1,100 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	CircuitBreakerConfig struct {
+		// FailureThreshold is the number of consecutive failures that trip the breaker.
+		FailureThreshold int
+		// Cooldown is how long the breaker stays open before a trial attempt is allowed.
+		Cooldown time.Duration
+	}
+
+	// CircuitBreaker stops calls to a failing dependency after repeated failures.
+	//
+	// Once tripped, the breaker is open until the Cooldown elapses. It then allows trial attempts while half open and
+	// closes again on the first success.
+	CircuitBreaker struct {
+		config CircuitBreakerConfig
+
+		state    CircuitState
+		failures int
+		openedAt time.Time
+		mu       sync.Mutex
+	}
+
+	CircuitState string
+)
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// DefaultCircuitBreakerConfig is used when a CircuitBreakerConfig is not configured.
+var DefaultCircuitBreakerConfig = CircuitBreakerConfig{
+	FailureThreshold: 3,
+	Cooldown:         5 * time.Minute,
+}
+
+// NewCircuitBreaker returns a closed CircuitBreaker.
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = DefaultCircuitBreakerConfig.FailureThreshold
+	}
+
+	if config.Cooldown <= 0 {
+		config.Cooldown = DefaultCircuitBreakerConfig.Cooldown
+	}
+
+	return &CircuitBreaker{
+		config: config,
+		state:  CircuitClosed,
+	}
+}
+
+// Allow reports whether a call may be attempted at now.
+func (c *CircuitBreaker) Allow(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == CircuitOpen && now.Sub(c.openedAt) >= c.config.Cooldown {
+		c.state = CircuitHalfOpen
+	}
+
+	return c.state != CircuitOpen
+}
+
+// RecordSuccess closes the breaker.
+func (c *CircuitBreaker) RecordSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.state = CircuitClosed
+	c.failures = 0
+}
+
+// RecordFailure records a failed call at now, tripping the breaker once the FailureThreshold is reached or a trial
+// attempt fails.
+func (c *CircuitBreaker) RecordFailure(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= c.config.FailureThreshold {
+		c.state = CircuitOpen
+		c.openedAt = now
+	}
+}
+
+// State returns the current state of the breaker.
+func (c *CircuitBreaker) State() CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.state
+}
@@ -0,0 +1,62 @@
+package retry_test
+
+import (
+	"scrollwork/internal/retry"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	t.Parallel()
+
+	b := retry.Backoff{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2}
+
+	require.Equal(t, time.Second, b.Delay(0))
+	require.Equal(t, 2*time.Second, b.Delay(1))
+	require.Equal(t, 4*time.Second, b.Delay(2))
+	require.Equal(t, 8*time.Second, b.Delay(3))
+	require.Equal(t, 10*time.Second, b.Delay(4))
+	require.Equal(t, 10*time.Second, b.Delay(100))
+}
+
+func TestBackoffDelay_Jitter(t *testing.T) {
+	t.Parallel()
+
+	b := retry.Backoff{Initial: time.Second, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.5}
+
+	for range 100 {
+		delay := b.Delay(2)
+		require.GreaterOrEqual(t, delay, 2*time.Second)
+		require.LessOrEqual(t, delay, 4*time.Second)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	c := retry.NewCircuitBreaker(retry.CircuitBreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+
+	require.True(t, c.Allow(now))
+	c.RecordFailure(now)
+	require.Equal(t, retry.CircuitClosed, c.State())
+
+	c.RecordFailure(now)
+	require.Equal(t, retry.CircuitOpen, c.State())
+	require.False(t, c.Allow(now.Add(30*time.Second)))
+
+	// A failed trial attempt trips the breaker again
+	require.True(t, c.Allow(now.Add(time.Minute)))
+	require.Equal(t, retry.CircuitHalfOpen, c.State())
+	c.RecordFailure(now.Add(time.Minute))
+	require.Equal(t, retry.CircuitOpen, c.State())
+	require.False(t, c.Allow(now.Add(90*time.Second)))
+
+	// A successful trial attempt closes the breaker
+	require.True(t, c.Allow(now.Add(2*time.Minute)))
+	c.RecordSuccess()
+	require.Equal(t, retry.CircuitClosed, c.State())
+	require.True(t, c.Allow(now.Add(2*time.Minute)))
+}
@@ -0,0 +1,174 @@
+package scrollwork
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"scrollwork/internal/llm"
+	"scrollwork/internal/logging"
+	"scrollwork/internal/usage"
+	"sync"
+	"time"
+)
+
+type (
+	// Status is the JSON body served by the /status endpoint.
+	Status struct {
+		Models         []string                   `json:"models"`
+		LastSyncAt     *time.Time                 `json:"last_sync_at,omitempty"`
+		UsageStatus    UsageSnapshotStatus        `json:"usage_status"`
+		UsageError     string                     `json:"usage_error,omitempty"`
+		UsageTokens    map[string]int             `json:"usage_tokens"`
+		Forecasts      map[string]usage.Forecast  `json:"forecasts,omitempty"`
+		RiskThresholds usage.RiskThresholdsConfig `json:"risk_thresholds"`
+	}
+
+	// healthCache caches provider health checks so that frequent readiness probes do not hit provider APIs.
+	healthCache struct {
+		checkedAt time.Time
+		err       error
+		mu        sync.Mutex
+	}
+)
+
+const (
+	// healthCheckTTL is how long a provider health check result is reused by readiness probes.
+	healthCheckTTL = time.Minute
+
+	// healthCheckTimeout bounds provider health checks made by readiness probes.
+	healthCheckTimeout = 5 * time.Second
+)
+
+// serveAdmin starts the HTTP admin listener on addr.
+func (a *Agent) serveAdmin(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("serveAdmin failed: %w", err)
+	}
+
+	a.adminServer = &http.Server{
+		Handler:           a.adminHandler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	logger := a.logger.With(logging.KeyComponent, "admin")
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		if err := a.adminServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Scrollwork Agent admin listener failed", logging.Err(err))
+		}
+	}()
+
+	logger.Info("Scrollwork Agent admin listener is serving", "addr", listener.Addr().String())
+	return nil
+}
+
+func (a *Agent) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", a.handleHealthz)
+	mux.HandleFunc("GET /readyz", a.handleReadyz)
+	mux.HandleFunc("GET /status", a.handleStatus)
+	mux.Handle("GET /metrics", a.metrics.Handler())
+
+	return mux
+}
+
+// handleHealthz reports that the agent process is alive.
+func (a *Agent) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// handleReadyz reports whether the agent has fresh usage and its providers are healthy.
+func (a *Agent) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if err := a.ready(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Write([]byte("ok\n"))
+}
+
+// handleStatus serves the agent's Status as JSON.
+func (a *Agent) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.status())
+}
+
+// ready returns why the agent is not ready to assess prompts.
+//
+// Usage is fresh when the latest snapshot was fetched successfully within two refresh intervals.
+func (a *Agent) ready(ctx context.Context) error {
+	updatedAt, status := a.getUsageState()
+	if updatedAt.IsZero() {
+		return fmt.Errorf("usage has not been fetched")
+	}
+
+	if status != UsageSnapshotFresh {
+		return fmt.Errorf("latest usage fetch was %s", status)
+	}
+
+	maxAge := 2 * time.Duration(a.config.RefreshUsageIntervalMinutes) * time.Minute
+	if age := time.Since(updatedAt); maxAge > 0 && age > maxAge {
+		return fmt.Errorf("usage was last fetched %s ago", age.Round(time.Second))
+	}
+
+	return a.health.check(ctx, a.worker.healthCheck)
+}
+
+// check returns the cached result of healthCheck, running it again once the cached result has expired.
+func (c *healthCache) check(ctx context.Context, healthCheck func(context.Context) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checkedAt.IsZero() && time.Since(c.checkedAt) < healthCheckTTL {
+		return c.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	c.err = healthCheck(ctx)
+	c.checkedAt = time.Now()
+
+	return c.err
+}
+
+// status returns a snapshot of the agent's configuration and usage.
+func (a *Agent) status() Status {
+	a.usageMu.Lock()
+	status := Status{
+		Models:         a.config.Models,
+		UsageStatus:    a.usageStatus,
+		UsageTokens:    make(map[string]int, len(a.currentUsageTokens)),
+		Forecasts:      make(map[string]usage.Forecast),
+		RiskThresholds: a.riskThresholds.Config(),
+	}
+
+	if !a.usageUpdatedAt.IsZero() {
+		lastSyncAt := a.usageUpdatedAt
+		status.LastSyncAt = &lastSyncAt
+	}
+
+	if a.usageErr != nil {
+		status.UsageError = a.usageErr.Error()
+	}
+
+	for model, tokens := range a.currentUsageTokens {
+		status.UsageTokens[model] = tokens
+	}
+	a.usageMu.Unlock()
+
+	for _, model := range a.config.Models {
+		key := llm.ModelKey(model)
+		if f, ok := a.forecaster.Forecast(key); ok {
+			status.Forecasts[key] = f
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,105 @@
+package scrollwork
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"scrollwork/internal/llm"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHealthz(t *testing.T) {
+	t.Parallel()
+
+	agent := newTestAgent(t)
+
+	rec := httptest.NewRecorder()
+	agent.adminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestAdminReadyz(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]struct {
+		snapshots []UsageSnapshot
+		healthErr error
+		code      int
+		expected  string
+	}{
+		"ready": {
+			snapshots: []UsageSnapshot{{Status: UsageSnapshotFresh, Tokens: map[string]int{"claude-sonnet-4-20250514": 100}, FetchedAt: time.Now()}},
+			code:      http.StatusOK,
+			expected:  "ok",
+		},
+		"not fetched": {
+			code:     http.StatusServiceUnavailable,
+			expected: "usage has not been fetched",
+		},
+		"failed fetch": {
+			snapshots: []UsageSnapshot{
+				{Status: UsageSnapshotFresh, FetchedAt: time.Now()},
+				{Status: UsageSnapshotFailed, FetchedAt: time.Now()},
+			},
+			code:     http.StatusServiceUnavailable,
+			expected: "latest usage fetch was failed",
+		},
+		"outdated usage": {
+			snapshots: []UsageSnapshot{{Status: UsageSnapshotFresh, FetchedAt: time.Now().Add(-time.Hour)}},
+			code:      http.StatusServiceUnavailable,
+			expected:  "usage was last fetched 1h0m0s ago",
+		},
+		"unhealthy provider": {
+			snapshots: []UsageSnapshot{{Status: UsageSnapshotFresh, FetchedAt: time.Now()}},
+			healthErr: errors.New("unavailable"),
+			code:      http.StatusServiceUnavailable,
+			expected:  "healthCheck failed: fake: unavailable",
+		},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			agent := newTestAgent(t)
+			agent.worker.Providers = map[string]llm.Provider{"claude-sonnet-4-20250514": &fakeProvider{name: "fake", healthErr: td.healthErr}}
+			for _, snapshot := range td.snapshots {
+				agent.updateUsage(snapshot)
+			}
+
+			rec := httptest.NewRecorder()
+			agent.adminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			require.Equal(t, td.code, rec.Code)
+			require.Contains(t, rec.Body.String(), td.expected)
+		})
+	}
+}
+
+func TestAdminStatus(t *testing.T) {
+	t.Parallel()
+
+	agent := newTestAgent(t)
+	fetchedAt := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	agent.updateUsage(UsageSnapshot{
+		Status:    UsageSnapshotFresh,
+		Tokens:    map[string]int{"claude-sonnet-4-20250514": 100},
+		FetchedAt: fetchedAt,
+	})
+
+	rec := httptest.NewRecorder()
+	agent.adminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var status Status
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	require.Equal(t, []string{"claude-sonnet-4-20250514"}, status.Models)
+	require.Equal(t, fetchedAt, *status.LastSyncAt)
+	require.Equal(t, UsageSnapshotFresh, status.UsageStatus)
+	require.Equal(t, 100, status.UsageTokens["claude-sonnet-4-20250514"])
+	require.Equal(t, float32(100), status.RiskThresholds.High)
+	require.Equal(t, float32(1000), status.RiskThresholds.Ceiling)
+}
@@ -1,22 +1,42 @@
 package scrollwork
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
+	"net/http"
+	"os"
+	"scrollwork/internal/alert"
+	"scrollwork/internal/audit"
 	"scrollwork/internal/llm"
+	"scrollwork/internal/logging"
+	"scrollwork/internal/metrics"
+	"scrollwork/internal/retry"
+	"scrollwork/internal/systemd"
+	"scrollwork/internal/tracing"
 	"scrollwork/internal/usage"
 	"strings"
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
 	_ "embed"
 )
 
 //go:embed banner.txt
 var banner []byte
 
+var tracer = otel.Tracer("scrollwork/internal/scrollwork")
+
 type (
 	AgentConfig struct {
 		Models                      []string
@@ -24,32 +44,150 @@ type (
 		AdminKey                    string
 		RefreshUsageIntervalMinutes int
 
+		// UsageFetchAttempts is the number of times usage is fetched on every refresh before giving up. Defaults to 3.
+		UsageFetchAttempts int
+		// UsageFetchBackoff is the delay between usage fetch attempts. Defaults to retry.DefaultBackoff.
+		UsageFetchBackoff *retry.Backoff
+		// UsageCircuitBreaker configures when repeated usage fetch failures mark usage as stale. Defaults to
+		// retry.DefaultCircuitBreakerConfig.
+		UsageCircuitBreaker retry.CircuitBreakerConfig
+
+		Socket SocketConfig
+		TCP    TCPConfig
+
+		// AdminAddr is the address of the HTTP admin listener serving health, readiness, status and metrics endpoints.
+		// The admin listener is disabled when empty.
+		AdminAddr string
+
+		// AllowedCallers restricts which clients may connect. Any client may connect when empty.
+		AllowedCallers []AllowedCaller
+
 		APIKeys *llm.APIKeys
 
-		LowRiskThreshold    float32
+		// Bedrock configures the provider of Bedrock model ids, such as anthropic.claude-sonnet-4-20250514-v1:0.
+		Bedrock *llm.BedrockConfig
+		// Vertex configures the provider of Vertex AI model ids, such as claude-sonnet-4@20250514.
+		Vertex *llm.VertexConfig
+		// Gemini configures the provider of Gemini model ids, such as gemini-2.5-pro.
+		Gemini *llm.GeminiConfig
+		// Local configures the provider of self-hosted model ids, such as local/meta-llama/Llama-3.1-8B-Instruct.
+		Local *llm.LocalConfig
+		// Azure configures the provider of Azure OpenAI deployment ids, such as azure/gpt-4o-prod.
+		Azure *llm.AzureConfig
+
+		// UsageLedger keeps the usage clients report for providers without a usage API, such as Gemini and
+		// self-hosted models. Usage is kept in memory when nil.
+		UsageLedger *llm.Ledger
+
 		MediumRiskThreshold float32
 		HigthRiskThreshold  float32
+		RiskCeiling         float32
+		BeyondHighRiskLevel usage.RiskLevel
+
+		// QuotaWindow is the budget window usage is fetched and forecast for. Defaults to usage.WindowDaily.
+		QuotaWindow usage.Window
+		// QuotaTokens is the token budget for each model within the QuotaWindow. Forecasting is disabled when 0.
+		QuotaTokens int
+
+		// StaleRiskLevel is the lowest risk level of assessments while usage is stale. Assessments that are already
+		// more severe keep their level. When empty, assessments are flagged as stale but their risk level is unchanged.
+		StaleRiskLevel usage.RiskLevel
+
+		// RateLimits are the per-minute rate limits of each model until they are reported by the provider.
+		RateLimits map[usage.RateLimitKind]int
+
+		// Alerts notifies webhooks when a model's usage crosses a fraction of QuotaTokens within the QuotaWindow.
+		// Alerting is disabled when no URLs are configured.
+		Alerts alert.Config
+
+		// Audit records every assessment decision. Auditing is disabled when nil.
+		Audit audit.Sink
+
+		// Logger is the logger used by the agent and its usage worker. Defaults to slog.Default().
+		Logger *slog.Logger
 	}
 
 	Agent struct {
 		config *AgentConfig
+		logger *slog.Logger
 
-		listener *net.UnixListener
-		worker   *UsageWorker
+		listeners   []net.Listener
+		adminServer *http.Server
+		health      healthCache
+		worker      *UsageWorker
 
-		llmClient       *llm.APIClient
-		anthropicClient *llm.AnthropicClient
-		openAIClient    struct{}
+		llmClient *llm.APIClient
+		providers map[string]llm.Provider
+		ledger    *llm.Ledger
 
-		usageReceived chan map[string]int
+		usageReceived chan UsageSnapshot
 		workerReady   chan bool
 
 		currentUsageTokens map[string]int
+		usageUpdatedAt     time.Time
+		usageStatus        UsageSnapshotStatus
+		usageErr           error
 		usageMu            sync.Mutex
 		riskThresholds     usage.RiskThresholds
+		forecaster         *usage.Forecaster
+		metrics            *metrics.Metrics
+		rateLimits         *usage.RateLimitTracker
+		promptCache        *llm.PromptCache
+		alerter            *alert.Alerter
+
+		// cancelRun stops everything started by Run once connections have drained.
+		cancelRun context.CancelFunc
+
+		conns         map[net.Conn]struct{}
+		connsMu       sync.Mutex
+		connWG        sync.WaitGroup
+		shuttingDown  bool
+		shutdownHooks []shutdownHook
 
 		wg *sync.WaitGroup
 	}
+
+	// shutdownHook flushes a component that buffers data which must be persisted before the agent exits.
+	shutdownHook struct {
+		name  string
+		flush func(ctx context.Context) error
+	}
+
+	// Assessment is the risk assessment of a prompt for a single model.
+	Assessment struct {
+		Model string `json:"model"`
+		// ModelKey is the canonical key of Model that usage is aggregated by, such as claude-sonnet-4-20250514.
+		ModelKey     string          `json:"model_key"`
+		PromptTokens int             `json:"prompt_tokens"`
+		UsageTokens  int             `json:"usage_tokens"`
+		RiskLevel    usage.RiskLevel `json:"risk_level"`
+
+		// EstimatedCostUSD is the list price of the prompt tokens and MaxTokens output tokens. It is 0 when the
+		// price of the model is unknown.
+		EstimatedCostUSD float64 `json:"estimated_cost_usd,omitempty"`
+
+		// Cache is the expected use of the prompt cache by prompts with cache_control breakpoints, which
+		// EstimatedCostUSD is priced by. CacheSavingsUSD is how much less that costs than uncached input, and is
+		// negative when the prompt writes more to the cache than it reads.
+		Cache           *llm.CacheEstimate `json:"cache,omitempty"`
+		CacheSavingsUSD float64            `json:"cache_savings_usd,omitempty"`
+
+		// UsageUpdatedAt is when UsageTokens was last fetched and UsageAgeSeconds is how long ago that was.
+		UsageUpdatedAt  time.Time `json:"usage_updated_at"`
+		UsageAgeSeconds float64   `json:"usage_age_seconds"`
+
+		// Stale is true when usage could not be refreshed and the assessment is based on outdated usage.
+		Stale bool `json:"stale"`
+
+		// Forecast is nil until usage has been received for the model.
+		Forecast *usage.Forecast `json:"forecast,omitempty"`
+
+		// RateLimit is the risk of the prompt hitting the model's rate limits. It is assessed separately from RiskLevel.
+		RateLimit *usage.RateLimitAssessment `json:"rate_limit,omitempty"`
+
+		// Caller is the client the assessment was made for.
+		Caller *Caller `json:"caller,omitempty"`
+	}
 )
 
 // NewAgent returns an Agent.
@@ -61,8 +199,34 @@ func NewAgent(config *AgentConfig) (*Agent, error) {
 		return nil, fmt.Errorf("NewAgent failed: missing LLM models")
 	}
 
+	for _, model := range config.Models {
+		if _, err := llm.ParseModel(model); err != nil {
+			return nil, fmt.Errorf("NewAgent failed: %w", err)
+		}
+	}
+
+	riskThresholds, err := usage.NewRiskThresholds(usage.RiskThresholdsConfig{
+		Medium:     config.MediumRiskThreshold,
+		High:       config.HigthRiskThreshold,
+		Ceiling:    config.RiskCeiling,
+		BeyondHigh: config.BeyondHighRiskLevel,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("NewAgent failed: %w", err)
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	quotaWindow := config.QuotaWindow
+	if quotaWindow == "" {
+		quotaWindow = usage.WindowDaily
+	}
+
 	var wg sync.WaitGroup
-	usageReceived := make(chan map[string]int, 1)
+	usageReceived := make(chan UsageSnapshot, 1)
 	workerReady := make(chan bool, 1)
 
 	c := llm.ClientConfig{
@@ -72,66 +236,102 @@ func NewAgent(config *AgentConfig) (*Agent, error) {
 	llmClient := llm.NewAPIClient(c)
 
 	workerConfig := &UsageWorkerConfig{
-		Models:        config.Models,
-		UsageReceived: usageReceived,
-		WorkerReady:   workerReady,
-		TickRate:      config.RefreshUsageIntervalMinutes,
-		Client:        llmClient,
+		Models:           config.Models,
+		UsageReceived:    usageReceived,
+		WorkerReady:      workerReady,
+		TickRate:         config.RefreshUsageIntervalMinutes,
+		MaxFetchAttempts: config.UsageFetchAttempts,
+		Backoff:          config.UsageFetchBackoff,
+		CircuitBreaker:   config.UsageCircuitBreaker,
+		UsageWindow:      quotaWindow,
+		Client:           llmClient,
+		Logger:           logger.With(logging.KeyComponent, "usage_worker"),
+	}
+
+	if interval, ok := systemd.WatchdogInterval(); ok {
+		workerConfig.WatchdogInterval = interval
 	}
 
+	m := metrics.New()
+	workerConfig.Metrics = m
+
 	// An agent always has a usage worker
 	worker := newUsageWorker(workerConfig)
 
-	riskThresholds := usage.NewRiskThresholds(config.LowRiskThreshold, config.MediumRiskThreshold, config.HigthRiskThreshold)
-
-	return &Agent{
+	agent := &Agent{
 		config: config,
+		logger: logger.With(logging.KeyComponent, "agent"),
 
 		worker: worker,
 
 		llmClient: llmClient,
 
-		usageReceived:  usageReceived,
-		workerReady:    workerReady,
-		wg:             &wg,
+		usageReceived:      usageReceived,
+		workerReady:        workerReady,
+		wg:                 &wg,
 		currentUsageTokens: make(map[string]int),
-		riskThresholds: riskThresholds,
-	}, nil
-}
+		conns:              make(map[net.Conn]struct{}),
+		riskThresholds:     riskThresholds,
+		metrics:            m,
+		forecaster: usage.NewForecaster(usage.ForecasterConfig{
+			Window:      quotaWindow,
+			QuotaTokens: config.QuotaTokens,
+		}),
+		rateLimits: usage.NewRateLimitTracker(usage.RateLimitTrackerConfig{
+			Limits: config.RateLimits,
+		}),
+		promptCache: llm.NewPromptCache(llm.PromptCacheConfig{}),
+	}
 
-// Start starts the Scrollwork Agent.
-func (a *Agent) Start(ctx context.Context) error {
-	for _, model := range a.config.Models {
-		supportedLLMModel := llm.IsAnthropicModel(model) || llm.IsOpenAIModel(model)
-		if !supportedLLMModel {
-			return fmt.Errorf("failed to Start: LLM model %s must either be an OpenAI model or Anthropic model", model)
-		}
+	if len(config.Alerts.URLs) > 0 {
+		alertsConfig := config.Alerts
+		alertsConfig.QuotaTokens = config.QuotaTokens
+		alertsConfig.Window = quotaWindow
+		alertsConfig.Logger = logger.With(logging.KeyComponent, "alerts")
 
-		// TODO: Remove this check once we have OpenAI integrated
-		if llm.IsOpenAIModel(model) {
-			return fmt.Errorf("failed to Start: OpenAI model %s is not supported at this time", model)
+		alerter, err := alert.New(alertsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("NewAgent failed: %w", err)
 		}
+		agent.alerter = alerter
+	}
 
-		// TODO: We should do something like llm.NewAPIClient and obfuscate the Anthropic and OpenAI clients. Scrollwork package shouldn't really care
-		// or have any logic based on the model we are using.
-		if llm.IsAnthropicModel(model) {
-			if a.anthropicClient == nil {
-				anthropicClient := llm.NewAnthropicClient(a.config.APIKey, a.config.AdminKey)
-				a.anthropicClient = anthropicClient
-				a.worker.AnthropicClient = anthropicClient
-			}
+	if config.Audit != nil {
+		agent.onShutdown("audit log", config.Audit.Close)
+	}
+
+	agent.ledger = config.UsageLedger
+	if agent.ledger == nil {
+		agent.ledger, err = llm.NewLedger(llm.LedgerConfig{})
+		if err != nil {
+			return nil, fmt.Errorf("NewAgent failed: %w", err)
 		}
 	}
+	agent.onShutdown("usage ledger", func(context.Context) error { return agent.ledger.Close() })
+
+	return agent, nil
+}
+
+// Start starts the Scrollwork Agent.
+func (a *Agent) Start(ctx context.Context) error {
+	providers, err := a.newProviders()
+	if err != nil {
+		return fmt.Errorf("failed to Start: %w", err)
+	}
+	a.providers = providers
+	a.worker.Providers = providers
 
 	a.startupMessage()
 
 	// Startup the Usage Worker
-	log.Printf("Scrollwork Usage Worker starting up...")
+	a.logger.Info("Scrollwork Usage Worker starting up")
 	workerStartCtx, workerStartCancel := context.WithTimeout(ctx, 5*time.Second)
 	defer workerStartCancel()
-	a.worker.Start(workerStartCtx)
+	if err := a.worker.Start(workerStartCtx); err != nil {
+		return fmt.Errorf("Scrollwork Usage Worker failed to start: %w", err)
+	}
 
-	// Wait until worker is ready to run before we start the UNIX listener
+	// Wait until worker is ready to run before we start the listeners
 	select {
 	case <-workerStartCtx.Done():
 		if workerStartCtx.Err() != nil {
@@ -141,92 +341,261 @@ func (a *Agent) Start(ctx context.Context) error {
 		return nil
 
 	case <-a.workerReady:
-		log.Printf("Scrollwork Usage Worker is ready")
+		a.logger.Info("Scrollwork Usage Worker is ready")
 		workerStartCancel()
 	}
 
 	return nil
 }
 
+// Run runs the usage worker and starts accepting connections.
+//
+// Everything started by Run keeps running after ctx is canceled until Stop is called, so that in-flight assessments
+// can be drained.
 func (a *Agent) Run(ctx context.Context) error {
 	if a.worker == nil {
 		return fmt.Errorf("Scrollwork Agent failed to start: Usage Worker not configured")
 	}
 
+	runCtx, cancelRun := context.WithCancel(context.WithoutCancel(ctx))
+	a.cancelRun = cancelRun
+
 	// Run usage Worker
 	a.wg.Add(1)
 	go func() {
 		defer a.wg.Done()
-		a.worker.Run(ctx)
+		a.worker.Run(runCtx)
 	}()
 
 	// Handle updates to current usage
 	a.wg.Add(1)
 	go func() {
 		defer a.wg.Done()
-		a.processUsageUpdates(ctx)
+		a.processUsageUpdates(runCtx)
 	}()
 
-	log.Printf("Scrollwork Usage Worker is now running")
+	a.logger.Info("Scrollwork Usage Worker is now running")
+
+	// Deliver alerts
+	if a.alerter != nil {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.alerter.Run(runCtx)
+		}()
+	}
 
 	// Configure unix socket listener
-	addr := net.UnixAddr{Name: "/tmp/scrollwork.sock", Net: "unix"}
-	listener, err := net.ListenUnix("unix", &addr)
+	listener, err := listenUnix(a.config.Socket, a.logger.With(logging.KeyComponent, "socket"))
 	if err != nil {
 		return err
 	}
-	a.listener = listener
-	a.wg.Add(1)
+	a.listeners = append(a.listeners, listener)
 
-	go func() {
-		defer a.wg.Done()
-		a.listen(ctx)
-	}()
+	// Configure TCP listener for remote clients
+	if a.config.TCP.Addr != "" {
+		tcpListener, err := listenTLS(a.config.TCP)
+		if err != nil {
+			return err
+		}
+		a.listeners = append(a.listeners, tcpListener)
+		a.logger.Info("Scrollwork Agent is listening for TLS connections", "addr", tcpListener.Addr().String())
+	}
+
+	for _, listener := range a.listeners {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.listen(runCtx, listener)
+		}()
+	}
+
+	// Configure HTTP admin listener
+	if a.config.AdminAddr != "" {
+		if err := a.serveAdmin(a.config.AdminAddr); err != nil {
+			return err
+		}
+	}
+
+	a.logger.Info("Scrollwork Agent is now running and ready to accept connections")
+	a.notifySystemd(systemd.Ready)
 
-	log.Printf("Scrollwork Agent is now running and ready to accept connections")
 	return nil
 }
 
-// Stop stops the Scrollwork Agent.
-func (a *Agent) Stop() error {
+// Stop gracefully shuts down the Scrollwork Agent.
+//
+// It stops accepting connections, waits for in-flight assessments to be answered, stops the usage worker and flushes
+// any stores. Connections still open once ctx is done are closed. The returned error lists every step that did not
+// finish before ctx was done.
+func (a *Agent) Stop(ctx context.Context) error {
+	var errs []error
+
+	a.notifySystemd(systemd.Stopping)
+
+	// Stop accepting connections
+	a.connsMu.Lock()
+	a.shuttingDown = true
+	a.connsMu.Unlock()
+
+	for _, listener := range a.listeners {
+		listener.Close()
+	}
+
+	// Drain in-flight assessments. Connections waiting for their next request are closed right away.
+	a.connsMu.Lock()
+	for conn := range a.conns {
+		conn.SetReadDeadline(time.Now())
+	}
+	a.connsMu.Unlock()
+
+	stoppingAt := time.Now()
+	if waitContext(ctx, a.connWG.Wait) {
+		a.logger.Info("Scrollwork Agent connections have drained", logging.KeyDuration, time.Since(stoppingAt))
+	} else {
+		n := a.closeConnections()
+		errs = append(errs, fmt.Errorf("%d connections did not drain: %w", n, ctx.Err()))
+	}
+
+	// Shut down the admin listener
+	if a.adminServer != nil {
+		if err := a.adminServer.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("admin listener did not shut down: %w", err))
+		}
+	}
+
 	// Shut down the usage worker
-	go func() {
-		a.worker.Stop()
-	}()
+	a.worker.Stop()
+	if a.cancelRun != nil {
+		a.cancelRun()
+	}
 
-	// TODO: We should probably have a context.Deadline here in case worker shutdownm fails
-	select {
-	case <-a.worker.config.WorkerReady:
-		log.Printf("Scrollwork Usage Worker has shut down")
+	if waitContext(ctx, a.wg.Wait) {
+		a.logger.Info("Scrollwork Usage Worker has shut down", logging.KeyDuration, time.Since(stoppingAt))
+	} else {
+		errs = append(errs, fmt.Errorf("usage worker did not stop: %w", ctx.Err()))
 	}
 
-	// Shut down the UNIX socket
-	if a.listener != nil {
-		a.listener.Close()
+	// Flush stores
+	for _, hook := range a.shutdownHooks {
+		if err := hook.flush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s did not flush: %w", hook.name, err))
+		}
 	}
 
-	// Wait for everything else to clean up
-	a.wg.Wait()
+	if len(errs) > 0 {
+		return fmt.Errorf("Scrollwork Agent shutdown incomplete: %w", errors.Join(errs...))
+	}
 
 	return nil
 }
 
-func (a *Agent) listen(ctx context.Context) {
+// onShutdown registers a store to flush once the agent has stopped.
+func (a *Agent) onShutdown(name string, flush func(ctx context.Context) error) {
+	a.shutdownHooks = append(a.shutdownHooks, shutdownHook{name: name, flush: flush})
+}
+
+func (a *Agent) listen(ctx context.Context, listener net.Listener) {
+	var delay time.Duration
+
 	for {
-		select {
-		case <-ctx.Done():
-			log.Printf("Scrollwork Agent socket has been terminated")
-			return
-		default:
-			conn, err := a.listener.AcceptUnix()
-			if err != nil {
-				log.Printf("Scrollwork Agent connections can no longer be accepted: %v", err)
-				break
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				a.logger.Info("Scrollwork Agent listener has been closed", "addr", listener.Addr().String())
+				return
 			}
 
-			log.Printf("Scrollwork Agent connection accepted")
-			go a.handleConnection(ctx, conn)
+			delay = acceptRetryDelay(delay)
+			a.logger.Warn("Scrollwork Agent failed to accept connection, retrying", "delay", delay, logging.Err(err))
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+
+		if !a.trackConnection(conn) {
+			conn.Close()
+			continue
 		}
+
+		a.logger.Debug("Scrollwork Agent connection accepted", "addr", listener.Addr().String())
+		go func() {
+			defer a.untrackConnection(conn)
+			a.handleConnection(ctx, conn)
+		}()
+	}
+}
+
+// acceptRetryDelay returns how long to wait before accepting connections again after a failure.
+func acceptRetryDelay(previous time.Duration) time.Duration {
+	if previous == 0 {
+		return 5 * time.Millisecond
+	}
+
+	return min(previous*2, time.Second)
+}
+
+// trackConnection tracks conn until it is closed so it can be drained on shutdown.
+// It returns false if the agent is shutting down.
+func (a *Agent) trackConnection(conn net.Conn) bool {
+	a.connsMu.Lock()
+	defer a.connsMu.Unlock()
+
+	if a.shuttingDown {
+		return false
+	}
+
+	a.conns[conn] = struct{}{}
+	a.connWG.Add(1)
+
+	return true
+}
+
+func (a *Agent) untrackConnection(conn net.Conn) {
+	a.connsMu.Lock()
+	defer a.connsMu.Unlock()
+
+	if _, ok := a.conns[conn]; ok {
+		delete(a.conns, conn)
+		a.connWG.Done()
+	}
+}
+
+// closeConnections closes every open connection and returns how many were closed.
+func (a *Agent) closeConnections() int {
+	a.connsMu.Lock()
+	defer a.connsMu.Unlock()
+
+	n := len(a.conns)
+	for conn := range a.conns {
+		conn.Close()
+		delete(a.conns, conn)
+		a.connWG.Done()
+	}
+
+	return n
+}
+
+// waitContext calls wait and reports whether it returned before ctx was done.
+func waitContext(ctx context.Context, wait func()) bool {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// notifySystemd notifies systemd of state when the agent is run as a systemd service.
+func (a *Agent) notifySystemd(state string) {
+	if _, err := systemd.Notify(state); err != nil {
+		a.logger.Warn("Scrollwork Agent failed to notify systemd", logging.Err(err))
 	}
 }
 
@@ -243,27 +612,192 @@ func (a *Agent) startupMessage() {
 
 func (a *Agent) handleConnection(ctx context.Context, conn net.Conn) {
 	defer conn.Close()
-	defer log.Printf("Connection closed")
+	defer a.logger.Debug("Scrollwork Agent connection closed")
 
-	// TODO:
-	// 1. parse the JSON received, split by \n
-	// 2. Validate. throw out invalid json
-	// 3. Count the tokens using model's APIs
-	// 4. Return tokens used and percentage of total quota used
-	// 5. Return a risk level of the request. Low = Low cost, Medium = Medium cost, High = High / Unknown cost. Costs are configurable
+	encoder := json.NewEncoder(conn)
 
-	messages := []llm.Message{{Role: llm.MessageRoleUser, Content: "Hello world"}}
-	riskLevels, err := a.assesPrompt(ctx, messages)
+	// Remote peers that never finish the TLS handshake must not hold the connection
+	identifyCtx, cancel := context.WithTimeout(ctx, a.config.TCP.handshakeTimeout())
+	caller, err := identifyCaller(identifyCtx, conn, a.config.AllowedCallers)
+	cancel()
 	if err != nil {
-		log.Printf("assesPrompt failed: %v", err)
+		a.logger.Warn("Scrollwork Agent rejected connection", logging.Err(err))
+		encoder.Encode(Response{Error: "caller is not allowed"})
 		return
 	}
+	connLogger := a.logger.With(logging.KeyCaller, caller.Name)
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRequestSize)
+
+	for a.extendIdleDeadline(conn); scanner.Scan(); a.extendIdleDeadline(conn) {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var response Response
+		var request Request
+		if err := json.Unmarshal(line, &request); err != nil {
+			response.Error = fmt.Sprintf("invalid request: %v", err)
+			connLogger.Warn("Scrollwork Agent received an invalid request", logging.Err(err))
+		} else {
+			if request.ID == "" {
+				request.ID = logging.NewRequestID()
+			}
+			reqLogger := connLogger.With(logging.KeyRequestID, request.ID)
+
+			reqCtx, span := tracer.Start(tracing.Extract(ctx, request.Metadata), "scrollwork.request",
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("scrollwork.request.id", request.ID),
+					attribute.String("scrollwork.request.type", string(request.Type)),
+					attribute.String("scrollwork.caller", caller.Name),
+				),
+			)
+			reqCtx = logging.WithLogger(reqCtx, reqLogger)
+
+			startedAt := time.Now()
+			response = a.handleRequest(reqCtx, caller, request)
+			response.ID = request.ID
+			if response.Error != "" {
+				span.SetStatus(codes.Error, response.Error)
+			}
+			span.End()
+
+			reqLogger.Info("Scrollwork Agent handled request", "type", request.Type, logging.KeyDuration, time.Since(startedAt), logging.KeyError, response.Error)
+		}
+
+		if err := encoder.Encode(response); err != nil {
+			connLogger.Warn("Scrollwork Agent failed to write response", logging.Err(err))
+			return
+		}
+	}
 
-	response := fmt.Sprintf("You are currently using %d tokens.\n", a.getTotalUsage())
-	for model, level := range riskLevels {
-		response += fmt.Sprintf("Model %s has a risk level of %s\n", model, level)
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+		connLogger.Warn("Scrollwork Agent failed to read request", logging.Err(err))
 	}
-	conn.Write([]byte(response))
+}
+
+// extendIdleDeadline gives a TLS connection the idle timeout to send its next request. Unix socket connections are
+// local and have no idle timeout. The deadline is left alone once the agent is shutting down, so that Stop can close
+// connections waiting for their next request.
+func (a *Agent) extendIdleDeadline(conn net.Conn) {
+	if _, ok := conn.(*tls.Conn); !ok {
+		return
+	}
+
+	a.connsMu.Lock()
+	defer a.connsMu.Unlock()
+
+	if !a.shuttingDown {
+		conn.SetReadDeadline(time.Now().Add(a.config.TCP.idleTimeout()))
+	}
+}
+
+func (a *Agent) handleRequest(ctx context.Context, caller Caller, request Request) Response {
+	switch request.Type {
+	case RequestTypeAssess:
+		models := a.config.Models
+		if len(request.Body) > 0 {
+			body, err := llm.ParseRequest(request.Body)
+			if err != nil {
+				return Response{Error: fmt.Sprintf("invalid request: %v", err)}
+			}
+
+			// Bedrock and Vertex AI bodies do not name their model, and are assessed for every model
+			if body.Model != "" {
+				model, _, ok := a.providerFor(body.Model)
+				if !ok {
+					return Response{Error: fmt.Sprintf("invalid request: model %s is not configured", body.Model)}
+				}
+				models = []string{model}
+			}
+
+			request.System, request.Messages, request.Tools, request.MaxTokens = body.Prompt.System, body.Prompt.Messages, body.Prompt.Tools, body.MaxTokens
+		}
+
+		if len(request.Messages) == 0 {
+			return Response{Error: "invalid request: messages are required"}
+		}
+
+		assessments, err := a.assesPrompt(ctx, models, llm.Prompt{System: request.System, Messages: request.Messages, Tools: request.Tools}, request.MaxTokens)
+		if err != nil {
+			logging.FromContext(ctx).Error("assesPrompt failed", logging.Err(err))
+			a.audit(ctx, caller, request, assessments, err)
+			return Response{Error: err.Error()}
+		}
+
+		for i := range assessments {
+			assessments[i].Caller = &caller
+			a.metrics.ObserveAssessment(assessments[i].Model, string(assessments[i].RiskLevel))
+		}
+		a.audit(ctx, caller, request, assessments, nil)
+
+		return Response{Assessments: assessments}
+	case RequestTypeReportUsage:
+		if request.Usage == nil || request.Usage.Model == "" {
+			return Response{Error: "invalid request: usage model is required"}
+		}
+		if u := request.Usage; u.InputTokens < 0 || u.OutputTokens < 0 || u.CacheReadInputTokens < 0 || u.CacheCreationInputTokens < 0 {
+			return Response{Error: "invalid request: usage token counts must not be negative"}
+		}
+
+		a.reportUsage(*request.Usage)
+		return Response{}
+	default:
+		return Response{Error: fmt.Sprintf("invalid request: unknown type %q", request.Type)}
+	}
+}
+
+// reportUsage records the usage and rate limits of a completed LLM request reported by a client.
+func (a *Agent) reportUsage(report UsageReport) {
+	reportedAt := time.Now()
+
+	h := http.Header{}
+	for name, value := range report.Headers {
+		h.Set(name, value)
+	}
+
+	// Rate limits are tracked per model snapshot, whichever alias or platform id it is reported and assessed as
+	key := llm.ModelKey(report.Model)
+	a.rateLimits.RecordUsage(key, reportedAt, report.InputTokens, report.OutputTokens)
+	a.rateLimits.RecordHeaders(key, reportedAt, h)
+
+	// Providers without a usage API rely on the usage clients report
+	model, provider, ok := a.providerFor(report.Model)
+	if !ok {
+		return
+	}
+
+	if recorder, ok := provider.(llm.UsageRecorder); ok {
+		u := llm.TokenUsage{
+			UncachedInput:      report.InputTokens,
+			CacheReadInput:     report.CacheReadInputTokens,
+			CacheCreationInput: report.CacheCreationInputTokens,
+			Output:             report.OutputTokens,
+		}
+		if err := recorder.RecordUsage(model, reportedAt, u); err != nil {
+			a.logger.Error("Usage could not be recorded", logging.KeyModel, model, logging.Err(err))
+		}
+	}
+}
+
+// providerFor returns the configured model and provider a reported or assessed model belongs to. It may be an alias or
+// another platform's id of a configured model.
+func (a *Agent) providerFor(model string) (string, llm.Provider, bool) {
+	if provider, ok := a.providers[model]; ok {
+		return model, provider, true
+	}
+
+	key := llm.ModelKey(model)
+	for _, configured := range a.config.Models {
+		if llm.ModelKey(configured) == key {
+			return configured, a.providers[configured], true
+		}
+	}
+
+	return "", nil, false
 }
 
 func (a *Agent) processUsageUpdates(ctx context.Context) {
@@ -271,31 +805,79 @@ func (a *Agent) processUsageUpdates(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
-		case usage := <-a.usageReceived:
+		case snapshot := <-a.usageReceived:
+			a.updateUsage(snapshot)
+
+			if snapshot.Status != UsageSnapshotFresh {
+				updatedAt, _ := a.getUsageState()
+				a.logger.Warn("Usage was not refreshed, keeping previous usage", "status", snapshot.Status, "usage_age", time.Since(updatedAt).Round(time.Second), logging.Err(snapshot.Err))
+				a.notifySystemd(systemd.Status(fmt.Sprintf("Usage sync %s, last synced at %s", snapshot.Status, updatedAt.Format(time.RFC3339))))
+				break
+			}
+
+			a.notifySystemd(systemd.Status(fmt.Sprintf("Usage last synced at %s", snapshot.FetchedAt.Format(time.RFC3339))))
+
 			// Update usage for all models
-			for model, tokens := range usage {
-				a.updateUsage(model, tokens)
+			for model, tokens := range snapshot.Tokens {
+				a.forecaster.Observe(model, snapshot.FetchedAt, tokens)
+			}
+			for model, u := range snapshot.Usage {
+				a.metrics.ObserveUsage(model, u, a.config.QuotaTokens)
+			}
+			a.logger.Info("Current usage", "tokens", a.getTotalUsage())
+
+			if a.alerter != nil {
+				for model, tokens := range snapshot.Tokens {
+					if err := a.alerter.Observe(model, snapshot.FetchedAt, tokens); err != nil {
+						a.logger.Error("Alerts could not be raised", logging.KeyModel, model, logging.Err(err))
+					}
+				}
+			}
+
+			for model := range snapshot.Tokens {
+				if f, ok := a.forecaster.Forecast(model); ok && f.OnTrackToExceed {
+					a.logger.Warn("Model is on track to exceed its quota", logging.KeyModel, model, "window", f.Window, "quota_tokens", f.QuotaTokens, "exhausts_at", f.ExhaustsAt)
+				}
 			}
-			log.Printf("Current Usage: %d tokens", a.getTotalUsage())
 			break
 		}
 	}
 }
 
-// updateUsage updates the token usage for a specific model in a thread-safe manner.
-func (a *Agent) updateUsage(model string, tokens int) {
+// updateUsage applies a usage snapshot from the worker in a thread-safe manner.
+//
+// Only fresh snapshots replace the current token usage. Otherwise the last fresh usage is kept along with when it
+// was fetched.
+func (a *Agent) updateUsage(snapshot UsageSnapshot) {
+	a.usageMu.Lock()
+	defer a.usageMu.Unlock()
+
+	a.usageStatus = snapshot.Status
+	a.usageErr = snapshot.Err
+	if snapshot.Status != UsageSnapshotFresh {
+		return
+	}
+
+	for model, tokens := range snapshot.Tokens {
+		a.currentUsageTokens[model] = tokens
+	}
+	a.usageUpdatedAt = snapshot.FetchedAt
+}
+
+// getUsageState returns when usage was last refreshed and the status of the latest usage snapshot in a thread-safe manner.
+func (a *Agent) getUsageState() (time.Time, UsageSnapshotStatus) {
 	a.usageMu.Lock()
 	defer a.usageMu.Unlock()
 
-	a.currentUsageTokens[model] = tokens
+	return a.usageUpdatedAt, a.usageStatus
 }
 
-// getUsage returns the current token usage for a specific model in a thread-safe manner.
-func (a *Agent) getUsage(model string) int {
+// getUsage returns the current token usage for a specific model key in a thread-safe manner.
+func (a *Agent) getUsage(key string) int {
 	a.usageMu.Lock()
 	defer a.usageMu.Unlock()
 
-	return a.currentUsageTokens[model]
+	return a.currentUsageTokens[key]
 }
 
 // getTotalUsage returns the total token usage across all models in a thread-safe manner.
@@ -310,33 +892,122 @@ func (a *Agent) getTotalUsage() int {
 	return total
 }
 
-// Asses determines the risk level of a given prompt for each configured model.
-func (a *Agent) assesPrompt(ctx context.Context, messages []llm.Message) (map[string]usage.RiskLevel, error) {
-	riskLevels := make(map[string]usage.RiskLevel)
+// Asses determines the risk level of a given prompt for each of models.
+//
+// The risk level is raised when a model is forecast to exceed its quota before the end of the quota window.
+func (a *Agent) assesPrompt(ctx context.Context, models []string, prompt llm.Prompt, maxTokens int) (assessments []Assessment, err error) {
+	ctx, span := tracer.Start(ctx, "assesPrompt", trace.WithAttributes(attribute.Int("scrollwork.max_tokens", maxTokens)))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if len(models) == 0 {
+		return assessments, fmt.Errorf("no models configured")
+	}
+
+	usageUpdatedAt, usageStatus := a.getUsageState()
+	stale := usageStatus == UsageSnapshotStale
+
+	for _, model := range models {
+		assessment, err := a.assesModel(ctx, model, prompt, maxTokens, usageUpdatedAt, stale)
+		assessments = append(assessments, assessment)
+		if err != nil {
+			return assessments, err
+		}
+	}
+
+	return assessments, nil
+}
+
+// assesModel determines the risk level of prompt for model, tracing it in a span of its own. The assessment is
+// returned even when it fails, with what was determined before the failure.
+func (a *Agent) assesModel(ctx context.Context, model string, prompt llm.Prompt, maxTokens int, usageUpdatedAt time.Time, stale bool) (assessment Assessment, err error) {
+	ctx, span := tracer.Start(ctx, "assesModel", trace.WithAttributes(attribute.String("llm.model", model)))
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("llm.prompt_tokens", assessment.PromptTokens),
+			attribute.String("scrollwork.risk_level", string(assessment.RiskLevel)),
+		)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
 
-	if len(a.config.Models) == 0 {
-		return riskLevels, fmt.Errorf("no models configured")
+	// Usage and forecasts are shared by every platform a model is served by
+	key := llm.ModelKey(model)
+	assessment = Assessment{
+		Model:           model,
+		ModelKey:        key,
+		UsageTokens:     a.getUsage(key),
+		UsageUpdatedAt:  usageUpdatedAt,
+		UsageAgeSeconds: time.Since(usageUpdatedAt).Seconds(),
+		RiskLevel:       usage.RiskLevelUnknown,
+		Stale:           stale,
+	}
+	if f, ok := a.forecaster.Forecast(key); ok {
+		assessment.Forecast = &f
 	}
 
-	for _, model := range a.config.Models {
-		switch {
-		case llm.IsAnthropicModel(model):
-			tokens, err := a.anthropicClient.CountTokens(ctx, messages)
-			if err != nil {
-				riskLevels[model] = usage.RiskLevelUnknown
-				return riskLevels, err
-			}
+	provider, ok := a.providers[model]
+	if !ok {
+		return assessment, fmt.Errorf("no provider was configured for model %s", model)
+	}
+
+	countCtx, countSpan := tracer.Start(ctx, provider.Name()+".CountTokens",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("llm.model", model)),
+	)
+	countStartedAt := time.Now()
+	tokens, err := provider.CountTokens(countCtx, model, prompt)
+	a.metrics.ObserveCountTokens(model, time.Since(countStartedAt))
+	countSpan.SetAttributes(attribute.Int("llm.prompt_tokens", tokens))
+	if err != nil {
+		countSpan.RecordError(err)
+		countSpan.SetStatus(codes.Error, err.Error())
+	}
+	countSpan.End()
+	if err != nil {
+		return assessment, err
+	}
+
+	assessment.PromptTokens = tokens
 
-			level := a.riskThresholds.Asses(tokens)
-			riskLevels[model] = level
-		case llm.IsOpenAIModel(model):
-			riskLevels[model] = usage.RiskLevelUnknown
-			return riskLevels, fmt.Errorf("OpenAI model %s is not supported at this time", model)
-		default:
-			riskLevels[model] = usage.RiskLevelUnknown
-			return riskLevels, fmt.Errorf("unknown model: %s", model)
+	// Only Claude models have cache breakpoints, wherever they are served
+	if m, err := llm.ParseModel(model); err == nil && m.Provider == llm.ModelProviderAnthropic {
+		if cache := a.promptCache.Estimate(m, prompt, tokens, llm.TokenizerFor(m), time.Now()); cache.UncachedInputTokens != tokens {
+			assessment.Cache = &cache
 		}
 	}
 
-	return riskLevels, nil
+	pricing, ok := llm.LookupPricing(key)
+	if pricer, isPricer := provider.(llm.Pricer); isPricer {
+		pricing, ok = pricer.Pricing(model)
+	}
+	if ok {
+		pricing = pricing.ForPrompt(tokens)
+		assessment.EstimatedCostUSD = pricing.Cost(llm.TokenUsage{UncachedInput: tokens, Output: maxTokens})
+		if assessment.Cache != nil {
+			cost := assessment.Cache.Cost(pricing, maxTokens)
+			assessment.CacheSavingsUSD = assessment.EstimatedCostUSD - cost
+			assessment.EstimatedCostUSD = cost
+		}
+	}
+	assessment.RiskLevel = a.riskThresholds.Asses(tokens)
+	if assessment.Forecast != nil {
+		assessment.RiskLevel = assessment.Forecast.Elevate(assessment.RiskLevel)
+	}
+	if stale && a.config.StaleRiskLevel != "" {
+		assessment.RiskLevel = usage.MaxRiskLevel(assessment.RiskLevel, a.config.StaleRiskLevel)
+	}
+
+	rateLimit := a.rateLimits.Assess(key, time.Now(), tokens, maxTokens)
+	assessment.RateLimit = &rateLimit
+
+	return assessment, nil
 }
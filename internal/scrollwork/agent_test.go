@@ -0,0 +1,660 @@
+package scrollwork
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"scrollwork/internal/alert"
+	"scrollwork/internal/llm"
+	"scrollwork/internal/retry"
+	"scrollwork/internal/usage"
+)
+
+func newTestAgent(t *testing.T) *Agent {
+	t.Helper()
+
+	agent, err := NewAgent(newTestAgentConfig("claude-sonnet-4-20250514"))
+	require.NoError(t, err)
+
+	return agent
+}
+
+func newTestAgentConfig(models ...string) *AgentConfig {
+	return &AgentConfig{
+		Models:                      models,
+		RefreshUsageIntervalMinutes: 1,
+		MediumRiskThreshold:         75,
+		HigthRiskThreshold:          100,
+	}
+}
+
+// ledgerProvider is a fakeProvider whose usage is reported by clients.
+type ledgerProvider struct {
+	*fakeProvider
+	ledger *llm.Ledger
+}
+
+func (p *ledgerProvider) RecordUsage(model string, at time.Time, usage llm.TokenUsage) error {
+	return p.ledger.Record(model, at, usage)
+}
+
+func TestAgent_ReportUsage(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]struct {
+		report   UsageReport
+		err      string
+		expected llm.TokenUsage
+	}{
+		"recorded": {
+			report:   UsageReport{Model: "claude-sonnet-4-20250514", InputTokens: 100, OutputTokens: 10, CacheReadInputTokens: 1000},
+			expected: llm.TokenUsage{UncachedInput: 100, CacheReadInput: 1000, Output: 10},
+		},
+		"alias": {
+			report:   UsageReport{Model: "claude-sonnet-4-0", InputTokens: 100},
+			expected: llm.TokenUsage{UncachedInput: 100},
+		},
+		"unconfigured model": {
+			report: UsageReport{Model: "claude-opus-4-1-20250805", InputTokens: 100},
+		},
+		"no model": {
+			report: UsageReport{InputTokens: 100},
+			err:    "invalid request: usage model is required",
+		},
+		"negative tokens": {
+			report: UsageReport{Model: "claude-sonnet-4-20250514", InputTokens: 100, OutputTokens: -50},
+			err:    "invalid request: usage token counts must not be negative",
+		},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ledger, err := llm.NewLedger(llm.LedgerConfig{})
+			require.NoError(t, err)
+
+			agent := newTestAgent(t)
+			agent.providers = map[string]llm.Provider{"claude-sonnet-4-20250514": &ledgerProvider{fakeProvider: &fakeProvider{name: "fake"}, ledger: ledger}}
+
+			response := agent.handleRequest(context.Background(), Caller{Name: "test"}, Request{Type: RequestTypeReportUsage, Usage: &td.report})
+			require.Equal(t, td.err, response.Error)
+
+			usage := ledger.Usage([]string{"claude-sonnet-4-20250514"}, time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+			require.Equal(t, td.expected, usage["claude-sonnet-4-20250514"])
+		})
+	}
+}
+
+func TestAgent_RateLimitsByModelKey(t *testing.T) {
+	t.Parallel()
+
+	agent := newTestAgent(t)
+	agent.providers = map[string]llm.Provider{"claude-sonnet-4-20250514": &fakeProvider{name: "fake", tokens: 10}}
+
+	// Usage reported under an alias counts against the configured model
+	response := agent.handleRequest(context.Background(), Caller{Name: "test"}, Request{Type: RequestTypeReportUsage, Usage: &UsageReport{
+		Model:        "claude-sonnet-4-0",
+		InputTokens:  1000,
+		OutputTokens: 100,
+		Headers:      map[string]string{"anthropic-ratelimit-input-tokens-limit": "2000", "anthropic-ratelimit-input-tokens-remaining": "1000", "anthropic-ratelimit-input-tokens-reset": time.Now().Add(time.Minute).Format(time.RFC3339)},
+	}})
+	require.Empty(t, response.Error)
+
+	assessments, err := agent.assesPrompt(context.Background(), []string{"claude-sonnet-4-20250514"}, llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("Hi")}}}, 100)
+	require.NoError(t, err)
+	require.Len(t, assessments, 1)
+	require.Equal(t, 1000, assessments[0].RateLimit.PerMinute[usage.RateLimitInputTokens])
+	require.Equal(t, 2000, assessments[0].RateLimit.Limits[usage.RateLimitInputTokens])
+}
+
+func TestNewAgent_UsageFetchRetry(t *testing.T) {
+	t.Parallel()
+
+	config := newTestAgentConfig("claude-sonnet-4-20250514")
+	config.UsageFetchAttempts = 5
+	config.UsageFetchBackoff = &retry.Backoff{Initial: time.Millisecond, Max: time.Second, Multiplier: 2}
+	config.UsageCircuitBreaker = retry.CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute}
+
+	agent, err := NewAgent(config)
+	require.NoError(t, err)
+	require.Equal(t, 5, agent.worker.config.MaxFetchAttempts)
+	require.Equal(t, config.UsageFetchBackoff, agent.worker.config.Backoff)
+
+	// A single failed refresh trips the configured breaker
+	agent.worker.Providers = map[string]llm.Provider{"claude-sonnet-4-20250514": &fakeProvider{name: "fake", usage: func(ctx context.Context, models []string) (map[string]llm.TokenUsage, error) {
+		return nil, errors.New("unavailable")
+	}}}
+	agent.worker.config.Backoff = &retry.Backoff{Initial: time.Millisecond, Max: time.Millisecond}
+	require.Equal(t, UsageSnapshotStale, agent.worker.fetchSnapshot(context.Background()).Status)
+}
+
+func TestAgent_AssesPrompt_StaleRiskLevel(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]struct {
+		tokens   int
+		expected usage.RiskLevel
+	}{
+		"raised to the stale level": {tokens: 10, expected: usage.RiskLevelMedium},
+		"high is kept":              {tokens: 150, expected: usage.RiskLevelHigh},
+		"beyond the ceiling":        {tokens: 5000, expected: usage.RiskLevelUnknown},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			agent := newTestAgent(t)
+			agent.config.StaleRiskLevel = usage.RiskLevelMedium
+			agent.providers = map[string]llm.Provider{"claude-sonnet-4-20250514": &fakeProvider{name: "fake", tokens: td.tokens}}
+			agent.updateUsage(UsageSnapshot{Status: UsageSnapshotStale, Err: errCircuitOpen})
+
+			assessments, err := agent.assesPrompt(context.Background(), []string{"claude-sonnet-4-20250514"}, llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("Hi")}}}, 100)
+			require.NoError(t, err)
+			require.True(t, assessments[0].Stale)
+			require.Equal(t, td.expected, assessments[0].RiskLevel)
+		})
+	}
+}
+
+// TestAgent_AssesPrompt_Spans is not parallel, since it replaces the package's tracer.
+func TestAgent_AssesPrompt_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(context.Background())
+
+	defer func(previous trace.Tracer) { tracer = previous }(tracer)
+	tracer = provider.Tracer("scrollwork/internal/scrollwork")
+
+	const (
+		sonnet = "claude-sonnet-4-20250514"
+		opus   = "claude-opus-4-1-20250805"
+	)
+	agent := newTestAgent(t)
+	agent.providers = map[string]llm.Provider{
+		sonnet: &fakeProvider{name: "fake", tokens: 60},
+		opus:   &fakeProvider{name: "fake", countErr: errors.New("unavailable")},
+	}
+
+	assessments, err := agent.assesPrompt(context.Background(), []string{sonnet, opus}, llm.Prompt{Messages: []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("Hi")}}}, 100)
+	require.Error(t, err)
+	require.Len(t, assessments, 2)
+
+	// Every model is assessed in a span of its own, with the outcome of its assessment
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if span.Name() == "assesModel" {
+			attributes := attribute.NewSet(span.Attributes()...)
+			model, _ := attributes.Value("llm.model")
+			spans[model.AsString()] = span
+		}
+	}
+	require.Len(t, spans, 2)
+
+	attributes := attribute.NewSet(spans[sonnet].Attributes()...)
+	tokens, _ := attributes.Value("llm.prompt_tokens")
+	require.Equal(t, int64(60), tokens.AsInt64())
+	risk, _ := attributes.Value("scrollwork.risk_level")
+	require.Equal(t, string(assessments[0].RiskLevel), risk.AsString())
+	require.Equal(t, codes.Unset, spans[sonnet].Status().Code)
+
+	require.Equal(t, codes.Error, spans[opus].Status().Code)
+	require.Equal(t, "unavailable", spans[opus].Status().Description)
+}
+
+// recordingSink is an audit.Sink that keeps the records it is given.
+type recordingSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (s *recordingSink) Append(ctx context.Context, record any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, record.(AuditRecord))
+	return nil
+}
+
+func (s *recordingSink) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestAgent_Audit(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]struct {
+		provider *fakeProvider
+		err      string
+	}{
+		"assessed": {provider: &fakeProvider{name: "fake", tokens: 10}},
+		"failed":   {provider: &fakeProvider{name: "fake", countErr: errors.New("unavailable")}, err: "unavailable"},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sink := &recordingSink{}
+			agent := newTestAgent(t)
+			agent.config.Audit = sink
+			agent.providers = map[string]llm.Provider{"claude-sonnet-4-20250514": td.provider}
+
+			response := agent.handleRequest(context.Background(), Caller{Name: "test"}, Request{
+				Type:      RequestTypeAssess,
+				ID:        "request-1",
+				MaxTokens: 100,
+				Messages:  []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("Hi")}},
+			})
+			require.Equal(t, td.err, response.Error)
+
+			// Failed assessments are audited with the error
+			require.Len(t, sink.records, 1)
+			require.Equal(t, "request-1", sink.records[0].RequestID)
+			require.Equal(t, "claude-sonnet-4-20250514", sink.records[0].Model)
+			require.Equal(t, td.provider.tokens, sink.records[0].PromptTokens)
+			require.Equal(t, td.err, sink.records[0].Error)
+		})
+	}
+}
+
+func TestAgent_HandleRequest_Body(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sonnet = "claude-sonnet-4-20250514"
+		opus   = "claude-opus-4-1-20250805"
+	)
+
+	tc := map[string]struct {
+		body     string
+		err      string
+		expected []string
+	}{
+		"messages api": {
+			body:     `{"model": "claude-sonnet-4-20250514", "max_tokens": 100, "system": "Be brief.", "messages": [{"role": "user", "content": "Hi"}]}`,
+			expected: []string{sonnet},
+		},
+		"alias": {
+			body:     `{"model": "claude-sonnet-4-0", "max_tokens": 100, "messages": [{"role": "user", "content": "Hi"}]}`,
+			expected: []string{sonnet},
+		},
+		"chat completions": {
+			body:     `{"model": "claude-opus-4-1-20250805", "max_completion_tokens": 100, "messages": [{"role": "developer", "content": "Be brief."}, {"role": "user", "content": "Hi"}]}`,
+			expected: []string{opus},
+		},
+		"no model": {
+			body:     `{"anthropic_version": "bedrock-2023-05-31", "max_tokens": 100, "messages": [{"role": "user", "content": "Hi"}]}`,
+			expected: []string{sonnet, opus},
+		},
+		"unconfigured model": {
+			body: `{"model": "claude-3-5-haiku-20241022", "max_tokens": 100, "messages": [{"role": "user", "content": "Hi"}]}`,
+			err:  "invalid request: model claude-3-5-haiku-20241022 is not configured",
+		},
+		"no messages": {
+			body: `{"model": "claude-sonnet-4-20250514", "max_tokens": 100}`,
+			err:  "invalid request: ParseRequest failed: anthropic_messages: messages are required",
+		},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			agent, err := NewAgent(newTestAgentConfig(sonnet, opus))
+			require.NoError(t, err)
+			providers := map[string]*fakeProvider{
+				sonnet: {name: "sonnet", tokens: 10},
+				opus:   {name: "opus", tokens: 20},
+			}
+			agent.providers = map[string]llm.Provider{sonnet: providers[sonnet], opus: providers[opus]}
+
+			response := agent.handleRequest(context.Background(), Caller{Name: "test"}, Request{Type: RequestTypeAssess, Body: []byte(td.body)})
+			require.Equal(t, td.err, response.Error)
+
+			var models []string
+			for _, assessment := range response.Assessments {
+				models = append(models, assessment.Model)
+				require.Equal(t, providers[assessment.Model].tokens, assessment.PromptTokens)
+			}
+			require.Equal(t, td.expected, models)
+
+			// Only the providers of the assessed models count the prompt
+			for model, provider := range providers {
+				if slices.Contains(td.expected, model) {
+					require.Len(t, provider.prompts, 1)
+					require.Equal(t, llm.TextContent("Hi"), provider.prompts[0].Messages[len(provider.prompts[0].Messages)-1].Content)
+				} else {
+					require.Empty(t, provider.prompts)
+				}
+			}
+		})
+	}
+}
+
+func TestAgent_NewProviders(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]struct {
+		config   func(config *AgentConfig)
+		models   []string
+		expected map[string]string
+		err      string
+	}{
+		"anthropic": {
+			models:   []string{"claude-sonnet-4-20250514", "claude-opus-4-1-20250805"},
+			config:   func(config *AgentConfig) { config.APIKey, config.AdminKey = "test-key", "test-admin-key" },
+			expected: map[string]string{"claude-sonnet-4-20250514": "*llm.AnthropicClient", "claude-opus-4-1-20250805": "*llm.AnthropicClient"},
+		},
+		"anthropic without admin key": {
+			models: []string{"claude-sonnet-4-20250514"},
+			config: func(config *AgentConfig) { config.APIKey = "test-key" },
+			err:    "Anthropic model claude-sonnet-4-20250514 requires an API key and an admin key",
+		},
+		"gemini and self-hosted": {
+			models: []string{"gemini-2.5-pro", "local/meta-llama/Llama-3.1-8B-Instruct"},
+			config: func(config *AgentConfig) {
+				config.Gemini = &llm.GeminiConfig{APIKey: "test-key"}
+				config.Local = &llm.LocalConfig{BaseURL: "http://127.0.0.1:8000/v1"}
+			},
+			expected: map[string]string{"gemini-2.5-pro": "*llm.GeminiClient", "local/meta-llama/Llama-3.1-8B-Instruct": "*llm.LocalClient"},
+		},
+		"bedrock not configured": {
+			models: []string{"anthropic.claude-sonnet-4-20250514-v1:0"},
+			err:    "Bedrock model anthropic.claude-sonnet-4-20250514-v1:0 requires Bedrock to be configured",
+		},
+		"gemini not configured": {
+			models: []string{"gemini-2.5-pro"},
+			err:    "Gemini model gemini-2.5-pro requires Gemini to be configured",
+		},
+		"openai": {
+			models: []string{"gpt-4o"},
+			err:    "OpenAI model gpt-4o is not supported at this time",
+		},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			config := newTestAgentConfig(td.models...)
+			if td.config != nil {
+				td.config(config)
+			}
+			agent, err := NewAgent(config)
+			require.NoError(t, err)
+
+			providers, err := agent.newProviders()
+			if td.err != "" {
+				require.EqualError(t, err, td.err)
+				return
+			}
+			require.NoError(t, err)
+
+			types := make(map[string]string, len(providers))
+			for model, provider := range providers {
+				types[model] = fmt.Sprintf("%T", provider)
+			}
+			require.Equal(t, td.expected, types)
+
+			// Models of the same platform share a client
+			for _, model := range td.models[1:] {
+				if types[model] == types[td.models[0]] {
+					require.Same(t, providers[td.models[0]], providers[model])
+				}
+			}
+		})
+	}
+}
+
+func TestAgent_ProcessUsageUpdates_Alerts(t *testing.T) {
+	t.Parallel()
+
+	events := make(chan alert.Event, 16)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event alert.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		events <- event
+	}))
+	t.Cleanup(server.Close)
+
+	config := newTestAgentConfig("claude-sonnet-4-20250514")
+	config.QuotaTokens = 1000
+	config.Alerts = alert.Config{URLs: []string{server.URL}, Thresholds: []float64{0.5, 1}}
+	agent, err := NewAgent(config)
+	require.NoError(t, err)
+	require.NotNil(t, agent.alerter)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go agent.alerter.Run(ctx)
+	go agent.processUsageUpdates(ctx)
+
+	// Snapshots that were not refreshed raise no alerts
+	agent.usageReceived <- UsageSnapshot{Status: UsageSnapshotFailed, FetchedAt: time.Now(), Err: errors.New("unavailable")}
+	agent.usageReceived <- UsageSnapshot{Status: UsageSnapshotFresh, FetchedAt: time.Now(), Tokens: map[string]int{"claude-sonnet-4-20250514": 600}}
+
+	select {
+	case event := <-events:
+		require.Equal(t, alert.EventThresholdCrossed, event.Type)
+		require.Equal(t, "claude-sonnet-4-20250514", event.Model)
+		require.Equal(t, 0.5, event.Threshold)
+		require.Equal(t, 600, event.UsedTokens)
+		require.Equal(t, 1000, event.QuotaTokens)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no alert was delivered")
+	}
+
+	select {
+	case event := <-events:
+		require.FailNow(t, "unexpected alert", "%+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestAgent_ProcessUsageUpdates_KeepsFreshUsage(t *testing.T) {
+	t.Parallel()
+
+	const model = "claude-sonnet-4-20250514"
+	fetchedAt := time.Now().Add(-time.Minute)
+
+	tc := map[string]struct {
+		next          UsageSnapshot
+		expected      int
+		expectedAt    time.Time
+		expectedError error
+	}{
+		"fresh": {
+			next:       UsageSnapshot{Status: UsageSnapshotFresh, Tokens: map[string]int{model: 200}, FetchedAt: fetchedAt.Add(time.Minute)},
+			expected:   200,
+			expectedAt: fetchedAt.Add(time.Minute),
+		},
+		"failed": {
+			next:          UsageSnapshot{Status: UsageSnapshotFailed, FetchedAt: fetchedAt.Add(time.Minute), Err: errors.New("unavailable")},
+			expected:      100,
+			expectedAt:    fetchedAt,
+			expectedError: errors.New("unavailable"),
+		},
+		"stale": {
+			next:          UsageSnapshot{Status: UsageSnapshotStale, FetchedAt: fetchedAt.Add(time.Minute), Err: errCircuitOpen},
+			expected:      100,
+			expectedAt:    fetchedAt,
+			expectedError: errCircuitOpen,
+		},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			agent := newTestAgent(t)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go agent.processUsageUpdates(ctx)
+
+			agent.usageReceived <- UsageSnapshot{Status: UsageSnapshotFresh, Tokens: map[string]int{model: 100}, FetchedAt: fetchedAt}
+			agent.usageReceived <- td.next
+
+			require.Eventually(t, func() bool {
+				_, status := agent.getUsageState()
+				return status == td.next.Status && agent.getUsage(model) == td.expected
+			}, time.Second, time.Millisecond)
+
+			// Snapshots that are not fresh keep the last fresh usage and when it was fetched
+			updatedAt, _ := agent.getUsageState()
+			require.Equal(t, td.expectedAt, updatedAt)
+
+			agent.usageMu.Lock()
+			defer agent.usageMu.Unlock()
+			require.Equal(t, td.expectedError, agent.usageErr)
+		})
+	}
+}
+
+// blockingProvider is a fakeProvider whose token counts block until released.
+type blockingProvider struct {
+	*fakeProvider
+
+	counting chan struct{}
+	release  chan struct{}
+}
+
+func (p *blockingProvider) CountTokens(ctx context.Context, model string, prompt llm.Prompt) (int, error) {
+	p.counting <- struct{}{}
+	<-p.release
+
+	return p.fakeProvider.CountTokens(ctx, model, prompt)
+}
+
+// runTestAgent runs an agent serving provider on a socket of its own, and returns the agent and a connection to it
+// with an assessment in flight.
+func runTestAgent(t *testing.T, provider *blockingProvider) (*Agent, net.Conn) {
+	t.Helper()
+
+	agent := newTestAgent(t)
+	agent.config.Socket.Path = filepath.Join(t.TempDir(), "scrollwork.sock")
+	agent.providers = map[string]llm.Provider{"claude-sonnet-4-20250514": provider}
+	require.NoError(t, agent.Run(context.Background()))
+
+	conn, err := net.Dial("unix", agent.config.Socket.Path)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	require.NoError(t, json.NewEncoder(conn).Encode(Request{
+		Type:      RequestTypeAssess,
+		Messages:  []llm.Message{{Role: llm.MessageRoleUser, Content: llm.TextContent("Hi")}},
+		MaxTokens: 100,
+	}))
+	<-provider.counting
+
+	return agent, conn
+}
+
+func TestAgent_Stop_Drain(t *testing.T) {
+	t.Parallel()
+
+	provider := &blockingProvider{fakeProvider: &fakeProvider{name: "fake", tokens: 10}, counting: make(chan struct{}), release: make(chan struct{})}
+	agent, conn := runTestAgent(t, provider)
+
+	// A connection that has been answered and waits for its next request
+	idle, err := net.Dial("unix", agent.config.Socket.Path)
+	require.NoError(t, err)
+	defer idle.Close()
+	require.NoError(t, json.NewEncoder(idle).Encode(Request{Type: RequestTypeReportUsage}))
+	require.NoError(t, json.NewDecoder(idle).Decode(&Response{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- agent.Stop(ctx) }()
+
+	// Connections waiting for their next request are closed right away
+	require.NoError(t, idle.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = idle.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+
+	// Stop waits for the assessment in flight, which is answered
+	select {
+	case err := <-stopped:
+		require.Fail(t, "Stop returned before the connection drained", "error: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(provider.release)
+
+	var response Response
+	require.NoError(t, json.NewDecoder(conn).Decode(&response))
+	require.Empty(t, response.Error)
+	require.Len(t, response.Assessments, 1)
+	require.Equal(t, 10, response.Assessments[0].PromptTokens)
+
+	require.NoError(t, <-stopped)
+}
+
+func TestAgent_Stop_Deadline(t *testing.T) {
+	t.Parallel()
+
+	provider := &blockingProvider{fakeProvider: &fakeProvider{name: "fake", tokens: 10}, counting: make(chan struct{}), release: make(chan struct{})}
+	defer close(provider.release)
+	agent, conn := runTestAgent(t, provider)
+
+	// The connection that is still busy at the deadline is closed
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := agent.Stop(ctx)
+	require.ErrorContains(t, err, "1 connections did not drain")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestAgent_Stop_ShutdownHooks(t *testing.T) {
+	t.Parallel()
+
+	agent := newTestAgent(t)
+	agent.config.Socket.Path = filepath.Join(t.TempDir(), "scrollwork.sock")
+	require.NoError(t, agent.Run(context.Background()))
+
+	// Hooks run in the order they were registered, once the agent no longer accepts connections
+	var flushed []string
+	for _, name := range []string{"first", "second", "third"} {
+		agent.onShutdown(name, func(ctx context.Context) error {
+			_, err := net.Dial("unix", agent.config.Socket.Path)
+			require.Error(t, err)
+
+			flushed = append(flushed, name)
+			if name == "second" {
+				return errors.New("disk full")
+			}
+			return nil
+		})
+	}
+
+	// A hook that fails does not keep the others from running
+	err := agent.Stop(context.Background())
+	require.ErrorContains(t, err, "second did not flush: disk full")
+	require.Equal(t, []string{"first", "second", "third"}, flushed)
+}
@@ -0,0 +1,70 @@
+package scrollwork
+
+import (
+	"context"
+	"scrollwork/internal/logging"
+	"scrollwork/internal/usage"
+	"time"
+)
+
+type (
+	// AuditRecord is the audit log record of an assessment decision.
+	AuditRecord struct {
+		Timestamp time.Time `json:"timestamp"`
+		RequestID string    `json:"request_id"`
+		Caller    Caller    `json:"caller"`
+
+		Model            string  `json:"model"`
+		PromptTokens     int     `json:"prompt_tokens"`
+		MaxTokens        int     `json:"max_tokens"`
+		EstimatedCostUSD float64 `json:"estimated_cost_usd"`
+
+		// UsageTokens is the usage of the model at decision time, as fetched at UsageUpdatedAt.
+		UsageTokens    int       `json:"usage_tokens"`
+		UsageUpdatedAt time.Time `json:"usage_updated_at"`
+		Stale          bool      `json:"stale"`
+
+		Thresholds         usage.RiskThresholdsConfig `json:"thresholds"`
+		RiskLevel          usage.RiskLevel            `json:"risk_level"`
+		RateLimitRiskLevel usage.RiskLevel            `json:"rate_limit_risk_level,omitempty"`
+
+		// Error is why the request failed. The caller gets no assessments of a failed request.
+		Error string `json:"error,omitempty"`
+	}
+)
+
+// audit appends a record of every assessment made for a request to the audit log, with the error the request failed
+// with, if any. Records that cannot be appended are logged and do not fail the request.
+func (a *Agent) audit(ctx context.Context, caller Caller, request Request, assessments []Assessment, err error) {
+	if a.config.Audit == nil {
+		return
+	}
+
+	thresholds := a.riskThresholds.Config()
+	for _, assessment := range assessments {
+		record := AuditRecord{
+			Timestamp:        time.Now().UTC(),
+			RequestID:        request.ID,
+			Caller:           caller,
+			Model:            assessment.Model,
+			PromptTokens:     assessment.PromptTokens,
+			MaxTokens:        request.MaxTokens,
+			EstimatedCostUSD: assessment.EstimatedCostUSD,
+			UsageTokens:      assessment.UsageTokens,
+			UsageUpdatedAt:   assessment.UsageUpdatedAt,
+			Stale:            assessment.Stale,
+			Thresholds:       thresholds,
+			RiskLevel:        assessment.RiskLevel,
+		}
+		if assessment.RateLimit != nil {
+			record.RateLimitRiskLevel = assessment.RateLimit.RiskLevel
+		}
+		if err != nil {
+			record.Error = err.Error()
+		}
+
+		if appendErr := a.config.Audit.Append(ctx, record); appendErr != nil {
+			logging.FromContext(ctx).Error("Scrollwork Agent failed to audit assessment", logging.KeyModel, assessment.Model, logging.Err(appendErr))
+		}
+	}
+}
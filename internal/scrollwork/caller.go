@@ -0,0 +1,152 @@
+package scrollwork
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Caller is the identity of the client on the other end of a connection.
+	Caller struct {
+		// Name is the name of the matching AllowedCaller. Without an allowlist it is the user name of a Unix socket
+		// peer, or the common name of a TLS client certificate.
+		Name string `json:"name"`
+
+		// UID, GID and PID are the credentials of a Unix socket peer.
+		UID *int `json:"uid,omitempty"`
+		GID *int `json:"gid,omitempty"`
+		PID *int `json:"pid,omitempty"`
+
+		// CommonName is the subject common name of a TLS client certificate.
+		CommonName string `json:"common_name,omitempty"`
+	}
+
+	// AllowedCaller is an allowlist entry. A caller matches when every field set on the entry matches.
+	AllowedCaller struct {
+		Name       string
+		UID        *int
+		GID        *int
+		CommonName string
+	}
+
+	// PeerCredentials are the credentials of the process on the other end of a Unix socket.
+	PeerCredentials struct {
+		UID int
+		GID int
+		PID int
+	}
+)
+
+var errPeerCredentialsUnsupported = errors.New("peer credentials are not supported on this platform")
+
+// ParseAllowedCaller parses an allowlist entry such as "name=billing,uid=1001,gid=1001" or "name=billing,cn=billing-service".
+func ParseAllowedCaller(s string) (AllowedCaller, error) {
+	var allowed AllowedCaller
+
+	for _, field := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok || value == "" {
+			return AllowedCaller{}, fmt.Errorf("ParseAllowedCaller failed: %q must be key=value", field)
+		}
+
+		switch key {
+		case "name":
+			allowed.Name = value
+		case "uid", "gid":
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return AllowedCaller{}, fmt.Errorf("ParseAllowedCaller failed: %s must be a number: %w", key, err)
+			}
+
+			if key == "uid" {
+				allowed.UID = &id
+			} else {
+				allowed.GID = &id
+			}
+		case "cn":
+			allowed.CommonName = value
+		default:
+			return AllowedCaller{}, fmt.Errorf("ParseAllowedCaller failed: unknown key %q", key)
+		}
+	}
+
+	if allowed.Name == "" {
+		return AllowedCaller{}, fmt.Errorf("ParseAllowedCaller failed: name is required")
+	}
+
+	if allowed.UID == nil && allowed.GID == nil && allowed.CommonName == "" {
+		return AllowedCaller{}, fmt.Errorf("ParseAllowedCaller failed: %s must match a uid, gid or cn", allowed.Name)
+	}
+
+	return allowed, nil
+}
+
+// matches reports whether caller matches the allowlist entry.
+func (a AllowedCaller) matches(caller Caller) bool {
+	if a.UID != nil && (caller.UID == nil || *caller.UID != *a.UID) {
+		return false
+	}
+
+	if a.GID != nil && (caller.GID == nil || *caller.GID != *a.GID) {
+		return false
+	}
+
+	if a.CommonName != "" && caller.CommonName != a.CommonName {
+		return false
+	}
+
+	return true
+}
+
+// identifyCaller returns the identity of the client connected on conn.
+//
+// Unix socket peers are identified by their SO_PEERCRED credentials and TLS clients by their certificate. When an
+// allowlist is configured, callers that match no entry are rejected.
+func identifyCaller(ctx context.Context, conn net.Conn, allowlist []AllowedCaller) (Caller, error) {
+	var caller Caller
+
+	switch c := conn.(type) {
+	case *net.UnixConn:
+		creds, err := peerCredentials(c)
+		if errors.Is(err, errPeerCredentialsUnsupported) && len(allowlist) == 0 {
+			return caller, nil
+		}
+		if err != nil {
+			return caller, fmt.Errorf("identifyCaller failed: %w", err)
+		}
+
+		caller.UID, caller.GID, caller.PID = &creds.UID, &creds.GID, &creds.PID
+		caller.Name = strconv.Itoa(creds.UID)
+		if u, err := user.LookupId(caller.Name); err == nil {
+			caller.Name = u.Username
+		}
+	case *tls.Conn:
+		if err := c.HandshakeContext(ctx); err != nil {
+			return caller, fmt.Errorf("identifyCaller failed: %w", err)
+		}
+
+		if certs := c.ConnectionState().PeerCertificates; len(certs) > 0 {
+			caller.CommonName = certs[0].Subject.CommonName
+			caller.Name = caller.CommonName
+		}
+	}
+
+	if len(allowlist) == 0 {
+		return caller, nil
+	}
+
+	for _, allowed := range allowlist {
+		if allowed.matches(caller) {
+			caller.Name = allowed.Name
+			return caller, nil
+		}
+	}
+
+	return caller, fmt.Errorf("identifyCaller failed: caller %s is not allowed", caller.Name)
+}
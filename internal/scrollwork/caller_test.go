@@ -0,0 +1,70 @@
+package scrollwork
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowedCaller(t *testing.T) {
+	t.Parallel()
+
+	allowed, err := ParseAllowedCaller("name=billing,uid=1001,gid=1002")
+	require.NoError(t, err)
+	require.Equal(t, "billing", allowed.Name)
+	require.Equal(t, 1001, *allowed.UID)
+	require.Equal(t, 1002, *allowed.GID)
+
+	allowed, err = ParseAllowedCaller("name=billing,cn=billing-service")
+	require.NoError(t, err)
+	require.Equal(t, "billing-service", allowed.CommonName)
+
+	for _, invalid := range []string{"", "uid=1001", "name=billing", "name=billing,uid=root", "name=billing,pid=1"} {
+		_, err := ParseAllowedCaller(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestIdentifyCaller_PeerCredentials(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("peer credentials are only supported on Linux")
+	}
+
+	path := filepath.Join(t.TempDir(), "scrollwork.sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	client, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer client.Close()
+
+	conn, err := listener.AcceptUnix()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	uid, gid, other := os.Getuid(), os.Getgid(), os.Getuid()+1
+
+	caller, err := identifyCaller(context.Background(), conn, nil)
+	require.NoError(t, err)
+	require.Equal(t, uid, *caller.UID)
+	require.Equal(t, gid, *caller.GID)
+	require.Equal(t, os.Getpid(), *caller.PID)
+
+	caller, err = identifyCaller(context.Background(), conn, []AllowedCaller{
+		{Name: "other", UID: &other},
+		{Name: "billing", UID: &uid, GID: &gid},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "billing", caller.Name)
+
+	_, err = identifyCaller(context.Background(), conn, []AllowedCaller{{Name: "other", UID: &other}})
+	require.ErrorContains(t, err, "not allowed")
+}
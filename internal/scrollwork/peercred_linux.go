@@ -0,0 +1,32 @@
+package scrollwork
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCredentials returns the SO_PEERCRED credentials of the process connected on conn.
+func peerCredentials(conn *net.UnixConn) (PeerCredentials, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return PeerCredentials{}, err
+	}
+
+	var ucred *syscall.Ucred
+	var ucredErr error
+	err = raw.Control(func(fd uintptr) {
+		ucred, ucredErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return PeerCredentials{}, err
+	}
+	if ucredErr != nil {
+		return PeerCredentials{}, ucredErr
+	}
+
+	return PeerCredentials{
+		UID: int(ucred.Uid),
+		GID: int(ucred.Gid),
+		PID: int(ucred.Pid),
+	}, nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package scrollwork
+
+import (
+	"net"
+)
+
+// peerCredentials is only supported on Linux.
+func peerCredentials(conn *net.UnixConn) (PeerCredentials, error) {
+	return PeerCredentials{}, errPeerCredentialsUnsupported
+}
@@ -0,0 +1,70 @@
+package scrollwork
+
+import (
+	"encoding/json"
+
+	"scrollwork/internal/llm"
+)
+
+// Clients talk to a Scrollwork Agent using newline delimited JSON. Every Request written to the socket is answered
+// with exactly one Response.
+type (
+	RequestType string
+
+	Request struct {
+		// ID identifies the request in logs and is echoed in its Response. One is generated when empty.
+		ID   string      `json:"id,omitempty"`
+		Type RequestType `json:"type"`
+
+		// Metadata carries request metadata such as the W3C traceparent and tracestate of the client's trace.
+		Metadata map[string]string `json:"metadata,omitempty"`
+
+		// System, Messages and Tools are the prompt to assess, in the shape of the Anthropic Messages API. Used by
+		// RequestTypeAssess.
+		System   llm.Content   `json:"system,omitempty"`
+		Messages []llm.Message `json:"messages,omitempty"`
+		Tools    []llm.Tool    `json:"tools,omitempty"`
+		// MaxTokens is the maximum number of output tokens the prompt may generate. Used by RequestTypeAssess.
+		MaxTokens int `json:"max_tokens,omitempty"`
+
+		// Body is the exact body of the request about to be sent upstream, an Anthropic Messages API request or an
+		// OpenAI Chat Completions request. It replaces System, Messages, Tools and MaxTokens, and only the model it
+		// names is assessed. Used by RequestTypeAssess.
+		Body json.RawMessage `json:"body,omitempty"`
+
+		// Usage is the usage of a completed LLM request. Used by RequestTypeReportUsage.
+		Usage *UsageReport `json:"usage,omitempty"`
+	}
+
+	// UsageReport is reported by clients once an LLM request completes.
+	UsageReport struct {
+		Model        string `json:"model"`
+		InputTokens  int    `json:"input_tokens"`
+		OutputTokens int    `json:"output_tokens"`
+
+		// CacheReadInputTokens and CacheCreationInputTokens are the prompt tokens read from and written to the
+		// provider's prompt cache. InputTokens does not include them.
+		CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+		CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+
+		// Headers are the response headers returned by the LLM provider. Rate limit headers are used to track the
+		// provider's rate limits.
+		Headers map[string]string `json:"headers,omitempty"`
+	}
+
+	Response struct {
+		ID          string       `json:"id,omitempty"`
+		Assessments []Assessment `json:"assessments,omitempty"`
+		Error       string       `json:"error,omitempty"`
+	}
+)
+
+const (
+	// RequestTypeAssess assesses the risk of a prompt.
+	RequestTypeAssess RequestType = "assess"
+	// RequestTypeReportUsage reports the usage of a completed LLM request.
+	RequestTypeReportUsage RequestType = "report_usage"
+
+	// maxRequestSize is the largest request accepted from a client.
+	maxRequestSize = 16 * 1024 * 1024
+)
@@ -0,0 +1,113 @@
+package scrollwork
+
+import (
+	"fmt"
+	"scrollwork/internal/llm"
+)
+
+// newProviders returns the provider of each configured model. Models served by the same provider share a client.
+func (a *Agent) newProviders() (map[string]llm.Provider, error) {
+	providers := make(map[string]llm.Provider, len(a.config.Models))
+
+	var anthropicClient *llm.AnthropicClient
+	var bedrockClient *llm.BedrockClient
+	var vertexClient *llm.VertexClient
+	var geminiClient *llm.GeminiClient
+	var localClient *llm.LocalClient
+	var azureClient *llm.AzureClient
+
+	for _, model := range a.config.Models {
+		parsed, err := llm.ParseModel(model)
+		if err != nil {
+			return nil, err
+		}
+
+		switch parsed.Platform {
+		case llm.PlatformBedrock:
+			if a.config.Bedrock == nil {
+				return nil, fmt.Errorf("Bedrock model %s requires Bedrock to be configured", model)
+			}
+
+			if bedrockClient == nil {
+				client, err := llm.NewBedrockClient(*a.config.Bedrock)
+				if err != nil {
+					return nil, err
+				}
+				bedrockClient = client
+			}
+			providers[model] = bedrockClient
+		case llm.PlatformVertex:
+			if a.config.Vertex == nil {
+				return nil, fmt.Errorf("Vertex model %s requires Vertex AI to be configured", model)
+			}
+
+			if vertexClient == nil {
+				client, err := llm.NewVertexClient(*a.config.Vertex)
+				if err != nil {
+					return nil, err
+				}
+				vertexClient = client
+			}
+			providers[model] = vertexClient
+		case llm.PlatformGemini:
+			if a.config.Gemini == nil {
+				return nil, fmt.Errorf("Gemini model %s requires Gemini to be configured", model)
+			}
+
+			if geminiClient == nil {
+				config := *a.config.Gemini
+				config.Ledger = a.ledger
+				client, err := llm.NewGeminiClient(config)
+				if err != nil {
+					return nil, err
+				}
+				geminiClient = client
+			}
+			providers[model] = geminiClient
+		case llm.PlatformLocal:
+			if a.config.Local == nil {
+				return nil, fmt.Errorf("self-hosted model %s requires a local inference server to be configured", model)
+			}
+
+			if localClient == nil {
+				config := *a.config.Local
+				config.Ledger = a.ledger
+				client, err := llm.NewLocalClient(config)
+				if err != nil {
+					return nil, err
+				}
+				localClient = client
+			}
+			providers[model] = localClient
+		case llm.PlatformAzure:
+			if a.config.Azure == nil {
+				return nil, fmt.Errorf("Azure OpenAI deployment %s requires Azure OpenAI to be configured", model)
+			}
+
+			if azureClient == nil {
+				client, err := llm.NewAzureClient(*a.config.Azure)
+				if err != nil {
+					return nil, err
+				}
+				azureClient = client
+			}
+			providers[model] = azureClient
+		case llm.PlatformAnthropic:
+			if a.config.APIKey == "" || a.config.AdminKey == "" {
+				return nil, fmt.Errorf("Anthropic model %s requires an API key and an admin key", model)
+			}
+
+			if anthropicClient == nil {
+				anthropicClient = llm.NewAnthropicClient(a.config.APIKey, a.config.AdminKey)
+			}
+			providers[model] = anthropicClient
+		// TODO: Remove this check once we have OpenAI integrated
+		case llm.PlatformOpenAI:
+			return nil, fmt.Errorf("OpenAI model %s is not supported at this time", model)
+		default:
+			return nil, fmt.Errorf("model %s is served by unsupported platform %s", model, parsed.Platform)
+		}
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,148 @@
+package scrollwork
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"os/user"
+	"scrollwork/internal/systemd"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+type (
+	// SocketConfig configures the Unix socket the agent listens on.
+	SocketConfig struct {
+		// Path is the path of the socket file. Defaults to /tmp/scrollwork.sock.
+		Path string
+		// Mode is the file mode of the socket file. Defaults to 0660.
+		Mode fs.FileMode
+		// Group is the name or id of the group that owns the socket file. The group is unchanged when empty.
+		Group string
+	}
+)
+
+const (
+	defaultSocketPath = "/tmp/scrollwork.sock"
+	defaultSocketMode = fs.FileMode(0o660)
+
+	// staleSocketDialTimeout is how long to wait for an existing agent to answer before its socket is considered stale.
+	staleSocketDialTimeout = time.Second
+)
+
+// listenUnix listens on the configured Unix socket, removing a stale socket left behind by an agent that crashed.
+//
+// When the agent is socket activated by systemd, the socket passed by systemd is used instead.
+func listenUnix(config SocketConfig, logger *slog.Logger) (*net.UnixListener, error) {
+	listeners, err := systemd.Listeners()
+	if err != nil {
+		return nil, fmt.Errorf("listenUnix failed: %w", err)
+	}
+
+	if len(listeners) > 0 {
+		for _, l := range listeners[1:] {
+			l.Close()
+		}
+
+		listener, ok := listeners[0].(*net.UnixListener)
+		if !ok {
+			listeners[0].Close()
+			return nil, fmt.Errorf("listenUnix failed: socket passed by systemd is not a Unix socket")
+		}
+
+		logger.Info("Scrollwork Agent is using the socket passed by systemd", "addr", listener.Addr().String())
+		return listener, nil
+	}
+
+	if config.Path == "" {
+		config.Path = defaultSocketPath
+	}
+
+	if config.Mode == 0 {
+		config.Mode = defaultSocketMode
+	}
+
+	gid := -1
+	if config.Group != "" {
+		var err error
+		gid, err = lookupGroup(config.Group)
+		if err != nil {
+			return nil, fmt.Errorf("listenUnix failed: %w", err)
+		}
+	}
+
+	if err := removeStaleSocket(config.Path); err != nil {
+		return nil, fmt.Errorf("listenUnix failed: %w", err)
+	}
+
+	// The socket is created with the process umask applied, so its mode and group are set before any connection is
+	// accepted. The umask is not changed, since it is shared with every goroutine creating files.
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: config.Path, Net: "unix"})
+	if err != nil {
+		return nil, fmt.Errorf("listenUnix failed: %w", err)
+	}
+
+	if err := os.Chmod(config.Path, config.Mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("listenUnix failed: could not set socket mode: %w", err)
+	}
+
+	if gid >= 0 {
+		if err := os.Chown(config.Path, -1, gid); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("listenUnix failed: could not set socket group: %w", err)
+		}
+	}
+
+	return listener, nil
+}
+
+// removeStaleSocket removes the socket at path if no agent is accepting connections on it.
+// It refuses to remove a socket owned by a live agent or a file that is not a socket.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if info.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout("unix", path, staleSocketDialTimeout)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another Scrollwork Agent", path)
+	}
+
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("could not determine whether %s is stale: %w", path, err)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("could not remove stale socket %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// lookupGroup returns the id of the group with the given name or id.
+func lookupGroup(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(g.Gid)
+}
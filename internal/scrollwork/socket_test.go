@@ -0,0 +1,83 @@
+package scrollwork
+
+import (
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenUnix(t *testing.T) {
+	t.Parallel()
+
+	tc := map[string]struct {
+		mode fs.FileMode
+	}{
+		"owner only":         {mode: 0o600},
+		"broader than umask": {mode: 0o666},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "scrollwork.sock")
+
+			listener, err := listenUnix(SocketConfig{Path: path, Mode: td.mode, Group: strconv.Itoa(os.Getgid())}, slog.Default())
+			require.NoError(t, err)
+			defer listener.Close()
+
+			info, err := os.Stat(path)
+			require.NoError(t, err)
+			require.Equal(t, fs.ModeSocket, info.Mode().Type())
+			require.Equal(t, td.mode, info.Mode().Perm())
+		})
+	}
+}
+
+func TestListenUnix_StaleSocket(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "scrollwork.sock")
+
+	// Simulate an agent that crashed without removing its socket
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+	stale.SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	listener, err := listenUnix(SocketConfig{Path: path}, slog.Default())
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+}
+
+func TestListenUnix_LiveSocket(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "scrollwork.sock")
+
+	live, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+	defer live.Close()
+
+	_, err = listenUnix(SocketConfig{Path: path}, slog.Default())
+	require.ErrorContains(t, err, "in use")
+}
+
+func TestListenUnix_NotASocket(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "scrollwork.sock")
+	require.NoError(t, os.WriteFile(path, []byte("not a socket"), 0o600))
+
+	_, err := listenUnix(SocketConfig{Path: path}, slog.Default())
+	require.ErrorContains(t, err, "not a socket")
+
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+}
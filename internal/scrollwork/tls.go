@@ -0,0 +1,100 @@
+package scrollwork
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+type (
+	// TCPConfig configures the optional TCP listener that serves remote clients over TLS.
+	TCPConfig struct {
+		// Addr is the address to listen on, such as ":7443". The TCP listener is disabled when empty.
+		Addr string
+
+		// CertFile and KeyFile are the PEM encoded certificate and private key presented to clients.
+		CertFile string
+		KeyFile  string
+
+		// ClientCAFile is a PEM encoded bundle of the certificate authorities that sign client certificates.
+		// When set, clients must present a certificate signed by one of them.
+		ClientCAFile string
+
+		// HandshakeTimeout bounds the TLS handshake of a new connection. Defaults to 10 seconds.
+		HandshakeTimeout time.Duration
+		// IdleTimeout closes connections that send no request for this long. Defaults to 5 minutes.
+		IdleTimeout time.Duration
+	}
+)
+
+const (
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultTLSIdleTimeout      = 5 * time.Minute
+)
+
+func (c TCPConfig) handshakeTimeout() time.Duration {
+	if c.HandshakeTimeout <= 0 {
+		return defaultTLSHandshakeTimeout
+	}
+
+	return c.HandshakeTimeout
+}
+
+func (c TCPConfig) idleTimeout() time.Duration {
+	if c.IdleTimeout <= 0 {
+		return defaultTLSIdleTimeout
+	}
+
+	return c.IdleTimeout
+}
+
+// listenTLS listens for TLS connections on the configured TCP address.
+func listenTLS(config TCPConfig) (net.Listener, error) {
+	tlsConfig, err := newServerTLSConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("listenTLS failed: %w", err)
+	}
+
+	listener, err := tls.Listen("tcp", config.Addr, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("listenTLS failed: %w", err)
+	}
+
+	return listener, nil
+}
+
+func newServerTLSConfig(config TCPConfig) (*tls.Config, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, fmt.Errorf("a certificate and key are required to listen on %s", config.Addr)
+	}
+
+	cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if config.ClientCAFile != "" {
+		pem, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read client CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client CA %s contains no certificates", config.ClientCAFile)
+		}
+
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
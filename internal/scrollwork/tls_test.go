@@ -0,0 +1,192 @@
+package scrollwork
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"scrollwork/internal/llm"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCertificate(t *testing.T, name string, parent *testCertificate, isCA bool) *testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCertificate{cert: cert, key: key}
+}
+
+func (c *testCertificate) write(t *testing.T, dir string, name string) (string, string) {
+	t.Helper()
+
+	certFile := filepath.Join(dir, name+".pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+
+	keyFile := filepath.Join(dir, name+"-key.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+
+	return certFile, keyFile
+}
+
+func (c *testCertificate) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+func TestListenTLS_MutualTLS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := newTestCertificate(t, "scrollwork-ca", nil, true)
+	server := newTestCertificate(t, "scrollwork", ca, false)
+	client := newTestCertificate(t, "billing-service", ca, false)
+	untrusted := newTestCertificate(t, "untrusted", nil, true)
+
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := server.write(t, dir, "server")
+
+	listener, err := listenTLS(TCPConfig{Addr: "127.0.0.1:0", CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+	require.NoError(t, err)
+	defer listener.Close()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte("ok"))
+			}()
+		}
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	dial := func(cert *testCertificate) error {
+		config := &tls.Config{RootCAs: roots}
+		if cert != nil {
+			config.Certificates = []tls.Certificate{cert.tlsCertificate()}
+		}
+
+		conn, err := tls.Dial("tcp", listener.Addr().String(), config)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		buf := make([]byte, 2)
+		_, err = conn.Read(buf)
+		return err
+	}
+
+	require.NoError(t, dial(client))
+	require.Error(t, dial(nil))
+	require.Error(t, dial(untrusted))
+}
+
+func TestListenTLS_MissingCertificate(t *testing.T) {
+	t.Parallel()
+
+	_, err := listenTLS(TCPConfig{Addr: "127.0.0.1:0"})
+	require.ErrorContains(t, err, "certificate and key are required")
+}
+
+func TestAgent_TLSTimeouts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	server := newTestCertificate(t, "scrollwork", nil, true)
+	certFile, keyFile := server.write(t, dir, "server")
+
+	agent := newTestAgent(t)
+	agent.config.Socket.Path = filepath.Join(dir, "scrollwork.sock")
+	agent.config.TCP = TCPConfig{
+		Addr:             "127.0.0.1:0",
+		CertFile:         certFile,
+		KeyFile:          keyFile,
+		HandshakeTimeout: 100 * time.Millisecond,
+		IdleTimeout:      200 * time.Millisecond,
+	}
+	agent.providers = map[string]llm.Provider{"claude-sonnet-4-20250514": &fakeProvider{name: "fake", tokens: 10}}
+	require.NoError(t, agent.Run(context.Background()))
+	t.Cleanup(func() { agent.Stop(context.Background()) })
+
+	addr := agent.listeners[len(agent.listeners)-1].Addr().String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.cert)
+
+	tests := map[string]struct {
+		dial func() (net.Conn, error)
+	}{
+		"no handshake": {
+			dial: func() (net.Conn, error) { return net.Dial("tcp", addr) },
+		},
+		"idle": {
+			dial: func() (net.Conn, error) { return tls.Dial("tcp", addr, &tls.Config{RootCAs: roots}) },
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			conn, err := tt.dial()
+			require.NoError(t, err)
+			defer conn.Close()
+
+			// The agent closes the connection well before the test's own deadline
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+			_, err = conn.Read(make([]byte, 1))
+			require.ErrorIs(t, err, io.EOF)
+		})
+	}
+}
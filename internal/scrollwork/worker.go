@@ -4,35 +4,122 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"scrollwork/internal/llm"
+	"scrollwork/internal/logging"
+	"scrollwork/internal/metrics"
+	"scrollwork/internal/retry"
+	"scrollwork/internal/systemd"
+	"scrollwork/internal/usage"
+	"slices"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
 	UsageWorkerConfig struct {
 		Models        []string
-		UsageReceived chan map[string]int
+		UsageReceived chan UsageSnapshot
 		WorkerReady   chan bool
 		TickRate      int
+		UsageWindow   usage.Window
+
+		// MaxFetchAttempts is the number of times usage is fetched on every tick before giving up. Defaults to 3.
+		MaxFetchAttempts int
+		// Backoff is the delay between fetch attempts. Defaults to retry.DefaultBackoff.
+		Backoff *retry.Backoff
+		// CircuitBreaker configures when repeated failures mark usage as stale.
+		CircuitBreaker retry.CircuitBreakerConfig
+
+		// WatchdogInterval is how often systemd expects a watchdog notification. Disabled when 0.
+		// The worker loop notifies the watchdog while it waits for its next fetch or retry, so a fetch that hangs for
+		// longer than the interval gets the agent restarted.
+		WatchdogInterval time.Duration
+
+		Metrics *metrics.Metrics
+
+		// Logger defaults to slog.Default().
+		Logger *slog.Logger
 
 		Client *llm.APIClient
 	}
 
+	// UsageSnapshot is the result of fetching an organization's usage.
+	UsageSnapshot struct {
+		Status UsageSnapshotStatus
+
+		// Tokens is the uncached input token usage of every configured model within the usage window, keyed by the
+		// model's canonical key. It is only set when fresh.
+		Tokens map[string]int
+
+		// Usage is the token usage by category of every configured model. It is only set when fresh.
+		Usage map[string]llm.TokenUsage
+
+		// FetchedAt is when the fetch completed, or was skipped.
+		FetchedAt time.Time
+
+		// Err is why a stale or failed snapshot could not be fetched.
+		Err error
+	}
+
+	UsageSnapshotStatus string
+
 	UsageWorker struct {
 		config *UsageWorkerConfig
 
-		ticker          *time.Ticker
-		AnthropicClient *llm.AnthropicClient
+		// tickInterval is how often usage is fetched, every TickRate minutes.
+		tickInterval time.Duration
+
+		stop     chan struct{}
+		stopOnce sync.Once
+		breaker  *retry.CircuitBreaker
+
+		// watchdog ticks twice per WatchdogInterval while Run is running. It is nil when the watchdog is disabled.
+		watchdog <-chan time.Time
+
+		// Providers is the provider of each configured model.
+		Providers map[string]llm.Provider
 	}
 )
 
+const (
+	// UsageSnapshotFresh is a successful fetch of usage for every configured model.
+	UsageSnapshotFresh UsageSnapshotStatus = "fresh"
+	// UsageSnapshotFailed is a failed fetch. The last fresh usage should be kept.
+	UsageSnapshotFailed UsageSnapshotStatus = "failed"
+	// UsageSnapshotStale is a failed or skipped fetch after the worker's circuit breaker has tripped.
+	// Usage is stale until the next fresh snapshot.
+	UsageSnapshotStale UsageSnapshotStatus = "stale"
+
+	defaultMaxFetchAttempts = 3
+)
+
+var errCircuitOpen = errors.New("usage fetch skipped: circuit breaker is open")
+
 // newUsageWorker creates a new [usageWorker].
 //
 // The usageWorker is responsible for fetching and storing the current token usage for a given organization.
 func newUsageWorker(config *UsageWorkerConfig) *UsageWorker {
+	if config.MaxFetchAttempts <= 0 {
+		config.MaxFetchAttempts = defaultMaxFetchAttempts
+	}
+
+	if config.Backoff == nil {
+		config.Backoff = &retry.DefaultBackoff
+	}
+
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
 	return &UsageWorker{
-		config: config,
+		config:       config,
+		tickInterval: time.Duration(config.TickRate) * time.Minute,
+		stop:         make(chan struct{}),
+		breaker:      retry.NewCircuitBreaker(config.CircuitBreaker),
 	}
 }
 
@@ -42,119 +129,245 @@ func (w *UsageWorker) Start(ctx context.Context) error {
 	}
 
 	// Fetch the latest usage snapshot for the organization
-	usage, err := w.fetchOrganizationUsage(ctx)
-	if err != nil {
-		return err
+	snapshot := w.fetchSnapshot(ctx)
+	if snapshot.Status != UsageSnapshotFresh {
+		return fmt.Errorf("Start failed: initial usage fetch was %s: %w", snapshot.Status, snapshot.Err)
 	}
 
-	w.config.UsageReceived <- usage
+	w.config.UsageReceived <- snapshot
 	w.config.WorkerReady <- true
 
 	return nil
 }
 
 func (w *UsageWorker) Run(ctx context.Context) {
-	ticker := time.NewTicker(time.Duration(w.config.TickRate) * time.Minute)
-	w.ticker = ticker
+	ticker := time.NewTicker(w.tickInterval)
+	defer ticker.Stop()
+
+	// Notify the watchdog twice per interval, so a single late notification does not restart the agent
+	if w.config.WatchdogInterval > 0 {
+		watchdog := time.NewTicker(w.config.WatchdogInterval / 2)
+		defer watchdog.Stop()
+		w.watchdog = watchdog.C
+	}
 
 	for {
 		select {
-		case <-w.ticker.C:
-			log.Printf("Scrollwork Usage Worker is fetching latest usage...")
-			usage, err := w.fetchOrganizationUsage(ctx)
-			if err != nil {
-				log.Printf("Scrollwork Usager Worker failed to fetch latest usage: %v", err)
-				break
+		case <-w.watchdog:
+			w.notifyWatchdog()
+		case <-ticker.C:
+			w.config.Logger.Debug("Scrollwork Usage Worker is fetching latest usage")
+			snapshot := w.fetchSnapshot(ctx)
+
+			switch snapshot.Status {
+			case UsageSnapshotFresh:
+				w.config.Logger.Info("Scrollwork Usage Worker has received the latest usage")
+			case UsageSnapshotStale:
+				w.config.Logger.Error("Scrollwork Usage Worker circuit breaker has tripped, usage is now stale", logging.Err(snapshot.Err))
+			default:
+				w.config.Logger.Warn("Scrollwork Usage Worker failed to fetch latest usage", logging.Err(snapshot.Err))
 			}
 
-			w.config.UsageReceived <- usage
-			log.Printf("Scrollwork Usage Worker has received the latest usage")
+			select {
+			case w.config.UsageReceived <- snapshot:
+			case <-w.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		case <-w.stop:
+			return
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
+// notifyWatchdog tells systemd the worker loop is making progress.
+func (w *UsageWorker) notifyWatchdog() {
+	if _, err := systemd.Notify(systemd.Watchdog); err != nil {
+		w.config.Logger.Warn("Scrollwork Usage Worker failed to notify systemd watchdog", logging.Err(err))
+	}
+}
+
+// Stop stops the worker once any in-progress fetch has finished. It is safe to call more than once.
 func (w *UsageWorker) Stop() {
-	if w.ticker != nil {
-		w.ticker.Stop()
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
+
+// Stale reports whether the usage last received from the worker is stale because its circuit breaker has tripped.
+func (w *UsageWorker) Stale() bool {
+	return w.breaker.State() != retry.CircuitClosed
+}
+
+// fetchSnapshot fetches the organization's usage unless the circuit breaker is open.
+func (w *UsageWorker) fetchSnapshot(ctx context.Context) UsageSnapshot {
+	if !w.breaker.Allow(time.Now()) {
+		return UsageSnapshot{Status: UsageSnapshotStale, FetchedAt: time.Now(), Err: errCircuitOpen}
+	}
+
+	usage, err := w.fetchOrganizationUsageWithRetry(ctx)
+	fetchedAt := time.Now()
+	if err != nil {
+		w.breaker.RecordFailure(fetchedAt)
+
+		status := UsageSnapshotFailed
+		if w.Stale() {
+			status = UsageSnapshotStale
+		}
+
+		return UsageSnapshot{Status: status, FetchedAt: fetchedAt, Err: err}
+	}
+
+	w.breaker.RecordSuccess()
+
+	tokens := make(map[string]int, len(usage))
+	for model, u := range usage {
+		tokens[model] = u.UncachedInput
 	}
 
-	w.config.WorkerReady <- false
+	return UsageSnapshot{Status: UsageSnapshotFresh, Tokens: tokens, Usage: usage, FetchedAt: fetchedAt}
 }
 
-func (w *UsageWorker) fetchOrganizationUsage(ctx context.Context) (map[string]int, error) {
-	usage := make(map[string]int)
+// fetchOrganizationUsageWithRetry fetches usage up to MaxFetchAttempts times, waiting with exponential backoff and
+// jitter between attempts. A provider's Retry-After is honored when it rate limits the worker, up to the Backoff's Max.
+func (w *UsageWorker) fetchOrganizationUsageWithRetry(ctx context.Context) (map[string]llm.TokenUsage, error) {
+	for attempt := 0; ; attempt++ {
+		usage, err := w.fetchOrganizationUsage(ctx)
+		if err == nil {
+			return usage, nil
+		}
 
-	// Check if we have any Anthropic models
-	hasAnthropicModel := false
-	for _, model := range w.config.Models {
-		if llm.IsAnthropicModel(model) {
-			hasAnthropicModel = true
-			break
+		if attempt+1 >= w.config.MaxFetchAttempts || ctx.Err() != nil {
+			return usage, err
+		}
+
+		delay := w.config.Backoff.Delay(attempt)
+		if retryAfter, ok := llm.RetryAfter(err); ok {
+			delay = retryAfter
+			if w.config.Backoff.Max > 0 {
+				delay = min(delay, w.config.Backoff.Max)
+			}
+		}
+
+		w.config.Logger.Warn("Scrollwork Usage Worker failed to fetch usage, retrying", "attempt", attempt+1, "delay", delay, logging.Err(err))
+
+		if !w.wait(ctx, delay) {
+			return usage, err
 		}
 	}
+}
 
-	// Fetch Anthropic usage once for all Anthropic models
-	if hasAnthropicModel {
-		if w.AnthropicClient == nil {
-			return usage, fmt.Errorf("fetchOrganizationUsage failed: AnthropicClient not configured")
+// wait waits for delay, notifying the watchdog meanwhile. It returns false if ctx is done first.
+func (w *UsageWorker) wait(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.watchdog:
+			w.notifyWatchdog()
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
 		}
+	}
+}
 
-		anthropicUsage, err := w.AnthropicClient.GetOrganizationMessageUsageReport(ctx)
+func (w *UsageWorker) fetchOrganizationUsage(ctx context.Context) (usage map[string]llm.TokenUsage, err error) {
+	ctx, span := tracer.Start(ctx, "UsageWorker.fetchOrganizationUsage")
+	defer func() {
 		if err != nil {
-			if errors.Is(err, context.DeadlineExceeded) {
-				return make(map[string]int), nil
-			}
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
 
-			return make(map[string]int), fmt.Errorf("Failed to fetchOrganizationUsage: %v", err)
+	usage = make(map[string]llm.TokenUsage)
+
+	providers, err := w.providers()
+	if err != nil {
+		return usage, fmt.Errorf("fetchOrganizationUsage failed: %w", err)
+	}
+
+	// Fetch usage once per provider for all of its models
+	startingAt, endingAt := w.config.UsageWindow.Bounds(time.Now())
+	for _, p := range providers {
+		fetchCtx, fetchSpan := tracer.Start(ctx, p.provider.Name()+".Usage", trace.WithSpanKind(trace.SpanKindClient))
+		fetchStartedAt := time.Now()
+		providerUsage, err := p.provider.Usage(fetchCtx, p.models, startingAt, endingAt)
+		w.config.Metrics.ObserveUsageFetch(p.provider.Name(), time.Since(fetchStartedAt), err)
+		if err != nil {
+			fetchSpan.RecordError(err)
+			fetchSpan.SetStatus(codes.Error, err.Error())
+		}
+		fetchSpan.End()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to fetchOrganizationUsage: %w", err)
 		}
 
-		// Copy Anthropic usage for configured models
-		for _, model := range w.config.Models {
-			if llm.IsAnthropicModel(model) {
-				if tokens, ok := anthropicUsage[model]; ok {
-					usage[model] = tokens
-				}
+		// Sum usage by model key, so a model served by several providers, or configured by an alias, is counted once.
+		// Models without usage are not part of the report.
+		keys := make(map[string]bool, len(p.models))
+		for _, model := range p.models {
+			key := llm.ModelKey(model)
+			keys[key] = true
+			if _, ok := usage[key]; !ok {
+				usage[key] = llm.TokenUsage{}
 			}
 		}
-	}
+		for model, u := range providerUsage {
+			key := llm.ModelKey(model)
+			if !keys[key] {
+				continue
+			}
 
-	// TODO: Add OpenAI support
-	for _, model := range w.config.Models {
-		if llm.IsOpenAIModel(model) {
-			return usage, fmt.Errorf("fetchOrganizationUsage failed: OpenAI model %s is not supported", model)
+			usage[key] = usage[key].Add(u)
 		}
 	}
 
 	return usage, nil
 }
 
-func (w *UsageWorker) healthCheck(ctx context.Context) error {
-	// Check if we have any Anthropic models
-	hasAnthropicModel := false
+// providerModels are the configured models served by a provider.
+type providerModels struct {
+	provider llm.Provider
+	models   []string
+}
+
+// providers groups the configured models by provider, in the order they were configured.
+func (w *UsageWorker) providers() ([]providerModels, error) {
+	var providers []providerModels
 	for _, model := range w.config.Models {
-		if llm.IsAnthropicModel(model) {
-			hasAnthropicModel = true
-			break
+		provider, ok := w.Providers[model]
+		if !ok {
+			return nil, fmt.Errorf("no provider was configured for model %s", model)
 		}
-	}
 
-	// Health check Anthropic client if needed
-	if hasAnthropicModel {
-		if w.AnthropicClient == nil {
-			return fmt.Errorf("healthCheck failed: AnthropicClient was not configured")
+		i := slices.IndexFunc(providers, func(p providerModels) bool { return p.provider == provider })
+		if i < 0 {
+			providers = append(providers, providerModels{provider: provider})
+			i = len(providers) - 1
 		}
+		providers[i].models = append(providers[i].models, model)
+	}
 
-		if err := w.AnthropicClient.HealthCheck(ctx); err != nil {
-			return fmt.Errorf("healthCheck failed: %v", err)
-		}
+	return providers, nil
+}
+
+func (w *UsageWorker) healthCheck(ctx context.Context) error {
+	providers, err := w.providers()
+	if err != nil {
+		return fmt.Errorf("healthCheck failed: %w", err)
 	}
 
-	// Check for unsupported models
-	for _, model := range w.config.Models {
-		if llm.IsOpenAIModel(model) {
-			return fmt.Errorf("healthCheck failed: LLM model %s is not supported", model)
+	for _, p := range providers {
+		if err := p.provider.HealthCheck(ctx); err != nil {
+			return fmt.Errorf("healthCheck failed: %s: %w", p.provider.Name(), err)
 		}
 	}
 
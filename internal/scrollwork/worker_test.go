@@ -0,0 +1,274 @@
+package scrollwork
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"scrollwork/internal/llm"
+	"scrollwork/internal/retry"
+)
+
+// fakeProvider is an llm.Provider that returns canned results.
+type fakeProvider struct {
+	name string
+
+	// healthErr is returned by every health check.
+	healthErr error
+
+	// tokens is the token count of every prompt unless countErr is set.
+	tokens   int
+	countErr error
+
+	// usage is called for every usage fetch. It returns no usage when nil.
+	usage func(ctx context.Context, models []string) (map[string]llm.TokenUsage, error)
+
+	mu         sync.Mutex
+	usageCalls int
+	prompts    []llm.Prompt
+}
+
+var _ llm.Provider = (*fakeProvider)(nil)
+
+func (f *fakeProvider) Name() string {
+	return f.name
+}
+
+func (f *fakeProvider) HealthCheck(ctx context.Context) error {
+	return f.healthErr
+}
+
+func (f *fakeProvider) CountTokens(ctx context.Context, model string, prompt llm.Prompt) (int, error) {
+	f.mu.Lock()
+	f.prompts = append(f.prompts, prompt)
+	f.mu.Unlock()
+
+	return f.tokens, f.countErr
+}
+
+func (f *fakeProvider) Usage(ctx context.Context, models []string, startingAt time.Time, endingAt time.Time) (map[string]llm.TokenUsage, error) {
+	f.mu.Lock()
+	f.usageCalls++
+	f.mu.Unlock()
+
+	if f.usage == nil {
+		return map[string]llm.TokenUsage{}, nil
+	}
+
+	return f.usage(ctx, models)
+}
+
+func (f *fakeProvider) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.usageCalls
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// newTestWorker returns a worker of a single model served by provider.
+func newTestWorker(t *testing.T, provider llm.Provider, config UsageWorkerConfig) *UsageWorker {
+	t.Helper()
+
+	config.Models = []string{"claude-sonnet-4-20250514"}
+	config.UsageReceived = make(chan UsageSnapshot, 16)
+	config.WorkerReady = make(chan bool, 1)
+	config.TickRate = 1
+	config.Logger = discardLogger()
+
+	w := newUsageWorker(&config)
+	w.Providers = map[string]llm.Provider{"claude-sonnet-4-20250514": provider}
+
+	return w
+}
+
+func TestUsageWorker_Watchdog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	// Every fetch hangs until the test ends
+	fetching := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	provider := &fakeProvider{name: "fake", usage: func(ctx context.Context, models []string) (map[string]llm.TokenUsage, error) {
+		select {
+		case fetching <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil, nil
+	}}
+
+	w := newTestWorker(t, provider, UsageWorkerConfig{WatchdogInterval: 40 * time.Millisecond})
+	w.tickInterval = 200 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+	defer w.Stop()
+
+	// The watchdog is notified while the worker waits for its next fetch
+	buf := make([]byte, 64)
+	for range 3 {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, "WATCHDOG=1", string(buf[:n]))
+	}
+
+	<-fetching
+
+	// Notifications sent before the fetch may still be queued, but none follow while it hangs
+	for range 20 {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+		if _, err = conn.Read(buf); err != nil {
+			break
+		}
+	}
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
+
+func TestUsageWorker_Start(t *testing.T) {
+	t.Parallel()
+
+	unavailable := errors.New("unavailable")
+
+	tc := map[string]struct {
+		err      error
+		breaker  retry.CircuitBreakerConfig
+		expected string
+	}{
+		"fresh":  {},
+		"failed": {err: unavailable, expected: "initial usage fetch was failed"},
+		"stale":  {err: unavailable, breaker: retry.CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute}, expected: "initial usage fetch was stale"},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			provider := &fakeProvider{name: "fake", usage: func(ctx context.Context, models []string) (map[string]llm.TokenUsage, error) {
+				return map[string]llm.TokenUsage{"claude-sonnet-4-20250514": {UncachedInput: 100}}, td.err
+			}}
+			w := newTestWorker(t, provider, UsageWorkerConfig{MaxFetchAttempts: 1, CircuitBreaker: td.breaker})
+
+			err := w.Start(context.Background())
+			if td.expected == "" {
+				require.NoError(t, err)
+				require.Equal(t, UsageSnapshotFresh, (<-w.config.UsageReceived).Status)
+				require.True(t, <-w.config.WorkerReady)
+				return
+			}
+
+			// The worker is not ready and no usage is sent when the first fetch is not fresh
+			require.ErrorContains(t, err, td.expected)
+			require.ErrorIs(t, err, unavailable)
+			require.Empty(t, w.config.UsageReceived)
+			require.Empty(t, w.config.WorkerReady)
+		})
+	}
+}
+
+func TestUsageWorker_FetchSnapshot_Retry(t *testing.T) {
+	t.Parallel()
+
+	rateLimited := &llm.HTTPError{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"3600"}}}
+
+	tc := map[string]struct {
+		failures int
+		err      error
+		expected UsageSnapshotStatus
+		calls    int
+	}{
+		"first attempt":      {failures: 0, expected: UsageSnapshotFresh, calls: 1},
+		"retried":            {failures: 2, err: errors.New("unavailable"), expected: UsageSnapshotFresh, calls: 3},
+		"out of attempts":    {failures: 3, err: errors.New("unavailable"), expected: UsageSnapshotFailed, calls: 3},
+		"retry after capped": {failures: 1, err: rateLimited, expected: UsageSnapshotFresh, calls: 2},
+	}
+
+	for name, td := range tc {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			provider := &fakeProvider{name: "fake"}
+			provider.usage = func(ctx context.Context, models []string) (map[string]llm.TokenUsage, error) {
+				if provider.calls() <= td.failures {
+					return nil, td.err
+				}
+				return map[string]llm.TokenUsage{"claude-sonnet-4-20250514": {UncachedInput: 100}}, nil
+			}
+
+			w := newTestWorker(t, provider, UsageWorkerConfig{
+				MaxFetchAttempts: 3,
+				Backoff:          &retry.Backoff{Initial: time.Millisecond, Max: 10 * time.Millisecond, Multiplier: 2},
+			})
+
+			// An hour long Retry-After would time the test out
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			snapshot := w.fetchSnapshot(ctx)
+			require.Equal(t, td.expected, snapshot.Status)
+			require.Equal(t, td.calls, provider.calls())
+			if td.expected == UsageSnapshotFresh {
+				require.Equal(t, map[string]int{"claude-sonnet-4-20250514": 100}, snapshot.Tokens)
+			} else {
+				require.ErrorIs(t, snapshot.Err, td.err)
+			}
+		})
+	}
+}
+
+func TestUsageWorker_FetchSnapshot_CircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	failing := true
+	provider := &fakeProvider{name: "fake", usage: func(ctx context.Context, models []string) (map[string]llm.TokenUsage, error) {
+		if failing {
+			return nil, errors.New("unavailable")
+		}
+		return map[string]llm.TokenUsage{}, nil
+	}}
+
+	w := newTestWorker(t, provider, UsageWorkerConfig{
+		MaxFetchAttempts: 1,
+		CircuitBreaker:   retry.CircuitBreakerConfig{FailureThreshold: 2, Cooldown: 50 * time.Millisecond},
+	})
+	ctx := context.Background()
+
+	require.Equal(t, UsageSnapshotFailed, w.fetchSnapshot(ctx).Status)
+	require.False(t, w.Stale())
+
+	// The second failure trips the breaker and usage is stale
+	require.Equal(t, UsageSnapshotStale, w.fetchSnapshot(ctx).Status)
+	require.True(t, w.Stale())
+
+	// Fetches are skipped while the breaker is open
+	snapshot := w.fetchSnapshot(ctx)
+	require.Equal(t, UsageSnapshotStale, snapshot.Status)
+	require.ErrorIs(t, snapshot.Err, errCircuitOpen)
+	require.Equal(t, 2, provider.calls())
+
+	// A successful trial fetch once the cooldown elapses closes it again
+	time.Sleep(50 * time.Millisecond)
+	failing = false
+	require.Equal(t, UsageSnapshotFresh, w.fetchSnapshot(ctx).Status)
+	require.False(t, w.Stale())
+	require.Equal(t, 3, provider.calls())
+}
@@ -0,0 +1,109 @@
+// Package systemd implements the parts of the systemd socket activation and service notification protocols used by
+// the Scrollwork Agent.
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Ready tells systemd the service has finished starting up.
+	Ready = "READY=1"
+	// Stopping tells systemd the service is shutting down.
+	Stopping = "STOPPING=1"
+	// Watchdog resets the systemd watchdog timer.
+	Watchdog = "WATCHDOG=1"
+
+	// listenFDsStart is the first file descriptor passed by systemd.
+	listenFDsStart = 3
+)
+
+// Status returns a STATUS= notification with the given message.
+func Status(message string) string {
+	return "STATUS=" + strings.ReplaceAll(message, "\n", " ")
+}
+
+// Listeners returns the listeners passed to the process through systemd socket activation.
+// It returns no listeners when the process was not socket activated.
+//
+// The LISTEN_* environment variables are unset so that they are not inherited by child processes.
+func Listeners() ([]net.Listener, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return nil, nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	listeners := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("LISTEN_FD_%d", listenFDsStart+i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		listener, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("Listeners failed: file descriptor %s is not a listening socket: %w", name, err)
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+// Notify sends state to the systemd service manager. It returns false without an error when the process is not
+// supervised by systemd.
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("Notify failed: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("Notify failed: %w", err)
+	}
+
+	return true, nil
+}
+
+// WatchdogInterval returns how often systemd expects Watchdog notifications. It returns false when the watchdog is
+// not enabled for the process.
+func WatchdogInterval() (time.Duration, bool) {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(usec) * time.Microsecond, true
+}
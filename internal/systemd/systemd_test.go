@@ -0,0 +1,70 @@
+package systemd_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"scrollwork/internal/systemd"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	ok, err := systemd.Notify(systemd.Ready)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = systemd.Notify(systemd.Status("Usage synced\nat noon"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "READY=1", string(buf[:n]))
+
+	n, err = conn.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, "STATUS=Usage synced at noon", string(buf[:n]))
+}
+
+func TestNotify_NotSupervised(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+
+	ok, err := systemd.Notify(systemd.Ready)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+	interval, ok := systemd.WatchdogInterval()
+	require.True(t, ok)
+	require.Equal(t, 30*time.Second, interval)
+
+	t.Setenv("WATCHDOG_PID", "1")
+	_, ok = systemd.WatchdogInterval()
+	require.False(t, ok)
+}
+
+func TestListeners_NotActivated(t *testing.T) {
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+
+	listeners, err := systemd.Listeners()
+	require.NoError(t, err)
+	require.Empty(t, listeners)
+	require.Empty(t, os.Getenv("LISTEN_FDS"))
+}
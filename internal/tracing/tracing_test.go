@@ -0,0 +1,58 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"scrollwork/internal/tracing"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExtract(t *testing.T) {
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{ServiceName: "scrollwork"})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+
+	ctx := tracing.Extract(context.Background(), map[string]string{
+		"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+
+	sc := trace.SpanContextFromContext(ctx)
+	require.True(t, sc.IsValid())
+	require.True(t, sc.IsRemote())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+
+	require.Equal(t, context.Background(), tracing.Extract(context.Background(), nil))
+}
+
+func TestSetup_Export(t *testing.T) {
+	var exported atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			exported.Add(1)
+		}
+	}))
+	defer collector.Close()
+
+	u, err := url.Parse(collector.URL)
+	require.NoError(t, err)
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{
+		Endpoint:    u.Host,
+		Insecure:    true,
+		ServiceName: "scrollwork",
+	})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("scrollwork/internal/tracing_test").Start(context.Background(), "test")
+	span.End()
+
+	require.NoError(t, shutdown(context.Background()))
+	require.Equal(t, int32(1), exported.Load())
+}
@@ -0,0 +1,150 @@
+package usage
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	ForecasterConfig struct {
+		// Window is the budget window usage snapshots are reported for.
+		Window Window
+
+		// QuotaTokens is the token budget for the Window. Forecasts never exceed a quota of 0.
+		QuotaTokens int
+
+		// Lookback is how long snapshots are kept when computing the burn rate. Defaults to 1 hour.
+		Lookback time.Duration
+	}
+
+	// Forecaster computes burn rates and quota exhaustion forecasts from periodic usage snapshots.
+	Forecaster struct {
+		config ForecasterConfig
+
+		samples map[string][]sample
+		mu      sync.Mutex
+	}
+
+	// Forecast is the projected usage of a model by the end of the current window.
+	Forecast struct {
+		Window          Window     `json:"window"`
+		WindowEndsAt    time.Time  `json:"window_ends_at"`
+		QuotaTokens     int        `json:"quota_tokens"`
+		UsedTokens      int        `json:"used_tokens"`
+		TokensPerMinute float64    `json:"tokens_per_minute"`
+		ProjectedTokens int        `json:"projected_tokens"`
+		ExhaustsAt      *time.Time `json:"exhausts_at,omitempty"`
+		OnTrackToExceed bool       `json:"on_track_to_exceed"`
+	}
+
+	sample struct {
+		at     time.Time
+		tokens int
+	}
+)
+
+const defaultForecastLookback = time.Hour
+
+// NewForecaster returns a Forecaster.
+func NewForecaster(config ForecasterConfig) *Forecaster {
+	if config.Window == "" {
+		config.Window = WindowDaily
+	}
+
+	if config.Lookback <= 0 {
+		config.Lookback = defaultForecastLookback
+	}
+
+	return &Forecaster{
+		config:  config,
+		samples: make(map[string][]sample),
+	}
+}
+
+// Observe records the cumulative tokens used by model within the current window at the given time.
+//
+// Snapshots from a previous window, or a drop in usage, discard the samples collected so far.
+func (f *Forecaster) Observe(model string, at time.Time, tokens int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	windowStart, _ := f.config.Window.Bounds(at)
+	cutoff := at.Add(-f.config.Lookback)
+
+	var kept []sample
+	for _, s := range f.samples[model] {
+		if s.at.Before(windowStart) || s.at.Before(cutoff) || s.tokens > tokens {
+			continue
+		}
+		kept = append(kept, s)
+	}
+
+	f.samples[model] = append(kept, sample{at: at, tokens: tokens})
+}
+
+// Forecast returns the forecast for model. It returns false if no usage has been observed for model.
+//
+// The burn rate is computed from the snapshots within the lookback period. With a single snapshot, the average
+// rate since the start of the window is used instead.
+func (f *Forecaster) Forecast(model string) (Forecast, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	samples := f.samples[model]
+	if len(samples) == 0 {
+		return Forecast{}, false
+	}
+
+	first := samples[0]
+	last := samples[len(samples)-1]
+	windowStart, windowEnd := f.config.Window.Bounds(last.at)
+
+	var rate float64
+	if elapsed := last.at.Sub(first.at).Minutes(); len(samples) > 1 && elapsed > 0 {
+		rate = float64(last.tokens-first.tokens) / elapsed
+	} else if elapsed := last.at.Sub(windowStart).Minutes(); elapsed > 0 {
+		rate = float64(last.tokens) / elapsed
+	}
+
+	forecast := Forecast{
+		Window:          f.config.Window,
+		WindowEndsAt:    windowEnd,
+		QuotaTokens:     f.config.QuotaTokens,
+		UsedTokens:      last.tokens,
+		TokensPerMinute: rate,
+		ProjectedTokens: last.tokens + int(math.Round(rate*windowEnd.Sub(last.at).Minutes())),
+	}
+
+	if f.config.QuotaTokens <= 0 {
+		return forecast, true
+	}
+
+	switch {
+	case last.tokens >= f.config.QuotaTokens:
+		exhaustsAt := last.at
+		forecast.ExhaustsAt = &exhaustsAt
+	case rate > 0:
+		remaining := float64(f.config.QuotaTokens - last.tokens)
+		exhaustsAt := last.at.Add(time.Duration(remaining / rate * float64(time.Minute)))
+		forecast.ExhaustsAt = &exhaustsAt
+	}
+
+	forecast.OnTrackToExceed = forecast.ExhaustsAt != nil && forecast.ExhaustsAt.Before(windowEnd)
+
+	return forecast, true
+}
+
+// Elevate raises level to RiskLevelHigh when the quota is forecast to run out before the window ends.
+func (f Forecast) Elevate(level RiskLevel) RiskLevel {
+	if !f.OnTrackToExceed {
+		return level
+	}
+
+	switch level {
+	case RiskLevelLow, RiskLevelMedium:
+		return RiskLevelHigh
+	default:
+		return level
+	}
+}
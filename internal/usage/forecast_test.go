@@ -0,0 +1,142 @@
+package usage_test
+
+import (
+	"scrollwork/internal/usage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestForecast(t *testing.T) {
+	t.Parallel()
+
+	windowStart := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		Name            string
+		QuotaTokens     int
+		Samples         []int
+		ExpectedRate    float64
+		ExpectedExhaust bool
+		ExpectedExceed  bool
+	}{
+		{
+			Name:         "no quota",
+			Samples:      []int{100, 200},
+			ExpectedRate: 10,
+		},
+		{
+			Name:            "on track",
+			QuotaTokens:     1_000_000,
+			Samples:         []int{1000, 1100, 1200},
+			ExpectedRate:    10,
+			ExpectedExhaust: true,
+		},
+		{
+			Name:            "on track to exceed",
+			QuotaTokens:     10_000,
+			Samples:         []int{1000, 2000, 3000},
+			ExpectedRate:    100,
+			ExpectedExhaust: true,
+			ExpectedExceed:  true,
+		},
+		{
+			Name:            "quota exhausted",
+			QuotaTokens:     1000,
+			Samples:         []int{1000, 1000},
+			ExpectedRate:    0,
+			ExpectedExhaust: true,
+			ExpectedExceed:  true,
+		},
+		{
+			Name:         "idle",
+			QuotaTokens:  1000,
+			Samples:      []int{500, 500},
+			ExpectedRate: 0,
+		},
+	}
+
+	for _, td := range tt {
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+
+			f := usage.NewForecaster(usage.ForecasterConfig{Window: usage.WindowDaily, QuotaTokens: td.QuotaTokens})
+
+			at := windowStart.Add(12 * time.Hour)
+			for _, tokens := range td.Samples {
+				f.Observe("claude-sonnet-4-20250514", at, tokens)
+				at = at.Add(10 * time.Minute)
+			}
+
+			forecast, ok := f.Forecast("claude-sonnet-4-20250514")
+			require.True(t, ok)
+			require.InDelta(t, td.ExpectedRate, forecast.TokensPerMinute, 0.001)
+			require.Equal(t, td.ExpectedExhaust, forecast.ExhaustsAt != nil)
+			require.Equal(t, td.ExpectedExceed, forecast.OnTrackToExceed)
+			require.Equal(t, windowStart.AddDate(0, 0, 1), forecast.WindowEndsAt)
+		})
+	}
+}
+
+func TestForecast_SingleSample(t *testing.T) {
+	t.Parallel()
+
+	f := usage.NewForecaster(usage.ForecasterConfig{Window: usage.WindowDaily, QuotaTokens: 2000})
+	f.Observe("claude-sonnet-4-20250514", time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC), 1200)
+
+	forecast, ok := f.Forecast("claude-sonnet-4-20250514")
+	require.True(t, ok)
+	require.InDelta(t, 1200.0/720, forecast.TokensPerMinute, 0.001)
+	require.Equal(t, 2400, forecast.ProjectedTokens)
+	require.True(t, forecast.OnTrackToExceed)
+}
+
+func TestForecast_WindowRollover(t *testing.T) {
+	t.Parallel()
+
+	f := usage.NewForecaster(usage.ForecasterConfig{Window: usage.WindowDaily, QuotaTokens: 1_000_000})
+	f.Observe("claude-sonnet-4-20250514", time.Date(2025, 9, 1, 23, 50, 0, 0, time.UTC), 90_000)
+	f.Observe("claude-sonnet-4-20250514", time.Date(2025, 9, 2, 0, 10, 0, 0, time.UTC), 100)
+
+	forecast, ok := f.Forecast("claude-sonnet-4-20250514")
+	require.True(t, ok)
+	require.Equal(t, 100, forecast.UsedTokens)
+	require.InDelta(t, 10, forecast.TokensPerMinute, 0.001)
+	require.False(t, forecast.OnTrackToExceed)
+}
+
+func TestForecast_Unknown(t *testing.T) {
+	t.Parallel()
+
+	f := usage.NewForecaster(usage.ForecasterConfig{})
+	_, ok := f.Forecast("claude-sonnet-4-20250514")
+	require.False(t, ok)
+}
+
+func TestForecastElevate(t *testing.T) {
+	t.Parallel()
+
+	exceed := usage.Forecast{OnTrackToExceed: true}
+	require.Equal(t, usage.RiskLevelHigh, exceed.Elevate(usage.RiskLevelLow))
+	require.Equal(t, usage.RiskLevelHigh, exceed.Elevate(usage.RiskLevelMedium))
+	require.Equal(t, usage.RiskLevelBlock, exceed.Elevate(usage.RiskLevelBlock))
+	require.Equal(t, usage.RiskLevelUnknown, exceed.Elevate(usage.RiskLevelUnknown))
+
+	onTrack := usage.Forecast{}
+	require.Equal(t, usage.RiskLevelLow, onTrack.Elevate(usage.RiskLevelLow))
+}
+
+func TestWindowBounds(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 12, 31, 15, 4, 5, 0, time.UTC)
+
+	start, end := usage.WindowDaily.Bounds(now)
+	require.Equal(t, time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), end)
+
+	start, end = usage.WindowMonthly.Bounds(now)
+	require.Equal(t, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), start)
+	require.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), end)
+}
@@ -0,0 +1,301 @@
+package usage
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// RateLimitKind is a per-minute limit enforced by an LLM provider.
+	RateLimitKind string
+
+	// RateLimitState is the state of a provider rate limit as reported by response headers.
+	RateLimitState struct {
+		Limit     int       `json:"limit"`
+		Remaining int       `json:"remaining"`
+		ResetAt   time.Time `json:"reset_at"`
+	}
+
+	RateLimitTrackerConfig struct {
+		// Limits are the per-minute limits used for every model until a provider reports them through response headers.
+		Limits map[RateLimitKind]int
+
+		// WarnRatio is the fraction of a limit past which a prompt is considered medium risk. Defaults to 0.8.
+		WarnRatio float64
+	}
+
+	// RateLimitTracker tracks requests and tokens per minute for each model along with the rate limits reported by providers.
+	RateLimitTracker struct {
+		config RateLimitTrackerConfig
+
+		models map[string]*modelRateLimits
+		mu     sync.Mutex
+	}
+
+	// RateLimitAssessment is the rate limit risk of sending a prompt to a model.
+	RateLimitAssessment struct {
+		RiskLevel RiskLevel `json:"risk_level"`
+
+		// PerMinute is the usage of each limit within the last minute.
+		PerMinute map[RateLimitKind]int `json:"per_minute"`
+
+		// Limits are the known limits for the model.
+		Limits map[RateLimitKind]int `json:"limits,omitempty"`
+
+		// Exceeded lists the limits the prompt would likely hit.
+		Exceeded []RateLimitKind `json:"exceeded,omitempty"`
+	}
+
+	modelRateLimits struct {
+		events []rateLimitEvent
+		states map[RateLimitKind]observedRateLimitState
+	}
+
+	rateLimitEvent struct {
+		at      time.Time
+		amounts map[RateLimitKind]int
+	}
+
+	observedRateLimitState struct {
+		RateLimitState
+		observedAt time.Time
+	}
+)
+
+const (
+	// RateLimitRequests is the requests per minute (RPM) limit.
+	RateLimitRequests RateLimitKind = "requests"
+	// RateLimitInputTokens is the input tokens per minute (ITPM) limit.
+	RateLimitInputTokens RateLimitKind = "input_tokens"
+	// RateLimitOutputTokens is the output tokens per minute (OTPM) limit.
+	RateLimitOutputTokens RateLimitKind = "output_tokens"
+
+	rateLimitInterval         = time.Minute
+	defaultRateLimitWarnRatio = 0.8
+)
+
+var rateLimitKinds = []RateLimitKind{RateLimitRequests, RateLimitInputTokens, RateLimitOutputTokens}
+
+// ParseRateLimitHeaders parses the anthropic-ratelimit-* and x-ratelimit-* response headers.
+//
+// OpenAI does not report input and output token limits separately. Its combined token limit is reported as the
+// input token limit as it is consumed by prompts before any output is generated.
+func ParseRateLimitHeaders(h http.Header, now time.Time) map[RateLimitKind]RateLimitState {
+	states := make(map[RateLimitKind]RateLimitState)
+
+	anthropicNames := map[RateLimitKind]string{
+		RateLimitRequests:     "requests",
+		RateLimitInputTokens:  "input-tokens",
+		RateLimitOutputTokens: "output-tokens",
+	}
+	for kind, name := range anthropicNames {
+		prefix := "anthropic-ratelimit-" + name
+		state, ok := parseRateLimitState(h.Get(prefix+"-limit"), h.Get(prefix+"-remaining"))
+		if !ok {
+			continue
+		}
+
+		if reset, err := time.Parse(time.RFC3339, h.Get(prefix+"-reset")); err == nil {
+			state.ResetAt = reset
+		} else {
+			state.ResetAt = now.Add(rateLimitInterval)
+		}
+		states[kind] = state
+	}
+
+	openAINames := map[RateLimitKind]string{
+		RateLimitRequests:    "requests",
+		RateLimitInputTokens: "tokens",
+	}
+	for kind, name := range openAINames {
+		if _, ok := states[kind]; ok {
+			continue
+		}
+
+		state, ok := parseRateLimitState(h.Get("x-ratelimit-limit-"+name), h.Get("x-ratelimit-remaining-"+name))
+		if !ok {
+			continue
+		}
+
+		if reset, err := time.ParseDuration(h.Get("x-ratelimit-reset-" + name)); err == nil {
+			state.ResetAt = now.Add(reset)
+		} else {
+			state.ResetAt = now.Add(rateLimitInterval)
+		}
+		states[kind] = state
+	}
+
+	return states
+}
+
+func parseRateLimitState(limit string, remaining string) (RateLimitState, bool) {
+	l, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil {
+		return RateLimitState{}, false
+	}
+
+	r, err := strconv.Atoi(strings.TrimSpace(remaining))
+	if err != nil {
+		return RateLimitState{}, false
+	}
+
+	return RateLimitState{Limit: l, Remaining: r}, true
+}
+
+// NewRateLimitTracker returns a RateLimitTracker.
+func NewRateLimitTracker(config RateLimitTrackerConfig) *RateLimitTracker {
+	if config.WarnRatio <= 0 || config.WarnRatio > 1 {
+		config.WarnRatio = defaultRateLimitWarnRatio
+	}
+
+	return &RateLimitTracker{
+		config: config,
+		models: make(map[string]*modelRateLimits),
+	}
+}
+
+// RecordUsage records a request to model that consumed the given input and output tokens.
+func (t *RateLimitTracker) RecordUsage(model string, at time.Time, inputTokens int, outputTokens int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := t.model(model)
+	m.prune(at)
+	m.events = append(m.events, rateLimitEvent{
+		at: at,
+		amounts: map[RateLimitKind]int{
+			RateLimitRequests:     1,
+			RateLimitInputTokens:  inputTokens,
+			RateLimitOutputTokens: outputTokens,
+		},
+	})
+}
+
+// RecordHeaders records the rate limits reported by a provider response for model.
+func (t *RateLimitTracker) RecordHeaders(model string, at time.Time, h http.Header) {
+	states := ParseRateLimitHeaders(h, at)
+	if len(states) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := t.model(model)
+	for kind, state := range states {
+		m.states[kind] = observedRateLimitState{RateLimitState: state, observedAt: at}
+	}
+}
+
+// Assess returns the rate limit risk of sending a prompt with the given input tokens and maximum output tokens to model.
+//
+// Limits reported by response headers take precedence over configured limits until they reset. The risk level is
+// unknown when no limits are known for model.
+func (t *RateLimitTracker) Assess(model string, at time.Time, inputTokens int, outputTokens int) RateLimitAssessment {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	m := t.model(model)
+	m.prune(at)
+
+	assessment := RateLimitAssessment{
+		RiskLevel: RiskLevelUnknown,
+		PerMinute: make(map[RateLimitKind]int),
+		Limits:    make(map[RateLimitKind]int),
+	}
+
+	amounts := map[RateLimitKind]int{
+		RateLimitRequests:     1,
+		RateLimitInputTokens:  inputTokens,
+		RateLimitOutputTokens: outputTokens,
+	}
+
+	for _, kind := range rateLimitKinds {
+		used := m.usedSince(kind, at.Add(-rateLimitInterval))
+		assessment.PerMinute[kind] = used
+
+		var limit, available int
+		if state, ok := m.states[kind]; ok && at.Before(state.ResetAt) {
+			limit = state.Limit
+			available = state.Remaining - m.usedSince(kind, state.observedAt)
+		} else if state, ok := m.states[kind]; ok && state.Limit > 0 {
+			limit = state.Limit
+			available = limit - used
+		} else if configured, ok := t.config.Limits[kind]; ok && configured > 0 {
+			limit = configured
+			available = limit - used
+		} else {
+			continue
+		}
+
+		assessment.Limits[kind] = limit
+
+		level := RiskLevelLow
+		switch {
+		case amounts[kind] > available:
+			assessment.Exceeded = append(assessment.Exceeded, kind)
+			level = RiskLevelHigh
+		case float64(limit-available+amounts[kind]) > t.config.WarnRatio*float64(limit):
+			level = RiskLevelMedium
+		}
+
+		if assessment.RiskLevel == RiskLevelUnknown || rateLimitSeverity(level) > rateLimitSeverity(assessment.RiskLevel) {
+			assessment.RiskLevel = level
+		}
+	}
+
+	return assessment
+}
+
+func (t *RateLimitTracker) model(model string) *modelRateLimits {
+	m, ok := t.models[model]
+	if !ok {
+		m = &modelRateLimits{states: make(map[RateLimitKind]observedRateLimitState)}
+		t.models[model] = m
+	}
+
+	return m
+}
+
+// prune discards events that no longer count towards any limit.
+func (m *modelRateLimits) prune(at time.Time) {
+	cutoff := at.Add(-rateLimitInterval)
+	for _, state := range m.states {
+		if at.Before(state.ResetAt) && state.observedAt.Before(cutoff) {
+			cutoff = state.observedAt
+		}
+	}
+
+	i := 0
+	for i < len(m.events) && m.events[i].at.Before(cutoff) {
+		i++
+	}
+	m.events = m.events[i:]
+}
+
+func (m *modelRateLimits) usedSince(kind RateLimitKind, since time.Time) int {
+	used := 0
+	for _, e := range m.events {
+		if e.at.After(since) {
+			used += e.amounts[kind]
+		}
+	}
+
+	return used
+}
+
+func rateLimitSeverity(level RiskLevel) int {
+	switch level {
+	case RiskLevelLow:
+		return 1
+	case RiskLevelMedium:
+		return 2
+	case RiskLevelHigh:
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,152 @@
+package usage_test
+
+import (
+	"net/http"
+	"scrollwork/internal/usage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimitHeaders_Anthropic(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	h := http.Header{}
+	h.Set("anthropic-ratelimit-requests-limit", "50")
+	h.Set("anthropic-ratelimit-requests-remaining", "49")
+	h.Set("anthropic-ratelimit-requests-reset", "2025-09-01T12:00:30Z")
+	h.Set("anthropic-ratelimit-input-tokens-limit", "30000")
+	h.Set("anthropic-ratelimit-input-tokens-remaining", "12000")
+	h.Set("anthropic-ratelimit-output-tokens-limit", "8000")
+	h.Set("anthropic-ratelimit-output-tokens-remaining", "7000")
+
+	states := usage.ParseRateLimitHeaders(h, now)
+	require.Equal(t, usage.RateLimitState{Limit: 50, Remaining: 49, ResetAt: now.Add(30 * time.Second)}, states[usage.RateLimitRequests])
+	require.Equal(t, usage.RateLimitState{Limit: 30000, Remaining: 12000, ResetAt: now.Add(time.Minute)}, states[usage.RateLimitInputTokens])
+	require.Equal(t, usage.RateLimitState{Limit: 8000, Remaining: 7000, ResetAt: now.Add(time.Minute)}, states[usage.RateLimitOutputTokens])
+}
+
+func TestParseRateLimitHeaders_OpenAI(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	h := http.Header{}
+	h.Set("x-ratelimit-limit-requests", "60")
+	h.Set("x-ratelimit-remaining-requests", "59")
+	h.Set("x-ratelimit-reset-requests", "1s")
+	h.Set("x-ratelimit-limit-tokens", "150000")
+	h.Set("x-ratelimit-remaining-tokens", "149984")
+	h.Set("x-ratelimit-reset-tokens", "6m0s")
+
+	states := usage.ParseRateLimitHeaders(h, now)
+	require.Len(t, states, 2)
+	require.Equal(t, usage.RateLimitState{Limit: 60, Remaining: 59, ResetAt: now.Add(time.Second)}, states[usage.RateLimitRequests])
+	require.Equal(t, usage.RateLimitState{Limit: 150000, Remaining: 149984, ResetAt: now.Add(6 * time.Minute)}, states[usage.RateLimitInputTokens])
+}
+
+func TestRateLimitTrackerAssess(t *testing.T) {
+	t.Parallel()
+
+	model := "claude-sonnet-4-20250514"
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		Name             string
+		Limits           map[usage.RateLimitKind]int
+		Usage            [][2]int
+		InputTokens      int
+		OutputTokens     int
+		ExpectedLevel    usage.RiskLevel
+		ExpectedExceeded []usage.RateLimitKind
+	}{
+		{
+			Name:          "no limits",
+			InputTokens:   100,
+			ExpectedLevel: usage.RiskLevelUnknown,
+		},
+		{
+			Name:          "within limits",
+			Limits:        map[usage.RateLimitKind]int{usage.RateLimitRequests: 50, usage.RateLimitInputTokens: 1000},
+			Usage:         [][2]int{{100, 10}},
+			InputTokens:   100,
+			ExpectedLevel: usage.RiskLevelLow,
+		},
+		{
+			Name:          "near input token limit",
+			Limits:        map[usage.RateLimitKind]int{usage.RateLimitInputTokens: 1000},
+			Usage:         [][2]int{{700, 10}},
+			InputTokens:   200,
+			ExpectedLevel: usage.RiskLevelMedium,
+		},
+		{
+			Name:             "exceeds input token limit",
+			Limits:           map[usage.RateLimitKind]int{usage.RateLimitInputTokens: 1000},
+			Usage:            [][2]int{{900, 10}},
+			InputTokens:      200,
+			ExpectedLevel:    usage.RiskLevelHigh,
+			ExpectedExceeded: []usage.RateLimitKind{usage.RateLimitInputTokens},
+		},
+		{
+			Name:             "exceeds requests limit",
+			Limits:           map[usage.RateLimitKind]int{usage.RateLimitRequests: 2},
+			Usage:            [][2]int{{1, 1}, {1, 1}},
+			InputTokens:      1,
+			ExpectedLevel:    usage.RiskLevelHigh,
+			ExpectedExceeded: []usage.RateLimitKind{usage.RateLimitRequests},
+		},
+		{
+			Name:             "exceeds output token limit",
+			Limits:           map[usage.RateLimitKind]int{usage.RateLimitOutputTokens: 1000},
+			Usage:            [][2]int{{10, 500}},
+			OutputTokens:     600,
+			ExpectedLevel:    usage.RiskLevelHigh,
+			ExpectedExceeded: []usage.RateLimitKind{usage.RateLimitOutputTokens},
+		},
+	}
+
+	for _, td := range tt {
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+
+			tracker := usage.NewRateLimitTracker(usage.RateLimitTrackerConfig{Limits: td.Limits})
+			for i, u := range td.Usage {
+				tracker.RecordUsage(model, now.Add(time.Duration(i)*time.Second), u[0], u[1])
+			}
+
+			assessment := tracker.Assess(model, now.Add(10*time.Second), td.InputTokens, td.OutputTokens)
+			require.Equal(t, td.ExpectedLevel, assessment.RiskLevel)
+			require.Equal(t, td.ExpectedExceeded, assessment.Exceeded)
+		})
+	}
+}
+
+func TestRateLimitTrackerAssess_Headers(t *testing.T) {
+	t.Parallel()
+
+	model := "claude-sonnet-4-20250514"
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	tracker := usage.NewRateLimitTracker(usage.RateLimitTrackerConfig{
+		Limits: map[usage.RateLimitKind]int{usage.RateLimitInputTokens: 1_000_000},
+	})
+
+	h := http.Header{}
+	h.Set("anthropic-ratelimit-input-tokens-limit", "30000")
+	h.Set("anthropic-ratelimit-input-tokens-remaining", "1000")
+	h.Set("anthropic-ratelimit-input-tokens-reset", "2025-09-01T12:00:30Z")
+	tracker.RecordUsage(model, now, 29000, 0)
+	tracker.RecordHeaders(model, now, h)
+	tracker.RecordUsage(model, now.Add(time.Second), 500, 0)
+
+	assessment := tracker.Assess(model, now.Add(2*time.Second), 600, 0)
+	require.Equal(t, usage.RiskLevelHigh, assessment.RiskLevel)
+	require.Equal(t, 30000, assessment.Limits[usage.RateLimitInputTokens])
+	require.Equal(t, 29500, assessment.PerMinute[usage.RateLimitInputTokens])
+
+	// Once the reported limit resets, usage within the last minute is compared against the reported limit
+	assessment = tracker.Assess(model, now.Add(60*time.Second), 600, 0)
+	require.Equal(t, usage.RiskLevelLow, assessment.RiskLevel)
+	require.Equal(t, 500, assessment.PerMinute[usage.RateLimitInputTokens])
+}
@@ -1,10 +1,33 @@
 package usage
 
+import (
+	"fmt"
+)
+
 type (
+	// RiskThresholdsConfig configures the token thresholds used when assessing risk.
+	//
+	// Thresholds must be strictly increasing: 0 < Medium < High < Ceiling. Prompts at or below the Medium threshold
+	// are low risk. When every threshold is 0 risk assessment is disabled and every prompt is considered low risk.
+	RiskThresholdsConfig struct {
+		Medium float32 `json:"medium"`
+		High   float32 `json:"high"`
+
+		// Ceiling is the number of tokens past which a prompt is considered beyond high risk.
+		// Defaults to 10 times the High threshold.
+		Ceiling float32 `json:"ceiling"`
+
+		// BeyondHigh is the risk level returned once tokens exceed the Ceiling.
+		// It must be one of RiskLevelHigh, RiskLevelUnknown or RiskLevelBlock. Defaults to RiskLevelUnknown.
+		BeyondHigh RiskLevel `json:"beyond_high"`
+	}
+
 	RiskThresholds struct {
-		lowThreshold    float32
-		mediumThreshold float32
-		highThreshold   float32
+		mediumThreshold  float32
+		highThreshold    float32
+		ceilingThreshold float32
+		beyondHigh       RiskLevel
+		disabled         bool
 	}
 
 	RiskLevel string
@@ -15,50 +38,121 @@ const (
 	RiskLevelLow     RiskLevel = "low"
 	RiskLevelMedium  RiskLevel = "medium"
 	RiskLevelHigh    RiskLevel = "high"
+	RiskLevelBlock   RiskLevel = "block"
+
+	defaultCeilingMultiplier = 10
 )
 
-func NewRiskThresholds(low float32, medium float32, high float32) RiskThresholds {
-	return RiskThresholds{
-		lowThreshold:    low,
-		mediumThreshold: medium,
-		highThreshold:   high,
+// ParseRiskLevel returns the RiskLevel named by s.
+func ParseRiskLevel(s string) (RiskLevel, error) {
+	switch level := RiskLevel(s); level {
+	case RiskLevelUnknown, RiskLevelLow, RiskLevelMedium, RiskLevelHigh, RiskLevelBlock:
+		return level, nil
+	default:
+		return "", fmt.Errorf("ParseRiskLevel failed: unknown risk level %q", s)
 	}
 }
 
-func (t *RiskThresholds) Asses(tokens int) RiskLevel {
-	// Special case: all thresholds are 0
-	if t.lowThreshold == 0 && t.mediumThreshold == 0 && t.highThreshold == 0 {
-		return RiskLevelLow
+// MaxRiskLevel returns whichever of a and b is more severe. Levels rise from low to medium, high, unknown and block,
+// since prompts past the ceiling are unknown rather than high risk.
+func MaxRiskLevel(a, b RiskLevel) RiskLevel {
+	if riskSeverity(b) > riskSeverity(a) {
+		return b
 	}
 
-	// Special case: all thresholds are the same (invalid configuration)
-	if t.lowThreshold == t.mediumThreshold && t.mediumThreshold == t.highThreshold {
-		return RiskLevelUnknown
+	return a
+}
+
+func riskSeverity(level RiskLevel) int {
+	switch level {
+	case RiskLevelLow:
+		return 1
+	case RiskLevelMedium:
+		return 2
+	case RiskLevelHigh:
+		return 3
+	case RiskLevelUnknown:
+		return 4
+	case RiskLevelBlock:
+		return 5
+	default:
+		return 0
 	}
+}
 
-	tokensFloat := float32(tokens)
+// NewRiskThresholds validates config and returns RiskThresholds.
+func NewRiskThresholds(config RiskThresholdsConfig) (RiskThresholds, error) {
+	beyondHigh := config.BeyondHigh
+	if beyondHigh == "" {
+		beyondHigh = RiskLevelUnknown
+	}
 
-	// If tokens far exceed the highest threshold, return unknown
-	// This seems to be the expected behavior based on test case 4
-	if tokensFloat > t.highThreshold*10 {
-		return RiskLevelUnknown
+	switch beyondHigh {
+	case RiskLevelHigh, RiskLevelUnknown, RiskLevelBlock:
+	default:
+		return RiskThresholds{}, fmt.Errorf("NewRiskThresholds failed: beyond high risk level must be %s, %s or %s, got %q", RiskLevelHigh, RiskLevelUnknown, RiskLevelBlock, beyondHigh)
 	}
 
-	// High risk: above high threshold
-	if tokensFloat > t.highThreshold {
-		return RiskLevelHigh
+	if config.Medium == 0 && config.High == 0 && config.Ceiling == 0 {
+		return RiskThresholds{disabled: true, beyondHigh: beyondHigh}, nil
 	}
 
-	// Medium risk: above medium threshold but not high
-	if tokensFloat > t.mediumThreshold {
-		return RiskLevelMedium
+	if config.Medium <= 0 {
+		return RiskThresholds{}, fmt.Errorf("NewRiskThresholds failed: medium threshold %v must be positive", config.Medium)
+	}
+
+	if config.High <= config.Medium {
+		return RiskThresholds{}, fmt.Errorf("NewRiskThresholds failed: high threshold %v must be greater than medium threshold %v", config.High, config.Medium)
+	}
+
+	ceiling := config.Ceiling
+	if ceiling == 0 {
+		ceiling = config.High * defaultCeilingMultiplier
+	}
+
+	if ceiling <= config.High {
+		return RiskThresholds{}, fmt.Errorf("NewRiskThresholds failed: ceiling %v must be greater than high threshold %v", ceiling, config.High)
+	}
+
+	return RiskThresholds{
+		mediumThreshold:  config.Medium,
+		highThreshold:    config.High,
+		ceilingThreshold: ceiling,
+		beyondHigh:       beyondHigh,
+	}, nil
+}
+
+// Config returns the thresholds with defaults applied. Every threshold is 0 when risk assessment is disabled.
+func (t *RiskThresholds) Config() RiskThresholdsConfig {
+	return RiskThresholdsConfig{
+		Medium:     t.mediumThreshold,
+		High:       t.highThreshold,
+		Ceiling:    t.ceilingThreshold,
+		BeyondHigh: t.beyondHigh,
 	}
+}
 
-	// Low risk: at or below low threshold
-	if tokensFloat <= t.lowThreshold {
+// Asses returns the risk level for the given number of tokens.
+//
+//   - tokens <= medium threshold is low risk
+//   - tokens > medium threshold is medium risk
+//   - tokens > high threshold is high risk
+//   - tokens > ceiling is the configured beyond high risk level
+func (t *RiskThresholds) Asses(tokens int) RiskLevel {
+	if t.disabled {
 		return RiskLevelLow
 	}
 
-	// Between low and medium thresholds
-	return RiskLevelLow
+	tokensFloat := float32(tokens)
+
+	switch {
+	case tokensFloat > t.ceilingThreshold:
+		return t.beyondHigh
+	case tokensFloat > t.highThreshold:
+		return RiskLevelHigh
+	case tokensFloat > t.mediumThreshold:
+		return RiskLevelMedium
+	default:
+		return RiskLevelLow
+	}
 }
@@ -11,66 +11,103 @@ func TestAsses(t *testing.T) {
 	t.Parallel()
 
 	tt := []struct {
-		LowThreshold    float32
-		MediumThreshold float32
-		HighThreshold   float32
-		Tokens          int
-		Expected        usage.RiskLevel
+		Name       string
+		Medium     float32
+		High       float32
+		Ceiling    float32
+		BeyondHigh usage.RiskLevel
+		Tokens     int
+		Expected   usage.RiskLevel
 	}{
-		{
-			LowThreshold:    0,
-			MediumThreshold: 0,
-			HighThreshold:   0,
-			Tokens:          0,
-			Expected:        usage.RiskLevelLow,
-		},
-		{
-			LowThreshold:    1,
-			MediumThreshold: 1,
-			HighThreshold:   1,
-			Tokens:          0,
-			Expected:        usage.RiskLevelUnknown,
-		},
-		{
-			LowThreshold:    1,
-			MediumThreshold: 2,
-			HighThreshold:   3,
-			Tokens:          0,
-			Expected:        usage.RiskLevelLow,
-		},
-		{
-			LowThreshold:    1,
-			MediumThreshold: 2,
-			HighThreshold:   3,
-			Tokens:          100,
-			Expected:        usage.RiskLevelUnknown,
-		},
-		{
-			LowThreshold:    200,
-			MediumThreshold: 400,
-			HighThreshold:   600,
-			Tokens:          100,
-			Expected:        usage.RiskLevelLow,
-		},
-		{
-			LowThreshold:    200,
-			MediumThreshold: 400,
-			HighThreshold:   600,
-			Tokens:          500,
-			Expected:        usage.RiskLevelMedium,
-		},
-		{
-			LowThreshold:    200,
-			MediumThreshold: 400,
-			HighThreshold:   600,
-			Tokens:          900,
-			Expected:        usage.RiskLevelHigh,
-		},
+		{Name: "disabled zero tokens", Tokens: 0, Expected: usage.RiskLevelLow},
+		{Name: "disabled many tokens", Tokens: 1_000_000, Expected: usage.RiskLevelLow},
+
+		{Name: "zero tokens", Medium: 2, High: 3, Tokens: 0, Expected: usage.RiskLevelLow},
+		{Name: "below medium", Medium: 400, High: 600, Tokens: 200, Expected: usage.RiskLevelLow},
+		{Name: "at medium", Medium: 400, High: 600, Tokens: 400, Expected: usage.RiskLevelLow},
+		{Name: "above medium", Medium: 400, High: 600, Tokens: 401, Expected: usage.RiskLevelMedium},
+		{Name: "between medium and high", Medium: 400, High: 600, Tokens: 500, Expected: usage.RiskLevelMedium},
+		{Name: "at high", Medium: 400, High: 600, Tokens: 600, Expected: usage.RiskLevelMedium},
+		{Name: "above high", Medium: 400, High: 600, Tokens: 601, Expected: usage.RiskLevelHigh},
+		{Name: "between high and ceiling", Medium: 400, High: 600, Tokens: 900, Expected: usage.RiskLevelHigh},
+
+		{Name: "at default ceiling", Medium: 400, High: 600, Tokens: 6000, Expected: usage.RiskLevelHigh},
+		{Name: "above default ceiling", Medium: 400, High: 600, Tokens: 6001, Expected: usage.RiskLevelUnknown},
+		{Name: "far above default ceiling", Medium: 2, High: 3, Tokens: 100, Expected: usage.RiskLevelUnknown},
+
+		{Name: "at ceiling", Medium: 400, High: 600, Ceiling: 1000, Tokens: 1000, Expected: usage.RiskLevelHigh},
+		{Name: "above ceiling unknown", Medium: 400, High: 600, Ceiling: 1000, BeyondHigh: usage.RiskLevelUnknown, Tokens: 1001, Expected: usage.RiskLevelUnknown},
+		{Name: "above ceiling high", Medium: 400, High: 600, Ceiling: 1000, BeyondHigh: usage.RiskLevelHigh, Tokens: 1001, Expected: usage.RiskLevelHigh},
+		{Name: "above ceiling block", Medium: 400, High: 600, Ceiling: 1000, BeyondHigh: usage.RiskLevelBlock, Tokens: 1001, Expected: usage.RiskLevelBlock},
 	}
 
 	for _, td := range tt {
-		rt := usage.NewRiskThresholds(td.LowThreshold, td.MediumThreshold, td.HighThreshold)
-		risk := rt.Asses(td.Tokens)
-		require.Equal(t, td.Expected, risk)
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+
+			rt, err := usage.NewRiskThresholds(usage.RiskThresholdsConfig{
+				Medium:     td.Medium,
+				High:       td.High,
+				Ceiling:    td.Ceiling,
+				BeyondHigh: td.BeyondHigh,
+			})
+			require.NoError(t, err)
+
+			risk := rt.Asses(td.Tokens)
+			require.Equal(t, td.Expected, risk)
+		})
+	}
+}
+
+func TestNewRiskThresholds_Error(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name   string
+		Config usage.RiskThresholdsConfig
+	}{
+		{Name: "equal thresholds", Config: usage.RiskThresholdsConfig{Medium: 1, High: 1}},
+		{Name: "zero medium", Config: usage.RiskThresholdsConfig{Medium: 0, High: 2}},
+		{Name: "negative medium", Config: usage.RiskThresholdsConfig{Medium: -1, High: 2}},
+		{Name: "high equals medium", Config: usage.RiskThresholdsConfig{Medium: 2, High: 2}},
+		{Name: "high below medium", Config: usage.RiskThresholdsConfig{Medium: 3, High: 2}},
+		{Name: "ceiling equals high", Config: usage.RiskThresholdsConfig{Medium: 2, High: 3, Ceiling: 3}},
+		{Name: "ceiling below high", Config: usage.RiskThresholdsConfig{Medium: 2, High: 3, Ceiling: 2}},
+		{Name: "ceiling only", Config: usage.RiskThresholdsConfig{Ceiling: 10}},
+		{Name: "invalid beyond high", Config: usage.RiskThresholdsConfig{Medium: 2, High: 3, BeyondHigh: usage.RiskLevelLow}},
+	}
+
+	for _, td := range tt {
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := usage.NewRiskThresholds(td.Config)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestMaxRiskLevel(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		Name     string
+		A, B     usage.RiskLevel
+		Expected usage.RiskLevel
+	}{
+		{Name: "raised", A: usage.RiskLevelLow, B: usage.RiskLevelMedium, Expected: usage.RiskLevelMedium},
+		{Name: "kept", A: usage.RiskLevelBlock, B: usage.RiskLevelMedium, Expected: usage.RiskLevelBlock},
+		{Name: "equal", A: usage.RiskLevelHigh, B: usage.RiskLevelHigh, Expected: usage.RiskLevelHigh},
+		{Name: "unknown above high", A: usage.RiskLevelHigh, B: usage.RiskLevelUnknown, Expected: usage.RiskLevelUnknown},
+		{Name: "block above unknown", A: usage.RiskLevelUnknown, B: usage.RiskLevelBlock, Expected: usage.RiskLevelBlock},
+	}
+
+	for _, td := range tt {
+		t.Run(td.Name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, td.Expected, usage.MaxRiskLevel(td.A, td.B))
+			require.Equal(t, td.Expected, usage.MaxRiskLevel(td.B, td.A))
+		})
 	}
 }
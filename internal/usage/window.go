@@ -0,0 +1,40 @@
+package usage
+
+import (
+	"fmt"
+	"time"
+)
+
+type (
+	// Window is the period a usage budget applies to.
+	Window string
+)
+
+const (
+	WindowDaily   Window = "daily"
+	WindowMonthly Window = "monthly"
+)
+
+// ParseWindow returns the Window named by s.
+func ParseWindow(s string) (Window, error) {
+	switch w := Window(s); w {
+	case WindowDaily, WindowMonthly:
+		return w, nil
+	default:
+		return "", fmt.Errorf("ParseWindow failed: window must be %s or %s, got %q", WindowDaily, WindowMonthly, s)
+	}
+}
+
+// Bounds returns the UTC start and end of the window containing now.
+func (w Window) Bounds(now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+
+	switch w {
+	case WindowMonthly:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1)
+	}
+}